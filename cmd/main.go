@@ -20,7 +20,8 @@ func main() {
 	}
 	defer svc.Close()
 
-	if err := service.Run(ctx, svc); err != nil {
+	wait, err := service.Run(ctx, svc)
+	if err != nil {
 		log.Printf("could not start service: %+v", err)
 		return
 	}
@@ -28,4 +29,7 @@ func main() {
 	svc.Log.Info("Application is running")
 	<-ctx.Done()
 	svc.Log.Info("Shutting down application")
+	// Closing the service must wait for the servers and workers to finish what they started.
+	wait()
+	svc.Log.Info("Application stopped")
 }
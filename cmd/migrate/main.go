@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/dagherghinescu/companies/internal/migrations"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+const usage = `usage: migrate <command>
+
+commands:
+  up         apply all pending migrations
+  down [n]   revert the last n migrations (default 1)
+  version    print the current schema version`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:]); err != nil {
+		log.Printf("migrate: %+v", err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", usage)
+	}
+
+	cfg, err := repository.EnvConfig()
+	if err != nil {
+		return fmt.Errorf("database configuration error: %w", err)
+	}
+
+	db, err := repository.NewDBClient(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+
+	return runCommand(ctx, m, args)
+}
+
+// runCommand runs the subcommand in args[0] with m.
+func runCommand(ctx context.Context, m *migrations.Migrator, args []string) error {
+	switch args[0] {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("applied %d migration(s)", n)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		n, err := m.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		log.Printf("reverted %d migration(s)", n)
+	case "version":
+		v, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("schema version %d", v)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+
+	return nil
+}
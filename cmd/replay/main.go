@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/kafka"
+	"github.com/dagherghinescu/companies/internal/logger"
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/replay"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+const usage = `usage: replay <command>
+
+commands:
+  start [-type T] [-id ID]...   re-publish a snapshot event of every company, or of those
+                                of type T or with the given IDs, and wait until done
+  resume <id>                   continue a paused or failed replay and wait until done
+  status <id>                   print the progress of a replay
+
+Interrupting start or resume pauses the replay, so that it can be resumed later.`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:]); err != nil {
+		log.Printf("replay: %+v", err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+// cli holds what the subcommands need.
+type cli struct {
+	appl   *app.App
+	runner *replay.Runner
+}
+
+func run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", usage)
+	}
+
+	c, closeFn, err := newCLI()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	return c.runCommand(ctx, args)
+}
+
+// newCLI connects to the database and Kafka with the configuration of the service.
+func newCLI() (*cli, func(), error) {
+	dbCfg, err := repository.EnvConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("database configuration error: %w", err)
+	}
+	kafkaCfg, err := kafka.EnvConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("kafka configuration error: %w", err)
+	}
+	if err := kafkaCfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("kafka configuration error: %w", err)
+	}
+	replayCfg, err := replay.EnvConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("replay configuration error: %w", err)
+	}
+	appCfg, err := app.EnvConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("app configuration error: %w", err)
+	}
+
+	l, err := logger.Init()
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := repository.NewDBClient(dbCfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	producer, err := newProducer(kafkaCfg, l)
+	if err != nil {
+		_ = db.Close()
+		return nil, nil, err
+	}
+
+	appl := app.New(l, repository.NewPostgresRepo(db), appCfg)
+	appl.Replays = repository.NewReplayRepo(db)
+	runner := replay.NewRunner(replayCfg, repository.NewReplayQueue(db), producer,
+		kafkaCfg.CloudEventsMode, appCfg.EventSchemaURL, l)
+
+	closeFn := func() {
+		_ = producer.Close()
+		_ = db.Close()
+		_ = l.Sync()
+	}
+	return &cli{appl: appl, runner: runner}, closeFn, nil
+}
+
+// newProducer creates a producer retrying like the service's replay runner: a replay
+// that cannot publish fails and is resumed later.
+func newProducer(cfg *kafka.Config, l *zap.Logger) (*kafka.ResilientProducer, error) {
+	writer, err := kafka.NewProducer(cfg, l)
+	if err != nil {
+		return nil, err
+	}
+	return kafka.NewResilientProducer(cfg, writer), nil
+}
+
+// runCommand runs the subcommand in args[0].
+func (c *cli) runCommand(ctx context.Context, args []string) error {
+	switch args[0] {
+	case "start":
+		r, err := parseStart(args[1:])
+		if err != nil {
+			return err
+		}
+		if err := c.appl.StartReplay(ctx, r); err != nil {
+			return err
+		}
+		log.Printf("started replay %s", r.ID)
+		return c.publish(ctx, r.ID)
+	case "resume":
+		id, err := parseID(args[1:])
+		if err != nil {
+			return err
+		}
+		if _, err := c.appl.ResumeReplay(ctx, id); err != nil {
+			return err
+		}
+		return c.publish(ctx, id)
+	case "status":
+		id, err := parseID(args[1:])
+		if err != nil {
+			return err
+		}
+		r, err := c.appl.GetReplay(ctx, id)
+		if err != nil {
+			return err
+		}
+		printReplay(r)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+// publish publishes the replay id in the foreground. When interrupted, the replay is
+// paused, so the runners of the service do not take it over.
+func (c *cli) publish(ctx context.Context, id uuid.UUID) error {
+	err := c.runner.Publish(ctx, id)
+	if ctx.Err() != nil {
+		if _, pauseErr := c.appl.PauseReplay(context.Background(), id); pauseErr != nil {
+			return errors.Join(err, pauseErr)
+		}
+		log.Printf("paused replay %s, continue it with: replay resume %s", id, id)
+		return nil
+	}
+	if errors.Is(err, replay.ErrNotClaimed) {
+		return fmt.Errorf("replay %s is published by the service, follow it with: replay status %s", id, id)
+	}
+
+	r, getErr := c.appl.GetReplay(context.Background(), id)
+	if getErr != nil {
+		return errors.Join(err, getErr)
+	}
+	printReplay(r)
+	return err
+}
+
+// parseStart reads the filters of a new replay.
+func parseStart(args []string) (*models.Replay, error) {
+	r := &models.Replay{}
+	fs := flag.NewFlagSet("start", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Func("type", "company type", func(v string) error {
+		t := models.CompanyType(v)
+		r.Type = &t
+		return nil
+	})
+	fs.Func("id", "company ID, can be repeated", func(v string) error {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return err
+		}
+		r.CompanyIDs = append(r.CompanyIDs, id)
+		return nil
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("%w\n%s", err, usage)
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q\n%s", fs.Arg(0), usage)
+	}
+	return r, nil
+}
+
+// parseID reads the replay ID of resume and status.
+func parseID(args []string) (uuid.UUID, error) {
+	if len(args) != 1 {
+		return uuid.Nil, fmt.Errorf("expected a replay id\n%s", usage)
+	}
+
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid replay id %q", args[0])
+	}
+	return id, nil
+}
+
+func printReplay(r *models.Replay) {
+	log.Printf("replay %s: %s, %d event(s) published", r.ID, r.Status, r.Published)
+	if r.LastError != "" {
+		log.Printf("last error: %s", r.LastError)
+	}
+}
@@ -3,35 +3,54 @@ package app
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"go.uber.org/zap"
 
-	"github.com/dagherghinescu/companies/internal/kafka"
+	"github.com/dagherghinescu/companies/internal/auth"
+	"github.com/dagherghinescu/companies/internal/changefeed"
+	"github.com/dagherghinescu/companies/internal/events"
 	"github.com/dagherghinescu/companies/internal/models"
 	"github.com/dagherghinescu/companies/internal/repository"
 )
 
+// SystemActor is recorded as the author of changes not made on behalf of a user.
+const SystemActor = "system"
+
 type App struct {
-	Logger   *zap.Logger
-	DB       repository.Company
-	Producer kafka.ProducerInterface
+	Logger *zap.Logger
+	DB     repository.Company
+	Config *Config
+	// Changes is notified of every committed change to a company.
+	Changes *changefeed.Broadcaster
+	// Webhooks stores the partner endpoints the company events are delivered to.
+	Webhooks repository.Webhooks
+	// Replays stores the requests to re-publish the company events.
+	Replays repository.Replays
 }
 
 // New creates a new App instance
-func New(logger *zap.Logger, db repository.Company, producer kafka.ProducerInterface) *App {
+func New(logger *zap.Logger, db repository.Company, cfg *Config) *App {
 	return &App{
-		Logger:   logger,
-		DB:       db,
-		Producer: producer,
+		Logger:  logger,
+		DB:      db,
+		Config:  cfg,
+		Changes: changefeed.NewBroadcaster(),
 	}
 }
 
 // CreateCompany creates a new company
 func (a *App) CreateCompany(ctx context.Context, c *models.Company) error {
-	err := a.DB.Create(ctx, c)
+	err := a.inTx(ctx, func(tx repository.Company) error {
+		return a.createCompany(ctx, tx, c)
+	})
 	if err != nil {
 		var pqErr *pq.Error
 		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
@@ -39,22 +58,9 @@ func (a *App) CreateCompany(ctx context.Context, c *models.Company) error {
 				zap.String("company_name", *c.Name),
 				zap.String("detail", pqErr.Detail),
 			)
-
-			return ErrCompanyAlreadyExists
 		}
 
-		return err
-	}
-
-	event := map[string]interface{}{
-		"id":     c.ID.String(),
-		"name":   *c.Name,
-		"action": "created",
-	}
-
-	err = a.Producer.Publish(ctx, c.ID.String(), event)
-	if err != nil {
-		return err
+		return writeError(err)
 	}
 
 	return nil
@@ -81,57 +87,313 @@ func (a *App) GetCompany(ctx context.Context, id uuid.UUID) (*models.Company, er
 	return company, nil
 }
 
-// UpdateCompany updates an existing company
-func (a *App) PatchCompany(ctx context.Context, id uuid.UUID, fields map[string]interface{}) error {
-	if len(fields) > 0 {
-		return nil
+// ListCompanies returns a page of companies matching the filter
+func (a *App) ListCompanies(ctx context.Context, f repository.ListFilter) (*repository.CompanyPage, error) {
+	page, err := a.DB.List(ctx, f)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, ErrInvalidCursor
+		}
+
+		return nil, err
 	}
 
-	err := a.DB.Patch(ctx, id, fields)
+	return page, nil
+}
+
+// ExportCompanies calls fn for every company matching the filter, in the order of the filter
+// and up to its limit, if any.
+// Companies are streamed from the database, so fn should write them out as they come.
+func (a *App) ExportCompanies(ctx context.Context, f repository.ListFilter, fn func(c *models.Company) error) error {
+	return a.DB.Export(ctx, f, fn)
+}
+
+// SearchCompanies returns the companies that best match the query, most relevant first
+func (a *App) SearchCompanies(ctx context.Context, q string, limit uint64) ([]repository.SearchHit, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, ErrEmptySearchQuery
+	}
+
+	return a.DB.Search(ctx, q, limit)
+}
+
+// PatchCompany updates the given fields of an existing company and returns it as stored.
+// When ifMatch is set the change is only applied if the company is still at that version,
+// otherwise ErrPreconditionFailed is returned.
+func (a *App) PatchCompany(
+	ctx context.Context, id uuid.UUID, fields map[string]interface{}, ifMatch *int64,
+) (*models.Company, error) {
+	if len(fields) == 0 {
+		return a.GetCompany(ctx, id)
+	}
+
+	var updated *models.Company
+	err := a.inTx(ctx, func(tx repository.Company) error {
+		var err error
+		updated, err = a.patchCompany(ctx, tx, id, fields, ifMatch)
+		return err
+	})
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, ErrCompanyNotFound) {
-			return ErrCompanyNotFound
+		return nil, writeError(err)
+	}
+
+	return updated, nil
+}
+
+// DeleteCompany soft deletes a company by ID on behalf of the authenticated principal.
+// When ifMatch is set the company is only deleted if it is still at that version,
+// otherwise ErrPreconditionFailed is returned.
+func (a *App) DeleteCompany(ctx context.Context, id uuid.UUID, ifMatch *int64) error {
+	err := a.inTx(ctx, func(tx repository.Company) error {
+		return a.deleteCompany(ctx, tx, id, ifMatch)
+	})
+	if err != nil {
+		return writeError(err)
+	}
+
+	return nil
+}
+
+// RestoreCompany brings back a soft deleted company and returns it
+func (a *App) RestoreCompany(ctx context.Context, id uuid.UUID) (*models.Company, error) {
+	var restored *models.Company
+	err := a.inTx(ctx, func(tx repository.Company) error {
+		if err := tx.Restore(ctx, id); err != nil {
+			return err
+		}
+
+		var err error
+		if restored, err = tx.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return a.record(ctx, tx, models.ActionRestored, id, nil, restored, nil)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCompanyNotFound
 		}
 
 		var pqErr *pq.Error
 		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
-			return ErrCompanyAlreadyExists
+			return nil, ErrCompanyAlreadyExists
+		}
+
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// PurgeCompanies permanently removes the companies deleted longer than the
+// configured retention ago, redacting the snapshots kept of them, and returns
+// how many were removed
+func (a *App) PurgeCompanies(ctx context.Context) (int, error) {
+	deletedBefore := time.Now().Add(-a.Config.PurgeRetention)
+
+	var purged []uuid.UUID
+	err := a.inTx(ctx, func(tx repository.Company) error {
+		var err error
+		if purged, err = tx.Purge(ctx, deletedBefore); err != nil {
+			return err
+		}
+
+		for _, id := range purged {
+			if err := a.record(ctx, tx, models.ActionPurged, id, nil, nil, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	a.Logger.Info("purged deleted companies",
+		zap.Int("count", len(purged)),
+		zap.Time("deleted_before", deletedBefore),
+	)
+	return len(purged), nil
+}
+
+// CompanyHistory returns the audit trail of a company, newest first
+func (a *App) CompanyHistory(
+	ctx context.Context, id uuid.UUID, limit uint64, cursor string,
+) (*repository.HistoryPage, error) {
+	page, err := a.DB.History(ctx, id, limit, cursor)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, ErrInvalidCursor
+		}
+
+		return nil, err
+	}
+
+	if len(page.Entries) == 0 && cursor == "" {
+		return nil, ErrCompanyNotFound
+	}
+
+	return page, nil
+}
+
+// CompanyAsOf returns the company as it was at t, rebuilt from its audit trail.
+// ErrCompanyNotFound is returned if the company did not exist or was deleted at that time.
+func (a *App) CompanyAsOf(ctx context.Context, id uuid.UUID, t time.Time) (*models.Company, error) {
+	company, err := a.companyAt(ctx, id, t)
+	if err != nil {
+		return nil, err
+	}
+
+	if company == nil {
+		return nil, ErrCompanyNotFound
+	}
+
+	return company, nil
+}
+
+// DiffCompany returns the fields of a company that changed between from and to.
+// A company that did not exist at one of the two points is diffed against nothing.
+func (a *App) DiffCompany(ctx context.Context, id uuid.UUID, from, to time.Time) (*models.CompanyDiff, error) {
+	if from.After(to) {
+		return nil, ErrInvalidTimeRange
+	}
+
+	before, err := a.companyAt(ctx, id, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := a.companyAt(ctx, id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if before == nil && after == nil {
+		return nil, ErrCompanyNotFound
+	}
+
+	return &models.CompanyDiff{From: from, To: to, Changes: models.DiffCompanies(before, after)}, nil
+}
+
+// companyAt returns the state recorded by the last change made to the company at or before t,
+// or nil if the company did not exist at that time.
+func (a *App) companyAt(ctx context.Context, id uuid.UUID, t time.Time) (*models.Company, error) {
+	entry, err := a.DB.AuditAt(ctx, id, t)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
 		}
 
+		return nil, err
+	}
+
+	return entry.After, nil
+}
+
+// createCompany inserts c as part of the transaction tx.
+func (a *App) createCompany(ctx context.Context, tx repository.Company, c *models.Company) error {
+	c.Version = 1
+
+	if err := tx.Create(ctx, c); err != nil {
 		return err
 	}
+	return a.record(ctx, tx, models.ActionCreated, c.ID, nil, c, nil)
+}
 
-	event := map[string]interface{}{
-		"id":     id.String(),
-		"action": "updated",
-		"fields": fields,
+// patchCompany applies fields to a company as part of the transaction tx and returns it as stored.
+func (a *App) patchCompany(
+	ctx context.Context, tx repository.Company, id uuid.UUID, fields map[string]interface{}, ifMatch *int64,
+) (*models.Company, error) {
+	before, err := tx.GetForUpdate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Patch(ctx, id, fields, ifMatch); err != nil {
+		return nil, err
+	}
+	updated, err := tx.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
+	changed := slices.Sorted(maps.Keys(fields))
+	return updated, a.record(ctx, tx, models.ActionUpdated, id, before, updated, changed)
+}
 
-	if err := a.Producer.Publish(ctx, id.String(), event); err != nil {
+// deleteCompany soft deletes a company as part of the transaction tx.
+func (a *App) deleteCompany(ctx context.Context, tx repository.Company, id uuid.UUID, ifMatch *int64) error {
+	before, err := tx.GetForUpdate(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := tx.Delete(ctx, id, actor(ctx), ifMatch); err != nil {
 		return err
 	}
+	return a.record(ctx, tx, models.ActionDeleted, id, before, nil, nil)
+}
 
-	return nil
+// writeError maps the errors of a failed create, patch or delete to the app errors.
+func writeError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, ErrCompanyNotFound) {
+		return ErrCompanyNotFound
+	}
+	if errors.Is(err, repository.ErrVersionMismatch) {
+		return ErrPreconditionFailed
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrCompanyAlreadyExists
+	}
+
+	return err
 }
 
-// DeleteCompany deletes a company by ID
-func (a *App) DeleteCompany(ctx context.Context, id uuid.UUID) error {
-	if err := a.DB.Delete(ctx, id); err != nil {
-		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, ErrCompanyNotFound) {
-			return ErrCompanyNotFound
-		}
+// record appends the change to the audit trail and stores its event in the outbox,
+// both as part of the transaction tx. changed lists the fields set by an update.
+func (a *App) record(
+	ctx context.Context, tx repository.Company, action models.AuditAction, id uuid.UUID,
+	before, after *models.Company, changed []string,
+) error {
+	entry := &models.AuditEntry{
+		CompanyID: id,
+		Actor:     actor(ctx),
+		Action:    action,
+		Before:    before,
+		After:     after,
+	}
+	if err := tx.AddAudit(ctx, entry); err != nil {
+		return err
+	}
 
+	event, err := events.NewCompanyEvent(&events.CompanyChangedV1{
+		CompanyID:     id,
+		Action:        action,
+		Actor:         entry.Actor,
+		Before:        before,
+		After:         after,
+		ChangedFields: changed,
+	}, a.Config.EventSchemaURL)
+	if err != nil {
 		return err
 	}
+	return enqueue(ctx, tx, id.String(), event)
+}
 
-	event := map[string]interface{}{
-		"id":     id.String(),
-		"action": "deleted",
+// actor identifies who is making the change, from the principal authenticated for the request.
+// Changes made outside of an authenticated request are attributed to SystemActor.
+func actor(ctx context.Context) string {
+	if p, ok := auth.PrincipalFrom(ctx); ok {
+		return p.UserID
 	}
+	return SystemActor
+}
 
-	if err := a.Producer.Publish(ctx, id.String(), event); err != nil {
+// enqueue stores the event in the outbox as part of the transaction tx.
+// The outbox relay publishes it to Kafka once the transaction commits.
+func enqueue(ctx context.Context, tx repository.Company, key string, event any) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	return tx.AddOutboxEvent(ctx, key, payload)
 }
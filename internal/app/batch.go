@@ -0,0 +1,140 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+// MaxBatchOperations is the maximum number of operations accepted by ExecuteBatch.
+const MaxBatchOperations = 1000
+
+// BatchMode selects how ExecuteBatch handles failing operations.
+type BatchMode string
+
+const (
+	// BatchAtomic applies all operations in one transaction, or none of them.
+	BatchAtomic BatchMode = "atomic"
+	// BatchBestEffort applies each operation in its own transaction.
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+// Valid reports whether m is a known batch mode.
+func (m BatchMode) Valid() bool {
+	return m == BatchAtomic || m == BatchBestEffort
+}
+
+// BatchOp is the kind of a batch operation.
+type BatchOp string
+
+const (
+	OpCreate BatchOp = "create"
+	OpPatch  BatchOp = "patch"
+	OpDelete BatchOp = "delete"
+)
+
+// BatchOperation is a single create, patch or delete of a batch.
+// Company is the company to create, Fields the fields to patch and
+// IfMatch the expected version for patch and delete.
+type BatchOperation struct {
+	Op      BatchOp
+	ID      uuid.UUID
+	Company *models.Company
+	Fields  map[string]interface{}
+	IfMatch *int64
+}
+
+// BatchResult is the outcome of the batch operation at the same index.
+// Company is set for successful creates and patches.
+type BatchResult struct {
+	Company *models.Company
+	Err     error
+}
+
+// ExecuteBatch applies the operations in order and returns one result per operation.
+// In BatchAtomic mode the first failure rolls back the whole batch and every other
+// operation reports ErrBatchAborted. Events are written to the outbox like for single
+// operations, so the relay publishes them together.
+func (a *App) ExecuteBatch(ctx context.Context, mode BatchMode, ops []BatchOperation) ([]BatchResult, error) {
+	if len(ops) == 0 {
+		return nil, ErrEmptyBatch
+	}
+	if len(ops) > MaxBatchOperations {
+		return nil, ErrBatchTooLarge
+	}
+
+	if mode == BatchBestEffort {
+		return a.executeBestEffort(ctx, ops), nil
+	}
+	return a.executeAtomic(ctx, ops)
+}
+
+// executeBestEffort applies each operation in its own transaction.
+func (a *App) executeBestEffort(ctx context.Context, ops []BatchOperation) []BatchResult {
+	results := make([]BatchResult, len(ops))
+	for i, op := range ops {
+		err := a.inTx(ctx, func(tx repository.Company) error {
+			return a.applyOperation(ctx, tx, op, &results[i])
+		})
+		if err != nil {
+			results[i] = BatchResult{Err: writeError(err)}
+		}
+	}
+	return results
+}
+
+// executeAtomic applies all operations in one transaction, stopping at the first failure.
+func (a *App) executeAtomic(ctx context.Context, ops []BatchOperation) ([]BatchResult, error) {
+	results := make([]BatchResult, len(ops))
+	failed := -1
+	err := a.inTx(ctx, func(tx repository.Company) error {
+		for i, op := range ops {
+			if err := a.applyOperation(ctx, tx, op, &results[i]); err != nil {
+				failed = i
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		return results, nil
+	}
+	if failed < 0 {
+		// The operations succeeded but the transaction could not be committed.
+		return nil, err
+	}
+
+	for i := range results {
+		results[i] = BatchResult{Err: ErrBatchAborted}
+	}
+	results[failed].Err = writeError(err)
+	return results, nil
+}
+
+func (a *App) applyOperation(
+	ctx context.Context, tx repository.Company, op BatchOperation, result *BatchResult,
+) error {
+	switch op.Op {
+	case OpCreate:
+		if err := a.createCompany(ctx, tx, op.Company); err != nil {
+			return err
+		}
+		result.Company = op.Company
+		return nil
+	case OpPatch:
+		company, err := a.patchCompany(ctx, tx, op.ID, op.Fields, op.IfMatch)
+		if err != nil {
+			return err
+		}
+		result.Company = company
+		return nil
+	case OpDelete:
+		return a.deleteCompany(ctx, tx, op.ID, op.IfMatch)
+	default:
+		return errors.New("unknown batch operation " + string(op.Op))
+	}
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+
+	"github.com/dagherghinescu/companies/internal/changefeed"
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+// inTx runs fn in a transaction like DB.InTx and, once it has committed,
+// publishes the changes recorded to the audit trail on a.Changes.
+func (a *App) inTx(ctx context.Context, fn func(tx repository.Company) error) error {
+	var entries []*models.AuditEntry
+	err := a.DB.InTx(ctx, func(tx repository.Company) error {
+		entries = entries[:0]
+		return fn(&auditRecorder{Company: tx, entries: &entries})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		a.Changes.Publish(changefeed.Change{
+			Action:    e.Action,
+			CompanyID: e.CompanyID,
+			Actor:     e.Actor,
+			Before:    e.Before,
+			After:     e.After,
+			Time:      e.CreatedAt,
+		})
+	}
+	return nil
+}
+
+// auditRecorder keeps the audit entries added through it, so they can be
+// published once their transaction commits.
+type auditRecorder struct {
+	repository.Company
+	entries *[]*models.AuditEntry
+}
+
+func (r *auditRecorder) AddAudit(ctx context.Context, e *models.AuditEntry) error {
+	if err := r.Company.AddAudit(ctx, e); err != nil {
+		return err
+	}
+	*r.entries = append(*r.entries, e)
+	return nil
+}
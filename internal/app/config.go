@@ -0,0 +1,31 @@
+package app
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Config holds the business rules that can be tuned per deployment.
+type Config struct {
+	// PurgeRetention is how long soft deleted companies are kept before they can be purged.
+	PurgeRetention time.Duration `envconfig:"PURGE_RETENTION" default:"2160h"`
+	// IdempotencyKeyTTL is how long responses are kept for replay to requests with the same Idempotency-Key.
+	IdempotencyKeyTTL time.Duration `envconfig:"IDEMPOTENCY_KEY_TTL" default:"24h"`
+	// StreamHeartbeat is how often idle change streams are sent a heartbeat to keep the connection open.
+	StreamHeartbeat time.Duration `envconfig:"STREAM_HEARTBEAT" default:"15s"`
+	// EventSchemaURL is where the JSON Schemas of the events are published, named in their dataschema.
+	EventSchemaURL string `envconfig:"EVENT_SCHEMA_URL" default:"http://localhost:8080/events/schemas"`
+	// AllowPrivateWebhooks lets webhooks target loopback, private and link-local addresses.
+	// It is set from the webhook configuration, which the dispatcher enforces the same way.
+	AllowPrivateWebhooks bool `ignored:"true"`
+}
+
+// EnvConfig loads the application configuration from environment variables
+func EnvConfig() (*Config, error) {
+	var cfg Config
+	if err := envconfig.Process("APP", &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
@@ -1,8 +1,88 @@
 package app
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// Kind classifies errors so that transports can map them to a response.
+type Kind string
+
+const (
+	KindNotFound           Kind = "not-found"
+	KindConflict           Kind = "conflict"
+	KindValidation         Kind = "validation"
+	KindPreconditionFailed Kind = "precondition-failed"
+	KindUnauthorized       Kind = "unauthorized"
+	KindForbidden          Kind = "forbidden"
+	KindUnprocessable      Kind = "unprocessable"
+	KindTooLarge           Kind = "too-large"
+)
+
+// Error is an error of a known Kind. Fields lists the invalid fields of validation errors.
+type Error struct {
+	Kind    Kind
+	Message string
+	Fields  []models.FieldError
+}
+
+// NewError returns an error of the given kind.
+func NewError(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Invalid returns a KindValidation error.
+func Invalid(message string) *Error {
+	return NewError(KindValidation, message)
+}
+
+// InvalidFields returns a KindValidation error listing fields after subject, such as
+// "invalid webhook: url is required", or nil when fields is empty.
+func InvalidFields(subject string, fields []models.FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	return &Error{Kind: KindValidation, Message: subject + ": " + models.JoinFieldErrors(fields), Fields: fields}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// AsError returns the *Error in err's chain, converting the validation errors
+// of models. It returns nil for errors of no known kind.
+func AsError(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var validationErr *models.ValidationError
+	if errors.As(err, &validationErr) {
+		return &Error{Kind: KindValidation, Message: err.Error(), Fields: validationErr.Fields}
+	}
+	return nil
+}
 
 var (
-	ErrCompanyNotFound      = errors.New("company not found")
-	ErrCompanyAlreadyExists = errors.New("company already exists")
+	ErrCompanyNotFound      = NewError(KindNotFound, "company not found")
+	ErrCompanyAlreadyExists = NewError(KindConflict, "company with that name already exists")
+	ErrInvalidCursor        = Invalid("invalid cursor")
+	ErrEmptySearchQuery     = Invalid("query parameter q is required")
+	ErrPreconditionFailed   = NewError(KindPreconditionFailed, "company was modified since it was read")
+	ErrInvalidTimeRange     = Invalid("from must not be after to")
+	ErrEmptyBatch           = Invalid("batch has no operations")
+	ErrBatchTooLarge        = Invalid("batch has too many operations")
+	ErrBatchAborted         = errors.New("not applied, another operation of the batch failed")
+	ErrInvalidImport        = Invalid("invalid import file")
+	ErrUnauthenticated      = NewError(KindUnauthorized, "unauthenticated")
+	ErrInvalidCredentials   = NewError(KindUnauthorized, "invalid credentials")
+	ErrInvalidRefreshToken  = NewError(KindUnauthorized, "invalid refresh token")
+	ErrRefreshTokenReused   = NewError(KindUnauthorized, "refresh token was already used, its session is revoked")
+	ErrForbidden            = NewError(KindForbidden, "insufficient role")
+	ErrWebhookNotFound      = NewError(KindNotFound, "webhook not found")
+	ErrReplayNotFound       = NewError(KindNotFound, "replay not found")
+	ErrReplayNotRunning     = NewError(KindConflict, "replay is not running")
+	ErrReplayCompleted      = NewError(KindConflict, "replay has completed")
 )
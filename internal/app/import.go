@@ -0,0 +1,172 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/dagherghinescu/companies/internal/importer"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+const (
+	// importChunkSize is the number of valid rows whose names are checked with a single query.
+	importChunkSize = 100
+	// MaxImportReportEntries bounds the accepted rows and errors listed in an ImportReport.
+	// The counters always cover the whole upload.
+	MaxImportReportEntries = 1000
+)
+
+// ImportReport summarizes an import. Rows counts the rows read, of which
+// Accepted were created (or would have been, in a dry run) and Rejected were not.
+type ImportReport struct {
+	DryRun       bool          `json:"dry_run"`
+	Rows         int           `json:"rows"`
+	Accepted     int           `json:"accepted"`
+	Rejected     int           `json:"rejected"`
+	AcceptedRows []ImportedRow `json:"accepted_rows"`
+	Errors       []ImportError `json:"errors"`
+	// Truncated is set when more entries were accepted or rejected than listed.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// ImportedRow is a row that was accepted, with the ID of its company.
+type ImportedRow struct {
+	Line int       `json:"line"`
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// ImportError is a row that was rejected and why.
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+func (r *ImportReport) accept(line int, c *models.Company) {
+	r.Accepted++
+	if len(r.AcceptedRows) >= MaxImportReportEntries {
+		r.Truncated = true
+		return
+	}
+	r.AcceptedRows = append(r.AcceptedRows, ImportedRow{Line: line, ID: c.ID, Name: *c.Name})
+}
+
+func (r *ImportReport) reject(line int, err error) {
+	r.Rejected++
+	if len(r.Errors) >= MaxImportReportEntries {
+		r.Truncated = true
+		return
+	}
+	r.Errors = append(r.Errors, ImportError{Line: line, Error: err.Error()})
+}
+
+// ImportCompanies creates a company for each valid row read from src. Each company is
+// created in its own transaction, so rejected rows do not affect the others. Rows are
+// validated like single creates and rejected when their name is already taken, by an
+// existing company or an earlier row. In a dry run nothing is written.
+//
+// Only the names of the current chunk are kept in memory, so that memory use does not
+// depend on the size of src. A row repeating the name of an earlier chunk is rejected
+// because that company was created by then; in a dry run, where nothing is created,
+// such rows are accepted.
+//
+// The report is returned even when the import stops early with an error; errors
+// reading src wrap ErrInvalidImport.
+func (a *App) ImportCompanies(ctx context.Context, src importer.Reader, dryRun bool) (*ImportReport, error) {
+	report := &ImportReport{
+		DryRun:       dryRun,
+		AcceptedRows: make([]ImportedRow, 0),
+		Errors:       make([]ImportError, 0),
+	}
+
+	// firstLine holds the names of the rows in chunk, the only ones compared to each other.
+	firstLine := make(map[string]int, importChunkSize)
+	chunk := make([]importer.Row, 0, importChunkSize)
+	for {
+		row, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return report, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+
+		report.Rows++
+		if row.Err == nil {
+			row.Err = row.Company.Validate()
+		}
+		if row.Err == nil {
+			if line, ok := firstLine[*row.Company.Name]; ok {
+				row.Err = fmt.Errorf("%w: same name as line %d", ErrCompanyAlreadyExists, line)
+			} else {
+				firstLine[*row.Company.Name] = row.Line
+			}
+		}
+		if row.Err != nil {
+			report.reject(row.Line, row.Err)
+			continue
+		}
+
+		if chunk = append(chunk, row); len(chunk) == importChunkSize {
+			if err := a.importChunk(ctx, chunk, report); err != nil {
+				return report, err
+			}
+			chunk = chunk[:0]
+			clear(firstLine)
+		}
+	}
+
+	err := a.importChunk(ctx, chunk, report)
+
+	// Rows of the same chunk are rejected for their name only after later rows
+	// failed validation, so restore the order of the file.
+	slices.SortStableFunc(report.Errors, func(a, b ImportError) int { return cmp.Compare(a.Line, b.Line) })
+	return report, err
+}
+
+// importChunk creates the companies of valid rows whose names are not taken yet.
+func (a *App) importChunk(ctx context.Context, rows []importer.Row, report *ImportReport) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(rows))
+	for i, row := range rows {
+		names[i] = *row.Company.Name
+	}
+	takenNames, err := a.DB.TakenNames(ctx, names)
+	if err != nil {
+		return err
+	}
+	taken := make(map[string]bool, len(takenNames))
+	for _, name := range takenNames {
+		taken[name] = true
+	}
+
+	for _, row := range rows {
+		if taken[*row.Company.Name] {
+			report.reject(row.Line, ErrCompanyAlreadyExists)
+			continue
+		}
+
+		row.Company.ID = uuid.New()
+		if !report.DryRun {
+			if err := a.CreateCompany(ctx, row.Company); err != nil {
+				if errors.Is(err, ErrCompanyAlreadyExists) {
+					report.reject(row.Line, err)
+					continue
+				}
+				return err
+			}
+		}
+		report.accept(row.Line, row.Company)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// MaxReplayCompanies caps the company IDs a replay can be limited to.
+const MaxReplayCompanies = 1000
+
+// StartReplay validates r and stores it as running, for a replay runner to publish.
+// Unset filters replay every company.
+func (a *App) StartReplay(ctx context.Context, r *models.Replay) error {
+	if err := validateReplay(r); err != nil {
+		return err
+	}
+
+	r.ID = uuid.New()
+	r.Status = models.ReplayRunning
+	r.RequestedBy = actor(ctx)
+	if r.CompanyIDs == nil {
+		r.CompanyIDs = []uuid.UUID{}
+	}
+	return a.Replays.Create(ctx, r)
+}
+
+// ListReplays returns the latest replays, newest first
+func (a *App) ListReplays(ctx context.Context, limit uint64) ([]models.Replay, error) {
+	return a.Replays.List(ctx, limit)
+}
+
+// GetReplay retrieves a replay by ID
+func (a *App) GetReplay(ctx context.Context, id uuid.UUID) (*models.Replay, error) {
+	r, err := a.Replays.Get(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReplayNotFound
+	}
+	return r, err
+}
+
+// PauseReplay stops a running replay after its current batch. Pausing a paused replay does nothing.
+func (a *App) PauseReplay(ctx context.Context, id uuid.UUID) (*models.Replay, error) {
+	return a.setReplayStatus(ctx, id, models.ReplayPaused, ErrReplayNotRunning, models.ReplayRunning)
+}
+
+// ResumeReplay continues a paused or failed replay after the last company it published.
+// Resuming a running replay does nothing.
+func (a *App) ResumeReplay(ctx context.Context, id uuid.UUID) (*models.Replay, error) {
+	return a.setReplayStatus(ctx, id, models.ReplayRunning, ErrReplayCompleted,
+		models.ReplayPaused, models.ReplayFailed)
+}
+
+// setReplayStatus moves a replay in one of the statuses from to status. A replay in
+// another status gets conflict, unless it already is in status.
+func (a *App) setReplayStatus(
+	ctx context.Context, id uuid.UUID, status models.ReplayStatus, conflict error, from ...models.ReplayStatus,
+) (*models.Replay, error) {
+	r, err := a.GetReplay(ctx, id)
+	if err != nil || r.Status == status {
+		return r, err
+	}
+
+	err = a.Replays.SetStatus(ctx, id, status, from...)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The replay is in another status, or left the one it was read in.
+		return nil, conflict
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return a.GetReplay(ctx, id)
+}
+
+// validateReplay checks the filters of a replay.
+func validateReplay(r *models.Replay) error {
+	var fields []models.FieldError
+	if r.Type != nil && !r.Type.Valid() {
+		fields = append(fields, models.FieldError{Field: "type", Message: "is not a known company type"})
+	}
+	if len(r.CompanyIDs) > MaxReplayCompanies {
+		fields = append(fields, models.FieldError{
+			Field:   "company_ids",
+			Message: fmt.Sprintf("must have at most %d IDs", MaxReplayCompanies),
+		})
+	}
+	return InvalidFields("invalid replay", fields)
+}
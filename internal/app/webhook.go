@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/url"
+
+	"github.com/google/uuid"
+
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+	"github.com/dagherghinescu/companies/internal/webhook"
+)
+
+// maxWebhookURLLength is the maximum length of a webhook URL, in bytes.
+const maxWebhookURLLength = 2048
+
+// CreateWebhook validates w, gives it an ID and a new signing secret, and stores it.
+// The secret is only ever returned here, so the caller must pass it on.
+func (a *App) CreateWebhook(ctx context.Context, w *models.Webhook) error {
+	if err := validateWebhook(w, a.Config.AllowPrivateWebhooks); err != nil {
+		return err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+
+	w.ID = uuid.New()
+	w.Secret = hex.EncodeToString(secret)
+	if w.Events == nil {
+		w.Events = []models.AuditAction{}
+	}
+	return a.Webhooks.Create(ctx, w)
+}
+
+// ListWebhooks returns every webhook, oldest first
+func (a *App) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	return a.Webhooks.List(ctx)
+}
+
+// GetWebhook retrieves a webhook by ID
+func (a *App) GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	w, err := a.Webhooks.Get(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	return w, err
+}
+
+// DeleteWebhook stops the deliveries to a webhook and removes it with its delivery log
+func (a *App) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	err := a.Webhooks.Delete(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWebhookNotFound
+	}
+	return err
+}
+
+// EnableWebhook resumes the deliveries to a webhook disabled after failing too many times.
+// Its pending deliveries are retried, but events committed while it was disabled are not sent.
+func (a *App) EnableWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	err := a.Webhooks.Enable(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return a.GetWebhook(ctx, id)
+}
+
+// WebhookDeliveries returns the delivery log of a webhook, newest first
+func (a *App) WebhookDeliveries(
+	ctx context.Context, id uuid.UUID, limit uint64, cursor string,
+) (*repository.DeliveryPage, error) {
+	if _, err := a.GetWebhook(ctx, id); err != nil {
+		return nil, err
+	}
+
+	page, err := a.Webhooks.Deliveries(ctx, id, limit, cursor)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return nil, ErrInvalidCursor
+	}
+	return page, err
+}
+
+// validateWebhook checks that the URL is an absolute http or https URL, of a public
+// host unless allowPrivate is set, and that the events are known actions.
+func validateWebhook(w *models.Webhook, allowPrivate bool) error {
+	var fields []models.FieldError
+	if msg := webhookURLError(w.URL, allowPrivate); msg != "" {
+		fields = append(fields, models.FieldError{Field: "url", Message: msg})
+	}
+	for _, e := range w.Events {
+		if !e.Valid() {
+			fields = append(fields, models.FieldError{Field: "events", Message: "has unknown action " + string(e)})
+		}
+	}
+	return InvalidFields("invalid webhook", fields)
+}
+
+// webhookURLError returns why raw cannot be a webhook URL, or an empty string if it can.
+func webhookURLError(raw string, allowPrivate bool) string {
+	if raw == "" {
+		return "is required"
+	}
+	if len(raw) > maxWebhookURLLength {
+		return "is too long"
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "must be an absolute http or https URL"
+	}
+	if !allowPrivate && webhook.CheckHost(u.Hostname()) != nil {
+		return "must not be a loopback, private or link-local address"
+	}
+	return ""
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits is the smallest RSA key accepted.
+const minRSABits = 2048
+
+var (
+	errUnknownKey    = errors.New("token signed with an unknown key")
+	errAlgMismatch   = errors.New("token algorithm does not match its key")
+	errNoPEM         = errors.New("no PEM block found")
+	errUnsupported   = errors.New("unsupported key type, expected RSA or Ed25519")
+	errRSAKeyTooWeak = fmt.Errorf("RSA keys must have at least %d bits", minRSABits)
+)
+
+// JWK is a public key in the JSON Web Key format of RFC 7517. N and E are set for
+// RSA keys, Crv and X for Ed25519 keys.
+type JWK struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Alg     string `json:"alg"`
+	N       string `json:"n,omitempty"`
+	E       string `json:"e,omitempty"`
+	Crv     string `json:"crv,omitempty"`
+	X       string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// key signs or verifies tokens. signing is nil for keys that only verify.
+type key struct {
+	method  jwt.SigningMethod
+	signing crypto.PrivateKey
+	public  crypto.PublicKey
+	jwk     *JWK
+}
+
+// KeySet holds the key the access tokens are signed with, and the keys they are
+// verified with by kid: the signing key and the keys rotated out or about to be
+// rotated in. Keys are identified by their RFC 7638 thumbprint.
+type KeySet struct {
+	signing *key
+	keys    map[string]*key
+}
+
+// NewHMACKeySet returns a key set signing and verifying HS256 tokens with secret.
+// The tokens carry no kid, and the secret is not published.
+func NewHMACKeySet(secret string) *KeySet {
+	k := &key{method: jwt.SigningMethodHS256, signing: []byte(secret), public: []byte(secret)}
+	return &KeySet{signing: k, keys: map[string]*key{"": k}}
+}
+
+// LoadKeySet reads the PEM private key, RSA or Ed25519, tokens are signed with, and the
+// PEM public keys tokens are also verified with.
+func LoadKeySet(signingKeyFile string, verificationKeyFiles []string) (*KeySet, error) {
+	pemBytes, err := os.ReadFile(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	signing, err := parsePrivateKey(pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", signingKeyFile, err)
+	}
+
+	set := &KeySet{signing: signing, keys: map[string]*key{signing.jwk.KeyID: signing}}
+	for _, file := range verificationKeyFiles {
+		pemBytes, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		k, err := parsePublicKey(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		set.keys[k.jwk.KeyID] = k
+	}
+	return set, nil
+}
+
+// Sign signs claims with the signing key, naming it in the kid header.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.signing.method, claims)
+	if s.signing.jwk != nil {
+		token.Header["kid"] = s.signing.jwk.KeyID
+	}
+	return token.SignedString(s.signing.signing)
+}
+
+// Parse verifies tokenString with the key named by its kid, only accepting the
+// algorithm of that key, and decodes its claims. opts add to the claims validation.
+func (s *KeySet) Parse(tokenString string, claims jwt.Claims, opts ...jwt.ParserOption) error {
+	methods := make([]string, 0, len(s.keys))
+	for _, k := range s.keys {
+		if !slices.Contains(methods, k.method.Alg()) {
+			methods = append(methods, k.method.Alg())
+		}
+	}
+
+	opts = append(opts, jwt.WithValidMethods(methods))
+	_, err := jwt.ParseWithClaims(tokenString, claims, s.keyFunc, opts...)
+	return err
+}
+
+func (s *KeySet) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	k, ok := s.keys[kid]
+	if !ok {
+		return nil, errUnknownKey
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, errAlgMismatch
+	}
+	return k.public, nil
+}
+
+// JWKS returns the public keys of the set, ordered by kid. HMAC secrets are left out.
+func (s *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(s.keys))}
+	for _, k := range s.keys {
+		if k.jwk != nil {
+			set.Keys = append(set.Keys, *k.jwk)
+		}
+	}
+	slices.SortFunc(set.Keys, func(a, b JWK) int { return strings.Compare(a.KeyID, b.KeyID) })
+	return set
+}
+
+// parsePrivateKey reads a PKCS #8 RSA or Ed25519 key, or a PKCS #1 RSA key.
+func parsePrivateKey(pemBytes []byte) (*key, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errNoPEM
+	}
+
+	var priv any
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		priv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, errUnsupported
+	}
+	k, err := newKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	k.signing = priv
+	return k, nil
+}
+
+// parsePublicKey reads a PKIX RSA or Ed25519 public key, or a PKCS #1 RSA public key.
+func parsePublicKey(pemBytes []byte) (*key, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errNoPEM
+	}
+
+	var pub any
+	var err error
+	if block.Type == "RSA PUBLIC KEY" {
+		pub, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	} else {
+		pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newKey(pub)
+}
+
+// newKey describes a public key as a JWK identified by its thumbprint.
+func newKey(pub crypto.PublicKey) (*key, error) {
+	var (
+		k          = &key{public: pub}
+		thumbprint string
+	)
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < minRSABits {
+			return nil, errRSAKeyTooWeak
+		}
+		k.method = jwt.SigningMethodRS256
+		k.jwk = &JWK{
+			KeyType: "RSA",
+			N:       base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}
+		thumbprint = `{"e":"` + k.jwk.E + `","kty":"RSA","n":"` + k.jwk.N + `"}`
+	case ed25519.PublicKey:
+		k.method = jwt.SigningMethodEdDSA
+		k.jwk = &JWK{KeyType: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(pub)}
+		thumbprint = `{"crv":"Ed25519","kty":"OKP","x":"` + k.jwk.X + `"}`
+	default:
+		return nil, errUnsupported
+	}
+
+	sum := sha256.Sum256([]byte(thumbprint))
+	k.jwk.KeyID = base64.RawURLEncoding.EncodeToString(sum[:])
+	k.jwk.Use = "sig"
+	k.jwk.Alg = k.method.Alg()
+	return k, nil
+}
@@ -0,0 +1,142 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/auth"
+)
+
+// writeKey writes priv as a PKCS #8 PEM file and its public key as a PKIX PEM file,
+// returning both paths.
+func writeKey(t *testing.T, name string, priv crypto.Signer) (string, string) {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	privFile := filepath.Join(t.TempDir(), name+".pem")
+	require.NoError(t, os.WriteFile(privFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	der, err = x509.MarshalPKIXPublicKey(priv.Public())
+	require.NoError(t, err)
+	pubFile := filepath.Join(t.TempDir(), name+".pub.pem")
+	require.NoError(t, os.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	return privFile, pubFile
+}
+
+func claims(sub string) *auth.Claims {
+	return &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   sub,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}}
+}
+
+func TestKeySet_SignParse(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		key  crypto.Signer
+		alg  string
+	}{
+		{name: "EdDSA", key: edKey, alg: "EdDSA"},
+		{name: "RS256", key: rsaKey, alg: "RS256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			privFile, _ := writeKey(t, "key", tt.key)
+			keys, err := auth.LoadKeySet(privFile, nil)
+			require.NoError(t, err)
+
+			token, err := keys.Sign(claims("user-1"))
+			require.NoError(t, err)
+
+			var parsed auth.Claims
+			require.NoError(t, keys.Parse(token, &parsed))
+			require.Equal(t, "user-1", parsed.Subject)
+
+			jwks := keys.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			require.Equal(t, tt.alg, jwks.Keys[0].Alg)
+			require.Equal(t, "sig", jwks.Keys[0].Use)
+
+			header, _, err := jwt.NewParser().ParseUnverified(token, &auth.Claims{})
+			require.NoError(t, err)
+			require.Equal(t, jwks.Keys[0].KeyID, header.Header["kid"])
+		})
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	oldPriv, oldPub := writeKey(t, "old", oldKey)
+	newPriv, _ := writeKey(t, "new", newKey)
+
+	before, err := auth.LoadKeySet(oldPriv, nil)
+	require.NoError(t, err)
+	token, err := before.Sign(claims("user-1"))
+	require.NoError(t, err)
+
+	// Once rotated, tokens of the old key are accepted while it is still listed.
+	during, err := auth.LoadKeySet(newPriv, []string{oldPub})
+	require.NoError(t, err)
+	require.NoError(t, during.Parse(token, &auth.Claims{}))
+	require.Len(t, during.JWKS().Keys, 2)
+
+	after, err := auth.LoadKeySet(newPriv, nil)
+	require.NoError(t, err)
+	require.Error(t, after.Parse(token, &auth.Claims{}))
+}
+
+func TestKeySet_RejectsForgedAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privFile, pubFile := writeKey(t, "key", rsaKey)
+	keys, err := auth.LoadKeySet(privFile, nil)
+	require.NoError(t, err)
+	kid := keys.JWKS().Keys[0].KeyID
+
+	pubPEM, err := os.ReadFile(pubFile)
+	require.NoError(t, err)
+
+	// HS256 signed with the public key, as if it were a shared secret.
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims("user-1"))
+	hmacToken.Header["kid"] = kid
+	forged, err := hmacToken.SignedString(pubPEM)
+	require.NoError(t, err)
+	require.Error(t, keys.Parse(forged, &auth.Claims{}))
+
+	noneToken := jwt.NewWithClaims(jwt.SigningMethodNone, claims("user-1"))
+	noneToken.Header["kid"] = kid
+	unsigned, err := noneToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	require.Error(t, keys.Parse(unsigned, &auth.Claims{}))
+}
+
+func TestLoadKeySet_RejectsWeakRSAKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	privFile, _ := writeKey(t, "weak", rsaKey)
+
+	_, err = auth.LoadKeySet(privFile, nil)
+	require.ErrorContains(t, err, "at least 2048 bits")
+}
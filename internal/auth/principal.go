@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// Claims are the JWT claims of the access tokens.
+// The subject holds the user ID, and the ID the jti the token is revoked by.
+type Claims struct {
+	Roles []models.Role `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+// Principal is the authenticated user making a request.
+type Principal struct {
+	UserID string
+	Roles  []models.Role
+	// TokenID and TokenExpiresAt are the jti and expiry of the access token, if any.
+	TokenID        string
+	TokenExpiresAt time.Time
+}
+
+// HasRole reports whether any of the principal's roles includes role.
+func (p *Principal) HasRole(role models.Role) bool {
+	for _, r := range p.Roles {
+		if r.Includes(role) {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal stored in ctx, if any.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
@@ -0,0 +1,13 @@
+// Package backoff computes the delays between the attempts of failing operations.
+package backoff
+
+import "time"
+
+// Exponential returns base doubled n times, capped at maxDelay.
+func Exponential(base, maxDelay time.Duration, n int) time.Duration {
+	d := base
+	for i := 0; i < n && d < maxDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxDelay)
+}
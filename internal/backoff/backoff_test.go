@@ -0,0 +1,29 @@
+package backoff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/backoff"
+)
+
+func TestExponential(t *testing.T) {
+	tests := []struct {
+		name     string
+		n        int
+		expected time.Duration
+	}{
+		{name: "first", n: 0, expected: time.Second},
+		{name: "doubled", n: 3, expected: 8 * time.Second},
+		{name: "capped", n: 10, expected: time.Minute},
+		{name: "does not overflow", n: 1000, expected: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, backoff.Exponential(time.Second, time.Minute, tt.n))
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// Package changefeed fans out the changes committed to companies to in-process subscribers.
+package changefeed
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+const (
+	// subscriberBuffer is how many changes a subscriber may fall behind before it is dropped.
+	subscriberBuffer = 64
+	// historySize is how many of the latest changes are kept for subscribers resuming after a disconnect.
+	historySize = 1024
+)
+
+// Change is a committed change to a company.
+// Before and After are snapshots of the company around the change, as in models.AuditEntry.
+type Change struct {
+	// ID increases with every change published by the broadcaster.
+	ID        uint64
+	Action    models.AuditAction
+	CompanyID uuid.UUID
+	Actor     string
+	Before    *models.Company
+	After     *models.Company
+	Time      time.Time
+}
+
+// Filter selects changes by company. Empty lists match every company.
+type Filter struct {
+	CompanyIDs []uuid.UUID
+	// Types match a change if the company had one of them before or after it.
+	Types []models.CompanyType
+}
+
+// Match reports whether c passes the filter.
+func (f *Filter) Match(c *Change) bool {
+	if len(f.CompanyIDs) > 0 && !slices.Contains(f.CompanyIDs, c.CompanyID) {
+		return false
+	}
+	return len(f.Types) == 0 || f.hasType(c.Before) || f.hasType(c.After)
+}
+
+func (f *Filter) hasType(c *models.Company) bool {
+	return c != nil && c.Type != nil && slices.Contains(f.Types, *c.Type)
+}
+
+// Broadcaster delivers every published change to all current subscribers and
+// keeps the latest ones so that subscribers can resume where they left off.
+// Publishing never blocks: a subscriber that falls too far behind is dropped.
+type Broadcaster struct {
+	// epoch tells apart the event IDs of different processes, whose change IDs restart at 1.
+	epoch string
+
+	mu      sync.Mutex
+	lastID  uint64
+	history []Change
+	subs    map[*Subscription]struct{}
+	closed  bool
+}
+
+// NewBroadcaster creates a broadcaster without subscribers.
+func NewBroadcaster() *Broadcaster {
+	epoch := make([]byte, 4)
+	_, _ = rand.Read(epoch)
+
+	return &Broadcaster{
+		epoch: hex.EncodeToString(epoch),
+		subs:  make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the changes published after it was made.
+type Subscription struct {
+	// Backlog holds the retained changes published after the resumed event, oldest first.
+	Backlog []Change
+	// Gap reports that changes after the resumed event were lost, for instance because
+	// they are no longer retained or were published by another process.
+	Gap bool
+	// Latest is the event ID of the last change published before the subscription.
+	// Resuming from it misses nothing published afterwards.
+	Latest string
+	// Changes receives the changes published from now on. It is closed when the
+	// subscription is canceled, dropped for lagging or the broadcaster is closed.
+	Changes <-chan Change
+
+	b      *Broadcaster
+	ch     chan Change
+	lagged bool
+}
+
+// Cancel ends the subscription.
+func (s *Subscription) Cancel() {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	s.b.remove(s)
+}
+
+// Lagged reports whether the subscription was dropped for falling too far behind.
+func (s *Subscription) Lagged() bool {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	return s.lagged
+}
+
+// EventID returns the ID clients resume from after receiving c.
+func (b *Broadcaster) EventID(c *Change) string {
+	return b.epoch + "-" + strconv.FormatUint(c.ID, 10)
+}
+
+// Subscribe returns a subscription to the changes published from now on.
+// With a lastEventID from EventID, the retained changes published after that
+// event are returned in the Backlog first.
+func (b *Broadcaster) Subscribe(lastEventID string) *Subscription {
+	ch := make(chan Change, subscriberBuffer)
+	s := &Subscription{Changes: ch, b: b, ch: ch}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s.Latest = b.epoch + "-" + strconv.FormatUint(b.lastID, 10)
+	if lastEventID != "" {
+		s.Backlog, s.Gap = b.since(lastEventID)
+	}
+	if b.closed {
+		close(ch)
+	} else {
+		b.subs[s] = struct{}{}
+	}
+	return s
+}
+
+// since returns the retained changes after the event lastEventID, and whether
+// some changes after it are missing.
+func (b *Broadcaster) since(lastEventID string) ([]Change, bool) {
+	epoch, seq, ok := strings.Cut(lastEventID, "-")
+	id, err := strconv.ParseUint(seq, 10, 64)
+	if !ok || err != nil || epoch != b.epoch || id > b.lastID {
+		return nil, true
+	}
+
+	if len(b.history) > 0 && id+1 < b.history[0].ID {
+		return nil, true
+	}
+	for i, c := range b.history {
+		if c.ID > id {
+			return append([]Change(nil), b.history[i:]...), false
+		}
+	}
+	return nil, false
+}
+
+// Publish assigns c the next ID and sends it to the subscribers.
+func (b *Broadcaster) Publish(c Change) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	c.ID = b.lastID
+	if len(b.history) == historySize {
+		b.history = append(b.history[:0], b.history[1:]...)
+	}
+	b.history = append(b.history, c)
+
+	for s := range b.subs {
+		select {
+		case s.ch <- c:
+		default:
+			s.lagged = true
+			b.remove(s)
+		}
+	}
+}
+
+// Close ends every subscription, and those made afterwards, as the service shuts down.
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for s := range b.subs {
+		b.remove(s)
+	}
+}
+
+func (b *Broadcaster) remove(s *Subscription) {
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
@@ -0,0 +1,129 @@
+package changefeed_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/changefeed"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+func TestBroadcaster_Lagging(t *testing.T) {
+	b := changefeed.NewBroadcaster()
+	id := uuid.New()
+
+	b.Publish(changefeed.Change{Action: models.ActionCreated, CompanyID: id})
+
+	fast := b.Subscribe("")
+	defer fast.Cancel()
+	slow := b.Subscribe("")
+	defer slow.Cancel()
+
+	b.Publish(changefeed.Change{Action: models.ActionUpdated, CompanyID: id})
+	first := <-fast.Changes
+	require.Equal(t, uint64(2), first.ID, "changes published before subscribing are not delivered")
+	require.Equal(t, models.ActionUpdated, first.Action)
+
+	// The slow subscriber never reads, so it is dropped once its buffer is full.
+	for i := 0; i < 100; i++ {
+		b.Publish(changefeed.Change{Action: models.ActionUpdated, CompanyID: id})
+		<-fast.Changes
+	}
+
+	var received int
+	for range slow.Changes {
+		received++
+	}
+	require.Equal(t, 64, received)
+	require.True(t, slow.Lagged())
+	require.False(t, fast.Lagged())
+
+	slow.Cancel() // canceling a dropped subscription is a no-op
+	b.Close()
+	_, ok := <-fast.Changes
+	require.False(t, ok)
+	require.False(t, fast.Lagged())
+
+	_, ok = <-b.Subscribe("").Changes
+	require.False(t, ok, "subscriptions made after closing are closed")
+}
+
+func TestBroadcaster_Resume(t *testing.T) {
+	b := changefeed.NewBroadcaster()
+	epoch, _, _ := strings.Cut(b.Subscribe("").Latest, "-")
+	eventID := func(id int) string { return epoch + "-" + strconv.Itoa(id) }
+
+	for i := 0; i < 1100; i++ {
+		b.Publish(changefeed.Change{Action: models.ActionUpdated, CompanyID: uuid.New()})
+	}
+
+	tests := []struct {
+		name            string
+		lastEventID     string
+		expectedBacklog int
+		expectedGap     bool
+	}{
+		{name: "live only"},
+		{name: "up to date", lastEventID: eventID(1100)},
+		{name: "retained", lastEventID: eventID(1090), expectedBacklog: 10},
+		{name: "oldest retained", lastEventID: eventID(76), expectedBacklog: 1024},
+		{name: "no longer retained", lastEventID: eventID(75), expectedGap: true},
+		{name: "other process", lastEventID: "00000000-1090", expectedGap: true},
+		{name: "from the future", lastEventID: eventID(1101), expectedGap: true},
+		{name: "malformed", lastEventID: "1090", expectedGap: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := b.Subscribe(tt.lastEventID)
+			defer sub.Cancel()
+
+			require.Len(t, sub.Backlog, tt.expectedBacklog)
+			require.Equal(t, tt.expectedGap, sub.Gap)
+			require.Equal(t, eventID(1100), sub.Latest)
+			if tt.expectedBacklog > 0 {
+				require.Equal(t, uint64(1100), sub.Backlog[len(sub.Backlog)-1].ID)
+			}
+		})
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	id := uuid.New()
+	corporation, nonProfit := models.Corporation, models.NonProfit
+	change := &changefeed.Change{
+		CompanyID: id,
+		Before:    &models.Company{Type: &corporation},
+		After:     &models.Company{Type: &nonProfit},
+	}
+
+	tests := []struct {
+		name     string
+		filter   changefeed.Filter
+		expected bool
+	}{
+		{name: "empty", expected: true},
+		{name: "company", filter: changefeed.Filter{CompanyIDs: []uuid.UUID{uuid.New(), id}}, expected: true},
+		{name: "other company", filter: changefeed.Filter{CompanyIDs: []uuid.UUID{uuid.New()}}},
+		{name: "type before", filter: changefeed.Filter{Types: []models.CompanyType{corporation}}, expected: true},
+		{name: "type after", filter: changefeed.Filter{Types: []models.CompanyType{nonProfit}}, expected: true},
+		{name: "other type", filter: changefeed.Filter{Types: []models.CompanyType{models.Cooperative}}},
+		{
+			name: "company and other type",
+			filter: changefeed.Filter{
+				CompanyIDs: []uuid.UUID{id},
+				Types:      []models.CompanyType{models.Cooperative},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.filter.Match(change))
+		})
+	}
+}
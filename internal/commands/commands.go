@@ -0,0 +1,146 @@
+// Package commands applies the company commands consumed from Kafka.
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	segkafka "github.com/segmentio/kafka-go"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/auth"
+	"github.com/dagherghinescu/companies/internal/kafka"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+var (
+	ErrUnknownOperation = app.Invalid("op must be create, patch or delete")
+	ErrInvalidCompanyID = app.Invalid("invalid company id")
+	ErrMissingData      = app.Invalid("data is required")
+	ErrNoFields         = app.Invalid("no fields to update")
+)
+
+// Command is a create, patch or delete of a company, in the shape of a batch operation.
+// Data holds the company to create or the fields to patch. ID is optional for creates;
+// setting it makes them safe to redeliver. Deletes are safe to redeliver, and so are
+// patches without IfMatch, which set the same fields again.
+type Command struct {
+	Op      app.BatchOp     `json:"op"`
+	ID      string          `json:"id,omitempty"`
+	IfMatch *int64          `json:"if_match,omitempty"`
+	Data    *models.Company `json:"data,omitempty"`
+}
+
+// Handler returns a kafka.Handler applying the command in each message through appl,
+// on behalf of actor. Commands that are malformed or rejected by the app, such as
+// invalid companies or version mismatches, fail permanently.
+func Handler(appl *app.App, actor string) kafka.Handler {
+	principal := &auth.Principal{UserID: actor, Roles: []models.Role{models.RoleAdmin}}
+
+	return func(ctx context.Context, msg segkafka.Message) error {
+		var cmd Command
+		if err := json.Unmarshal(msg.Value, &cmd); err != nil {
+			return kafka.Permanent(app.Invalid("invalid command: " + err.Error()))
+		}
+
+		err := apply(auth.WithPrincipal(ctx, principal), appl, &cmd)
+		if app.AsError(err) != nil {
+			return kafka.Permanent(err)
+		}
+		return err
+	}
+}
+
+// apply runs cmd through appl.
+func apply(ctx context.Context, appl *app.App, cmd *Command) error {
+	switch cmd.Op {
+	case app.OpCreate:
+		return create(ctx, appl, cmd)
+	case app.OpPatch:
+		return patch(ctx, appl, cmd)
+	case app.OpDelete:
+		return remove(ctx, appl, cmd)
+	default:
+		return ErrUnknownOperation
+	}
+}
+
+// create adds the company of cmd. A create with an ID that already exists was applied
+// by an earlier delivery of the command, and succeeds without changing anything.
+func create(ctx context.Context, appl *app.App, cmd *Command) error {
+	if cmd.Data == nil {
+		return ErrMissingData
+	}
+	if err := cmd.Data.Validate(); err != nil {
+		return err
+	}
+
+	company := *cmd.Data
+	company.ID = uuid.New()
+	if cmd.ID != "" {
+		id, err := uuid.Parse(cmd.ID)
+		if err != nil {
+			return ErrInvalidCompanyID
+		}
+		company.ID = id
+	}
+
+	err := appl.CreateCompany(ctx, &company)
+	if errors.Is(err, app.ErrCompanyAlreadyExists) && cmd.ID != "" {
+		if _, getErr := appl.GetCompany(ctx, company.ID); getErr == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// patch sets the fields of cmd on its company. A redelivered patch with IfMatch fails
+// with a version mismatch once an earlier delivery was applied, and a patch of a company
+// deleted since fails as not found; both are permanent, as for any stale command.
+func patch(ctx context.Context, appl *app.App, cmd *Command) error {
+	id, err := uuid.Parse(cmd.ID)
+	if err != nil {
+		return ErrInvalidCompanyID
+	}
+	if cmd.Data == nil {
+		return ErrMissingData
+	}
+	fields := cmd.Data.PatchFields()
+	if len(fields) == 0 {
+		return ErrNoFields
+	}
+	if err := cmd.Data.ValidatePatch(); err != nil {
+		return err
+	}
+
+	_, err = appl.PatchCompany(ctx, id, fields, cmd.IfMatch)
+	return err
+}
+
+// remove deletes the company of cmd. A delete of a company that is already deleted was
+// applied by an earlier delivery of the command, and succeeds without changing anything.
+func remove(ctx context.Context, appl *app.App, cmd *Command) error {
+	id, err := uuid.Parse(cmd.ID)
+	if err != nil {
+		return ErrInvalidCompanyID
+	}
+
+	err = appl.DeleteCompany(ctx, id, cmd.IfMatch)
+	if !errors.Is(err, app.ErrCompanyNotFound) {
+		return err
+	}
+
+	page, histErr := appl.CompanyHistory(ctx, id, 1, "")
+	switch {
+	case errors.Is(histErr, app.ErrCompanyNotFound):
+		return err
+	case histErr != nil:
+		return histErr
+	}
+	if last := page.Entries[0].Action; last == models.ActionDeleted || last == models.ActionPurged {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,203 @@
+package commands_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	segkafka "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/commands"
+	"github.com/dagherghinescu/companies/internal/kafka"
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+// memRepo keeps companies in memory. Methods the commands do not use panic.
+type memRepo struct {
+	repository.Company
+	companies map[uuid.UUID]models.Company
+	history   []models.AuditEntry
+	audit     []models.AuditEntry
+	err       error
+}
+
+func (m *memRepo) GetByID(_ context.Context, id uuid.UUID) (*models.Company, error) {
+	c, ok := m.companies[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &c, nil
+}
+func (m *memRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Company, error) {
+	return m.GetByID(ctx, id)
+}
+func (m *memRepo) Create(_ context.Context, c *models.Company) error {
+	if m.err != nil {
+		return m.err
+	}
+	for _, existing := range m.companies {
+		if existing.ID == c.ID || *existing.Name == *c.Name {
+			return &pq.Error{Code: "23505"}
+		}
+	}
+	m.companies[c.ID] = *c
+	return nil
+}
+func (m *memRepo) Patch(_ context.Context, id uuid.UUID, fields map[string]interface{}, version *int64) error {
+	c := m.companies[id]
+	if version != nil && *version != c.Version {
+		return repository.ErrVersionMismatch
+	}
+	if name, ok := fields["name"].(string); ok {
+		c.Name = &name
+	}
+	c.Version++
+	m.companies[id] = c
+	return nil
+}
+func (m *memRepo) Delete(_ context.Context, id uuid.UUID, _ string, _ *int64) error {
+	delete(m.companies, id)
+	return nil
+}
+func (m *memRepo) AddOutboxEvent(_ context.Context, _ string, _ []byte) error { return nil }
+func (m *memRepo) AddAudit(_ context.Context, e *models.AuditEntry) error {
+	m.audit = append(m.audit, *e)
+	return nil
+}
+func (m *memRepo) History(_ context.Context, id uuid.UUID, _ uint64, _ string) (*repository.HistoryPage, error) {
+	page := &repository.HistoryPage{}
+	for _, e := range slices.Concat(m.history, m.audit) {
+		if e.CompanyID == id {
+			page.Entries = append([]models.AuditEntry{e}, page.Entries...)
+		}
+	}
+	return page, nil
+}
+func (m *memRepo) InTx(_ context.Context, fn func(tx repository.Company) error) error {
+	return fn(m)
+}
+
+func TestHandler(t *testing.T) {
+	existing, deleted := uuid.New(), uuid.New()
+	company := `{"name":"Acme","amount_of_employees":10,"registered":true,"type":"Corporation"}`
+
+	tests := []struct {
+		name              string
+		value             string
+		repoErr           error
+		expectedErr       bool
+		expectedPermanent bool
+		expectedAudit     models.AuditAction
+	}{
+		{
+			name: "create",
+			value: `{"op":"create","data":{"name":"Initech","amount_of_employees":5,"registered":false,` +
+				`"type":"NonProfit"}}`,
+			expectedAudit: models.ActionCreated,
+		},
+		{
+			name:  "create redelivered",
+			value: `{"op":"create","id":"` + existing.String() + `","data":` + company + `}`,
+		},
+		{
+			name:              "create with a taken name",
+			value:             `{"op":"create","data":` + company + `}`,
+			expectedErr:       true,
+			expectedPermanent: true,
+		},
+		{
+			name:              "create an invalid company",
+			value:             `{"op":"create","data":{"name":"Initech"}}`,
+			expectedErr:       true,
+			expectedPermanent: true,
+		},
+		{
+			name:          "patch",
+			value:         `{"op":"patch","id":"` + existing.String() + `","if_match":1,"data":{"name":"Acme Corp"}}`,
+			expectedAudit: models.ActionUpdated,
+		},
+		{
+			name:              "patch a stale version",
+			value:             `{"op":"patch","id":"` + existing.String() + `","if_match":3,"data":{"name":"X"}}`,
+			expectedErr:       true,
+			expectedPermanent: true,
+		},
+		{
+			name:          "delete",
+			value:         `{"op":"delete","id":"` + existing.String() + `"}`,
+			expectedAudit: models.ActionDeleted,
+		},
+		{
+			name:  "delete redelivered",
+			value: `{"op":"delete","id":"` + deleted.String() + `","if_match":1}`,
+		},
+		{
+			name:              "delete a missing company",
+			value:             `{"op":"delete","id":"` + uuid.NewString() + `"}`,
+			expectedErr:       true,
+			expectedPermanent: true,
+		},
+		{
+			name:              "malformed",
+			value:             `{"op":`,
+			expectedErr:       true,
+			expectedPermanent: true,
+		},
+		{
+			name:              "unknown op",
+			value:             `{"op":"rename","id":"` + existing.String() + `"}`,
+			expectedErr:       true,
+			expectedPermanent: true,
+		},
+		{
+			name: "database unavailable",
+			value: `{"op":"create","data":{"name":"Initech","amount_of_employees":5,"registered":false,` +
+				`"type":"NonProfit"}}`,
+			repoErr:     errors.New("connection refused"),
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, employees, registered, typ := "Acme", 10, true, models.Corporation
+			repo := &memRepo{
+				companies: map[uuid.UUID]models.Company{existing: {
+					ID: existing, Name: &name, AmountEmployees: &employees, Registered: &registered,
+					Type: &typ, Version: 1,
+				}},
+				history: []models.AuditEntry{
+					{CompanyID: deleted, Action: models.ActionCreated},
+					{CompanyID: deleted, Action: models.ActionDeleted},
+				},
+				err: tt.repoErr,
+			}
+			appl := app.New(zap.NewNop(), repo, &app.Config{})
+
+			err := commands.Handler(appl, "kafka")(context.Background(), segkafka.Message{Value: []byte(tt.value)})
+			if tt.expectedErr {
+				require.Error(t, err)
+				require.Equal(t, tt.expectedPermanent, kafka.IsPermanent(err), err)
+				require.Empty(t, repo.audit)
+				return
+			}
+			require.NoError(t, err)
+
+			if tt.expectedAudit == "" {
+				require.Empty(t, repo.audit)
+				return
+			}
+			require.Len(t, repo.audit, 1)
+			require.Equal(t, tt.expectedAudit, repo.audit[0].Action)
+			require.Equal(t, "kafka", repo.audit[0].Actor)
+		})
+	}
+}
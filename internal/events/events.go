@@ -0,0 +1,155 @@
+// Package events defines the events published when companies change, as CloudEvents 1.0.
+package events
+
+import (
+	"embed"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+const (
+	// SpecVersion is the CloudEvents version of the events.
+	SpecVersion = "1.0"
+	// Source identifies this service as the producer of the events.
+	Source = "/companies"
+	// DataContentType is the media type of the event data.
+	DataContentType = "application/json"
+	// StructuredContentType is the media type of an event encoded as a single JSON document.
+	StructuredContentType = "application/cloudevents+json"
+)
+
+// typePrefix starts every event type; the last segment of a type is its version.
+const typePrefix = "com.github.dagherghinescu.companies."
+
+// Company event types. An incompatible change to the data of an event type is
+// published under a new version, next to the old one, rather than in place.
+const (
+	CompanyCreatedV1  = typePrefix + "company.created.v1"
+	CompanyUpdatedV1  = typePrefix + "company.updated.v1"
+	CompanyDeletedV1  = typePrefix + "company.deleted.v1"
+	CompanyRestoredV1 = typePrefix + "company.restored.v1"
+	CompanyPurgedV1   = typePrefix + "company.purged.v1"
+	// CompanySnapshotV1 carries the current state of a company rather than a change.
+	// Snapshots are only published by replays, to rebuild downstream consumers.
+	CompanySnapshotV1 = typePrefix + "company.snapshot.v1"
+)
+
+// ErrUnknownAction is returned for changes no event type exists for.
+var ErrUnknownAction = errors.New("no event type for action")
+
+//go:embed schemas/*.json
+var schemas embed.FS
+
+// Event is a CloudEvent in the JSON event format, holding data of type T.
+type Event[T any] struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype,omitempty"`
+	DataSchema      string    `json:"dataschema,omitempty"`
+	Data            T         `json:"data"`
+}
+
+// CompanyChangedV1 is the data of version 1 of the company events. Before and After are
+// the full company around the change; Before is null for creations and restorations,
+// After once the company is deleted or purged.
+type CompanyChangedV1 struct {
+	CompanyID uuid.UUID          `json:"company_id"`
+	Action    models.AuditAction `json:"action"`
+	Actor     string             `json:"actor"`
+	Before    *models.Company    `json:"before"`
+	After     *models.Company    `json:"after"`
+	// ChangedFields lists the fields set by an update, by their JSON names.
+	ChangedFields []string `json:"changed_fields,omitempty"`
+}
+
+// CompanyStateV1 is the data of version 1 of the company snapshot events.
+type CompanyStateV1 struct {
+	CompanyID uuid.UUID       `json:"company_id"`
+	Company   *models.Company `json:"company"`
+}
+
+// NewCompanyEvent wraps a change in an event of the type matching its action.
+// schemaURL is the base URL the schemas are published at; when empty the
+// event has no dataschema.
+func NewCompanyEvent(data *CompanyChangedV1, schemaURL string) (*Event[*CompanyChangedV1], error) {
+	typ, err := companyType(data.Action)
+	if err != nil {
+		return nil, err
+	}
+
+	return newEvent(typ, data.CompanyID, data, schemaURL), nil
+}
+
+// NewSnapshotEvent returns an event holding the current state of c.
+func NewSnapshotEvent(c *models.Company, schemaURL string) *Event[*CompanyStateV1] {
+	return newEvent(CompanySnapshotV1, c.ID, &CompanyStateV1{CompanyID: c.ID, Company: c}, schemaURL)
+}
+
+func newEvent[T any](typ string, companyID uuid.UUID, data T, schemaURL string) *Event[T] {
+	e := &Event[T]{
+		SpecVersion:     SpecVersion,
+		ID:              uuid.NewString(),
+		Source:          Source,
+		Type:            typ,
+		Subject:         companyID.String(),
+		Time:            time.Now().UTC(),
+		DataContentType: DataContentType,
+		Data:            data,
+	}
+	if schemaURL != "" {
+		e.DataSchema = strings.TrimSuffix(schemaURL, "/") + "/" + SchemaName(typ)
+	}
+	return e
+}
+
+func companyType(action models.AuditAction) (string, error) {
+	switch action {
+	case models.ActionCreated:
+		return CompanyCreatedV1, nil
+	case models.ActionUpdated:
+		return CompanyUpdatedV1, nil
+	case models.ActionDeleted:
+		return CompanyDeletedV1, nil
+	case models.ActionRestored:
+		return CompanyRestoredV1, nil
+	case models.ActionPurged:
+		return CompanyPurgedV1, nil
+	default:
+		return "", ErrUnknownAction
+	}
+}
+
+// Types returns every event type.
+func Types() []string {
+	return []string{
+		CompanyCreatedV1, CompanyUpdatedV1, CompanyDeletedV1, CompanyRestoredV1, CompanyPurgedV1, CompanySnapshotV1,
+	}
+}
+
+// SchemaName returns the file name of the JSON Schema of the data of an event type,
+// such as company.created.v1.json.
+func SchemaName(eventType string) string {
+	return strings.TrimPrefix(eventType, typePrefix) + ".json"
+}
+
+// Schema returns the JSON Schema named name, and false if there is none.
+func Schema(name string) ([]byte, bool) {
+	if strings.Contains(name, "/") {
+		return nil, false
+	}
+
+	data, err := schemas.ReadFile("schemas/" + name)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
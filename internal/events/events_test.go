@@ -0,0 +1,188 @@
+package events_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/events"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// compileSchema compiles the schema of the data of an event type.
+func compileSchema(t *testing.T, eventType string) *jsonschema.Schema {
+	t.Helper()
+
+	data, ok := events.Schema(events.SchemaName(eventType))
+	require.True(t, ok, "no schema for %s", eventType)
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	c := jsonschema.NewCompiler()
+	require.NoError(t, c.AddResource("schema.json", doc))
+	schema, err := c.Compile("schema.json")
+	require.NoError(t, err)
+	return schema
+}
+
+func TestNewCompanyEvent(t *testing.T) {
+	id := uuid.New()
+	corporation := models.Corporation
+	before := &models.Company{
+		ID: id, Name: ptrString("Acme"), AmountEmployees: ptrInt(10), Registered: ptrBool(true),
+		Type: &corporation, Version: 1,
+	}
+	after := *before
+	after.AmountEmployees, after.Version = ptrInt(12), 2
+
+	tests := []struct {
+		name         string
+		data         events.CompanyChangedV1
+		expectedType string
+		expectedErr  bool
+	}{
+		{
+			name:         "created",
+			data:         events.CompanyChangedV1{Action: models.ActionCreated, After: before},
+			expectedType: events.CompanyCreatedV1,
+		},
+		{
+			name: "updated",
+			data: events.CompanyChangedV1{
+				Action: models.ActionUpdated, Before: before, After: &after,
+				ChangedFields: []string{"amount_of_employees"},
+			},
+			expectedType: events.CompanyUpdatedV1,
+		},
+		{
+			name:         "deleted",
+			data:         events.CompanyChangedV1{Action: models.ActionDeleted, Before: &after},
+			expectedType: events.CompanyDeletedV1,
+		},
+		{
+			name:         "restored",
+			data:         events.CompanyChangedV1{Action: models.ActionRestored, After: &after},
+			expectedType: events.CompanyRestoredV1,
+		},
+		{
+			name:         "purged",
+			data:         events.CompanyChangedV1{Action: models.ActionPurged},
+			expectedType: events.CompanyPurgedV1,
+		},
+		{
+			name:        "unknown action",
+			data:        events.CompanyChangedV1{Action: "renamed"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.data.CompanyID, tt.data.Actor = id, "user-1"
+
+			e, err := events.NewCompanyEvent(&tt.data, "http://localhost:8080/events/schemas/")
+			if tt.expectedErr {
+				require.ErrorIs(t, err, events.ErrUnknownAction)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Equal(t, events.SpecVersion, e.SpecVersion)
+			require.Equal(t, events.Source, e.Source)
+			require.Equal(t, tt.expectedType, e.Type)
+			require.Equal(t, id.String(), e.Subject)
+			require.NotEmpty(t, e.ID)
+			require.Equal(t, "http://localhost:8080/events/schemas/"+events.SchemaName(tt.expectedType), e.DataSchema)
+
+			// The data must match the schema published for its type.
+			raw, err := json.Marshal(e)
+			require.NoError(t, err)
+			var decoded events.Event[any]
+			require.NoError(t, json.Unmarshal(raw, &decoded))
+			require.NoError(t, compileSchema(t, e.Type).Validate(decoded.Data))
+		})
+	}
+}
+
+func TestNewSnapshotEvent(t *testing.T) {
+	nonProfit := models.NonProfit
+	c := &models.Company{
+		ID: uuid.New(), Name: ptrString("Acme"), AmountEmployees: ptrInt(10), Registered: ptrBool(false),
+		Type: &nonProfit, Version: 3,
+	}
+
+	e := events.NewSnapshotEvent(c, "")
+	require.Equal(t, events.CompanySnapshotV1, e.Type)
+	require.Equal(t, c.ID.String(), e.Subject)
+	require.Empty(t, e.DataSchema)
+
+	raw, err := json.Marshal(e)
+	require.NoError(t, err)
+	var decoded events.Event[any]
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	require.NoError(t, compileSchema(t, e.Type).Validate(decoded.Data))
+}
+
+func TestSchemas_RejectMismatchedData(t *testing.T) {
+	company := `{"id":"3f2c8d4e-6b1a-4f5e-9c7d-2a1b0e9f8d7c","name":"Acme","amount_of_employees":10,` +
+		`"registered":true,"type":"Corporation","version":1}`
+
+	tests := []struct {
+		name      string
+		eventType string
+		data      string
+	}{
+		{
+			name:      "created with a before snapshot",
+			eventType: events.CompanyCreatedV1,
+			data: `{"company_id":"3f2c8d4e-6b1a-4f5e-9c7d-2a1b0e9f8d7c","action":"created","actor":"u",` +
+				`"before":` + company + `,"after":` + company + `}`,
+		},
+		{
+			name:      "updated without changed fields",
+			eventType: events.CompanyUpdatedV1,
+			data: `{"company_id":"3f2c8d4e-6b1a-4f5e-9c7d-2a1b0e9f8d7c","action":"updated","actor":"u",` +
+				`"before":` + company + `,"after":` + company + `}`,
+		},
+		{
+			name:      "snapshot without the company",
+			eventType: events.CompanySnapshotV1,
+			data:      `{"company_id":"3f2c8d4e-6b1a-4f5e-9c7d-2a1b0e9f8d7c","company":null}`,
+		},
+		{
+			name:      "deleted with the wrong action",
+			eventType: events.CompanyDeletedV1,
+			data: `{"company_id":"3f2c8d4e-6b1a-4f5e-9c7d-2a1b0e9f8d7c","action":"purged","actor":"u",` +
+				`"before":` + company + `,"after":null}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := jsonschema.UnmarshalJSON(bytes.NewReader([]byte(tt.data)))
+			require.NoError(t, err)
+			require.Error(t, compileSchema(t, tt.eventType).Validate(data))
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	for _, typ := range events.Types() {
+		_, ok := events.Schema(events.SchemaName(typ))
+		require.True(t, ok, "no schema for %s", typ)
+	}
+
+	_, ok := events.Schema("../events.go")
+	require.False(t, ok)
+	_, ok = events.Schema("company.created.v9.json")
+	require.False(t, ok)
+}
+
+func ptrString(s string) *string { return &s }
+func ptrInt(i int) *int          { return &i }
+func ptrBool(b bool) *bool       { return &b }
@@ -0,0 +1,174 @@
+// Package exporter encodes companies one at a time for streaming exports.
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// Format is the encoding of an export.
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatParquet Format = "parquet"
+)
+
+// parquetRowGroupSize is the number of rows buffered before a Parquet row group is written out.
+const parquetRowGroupSize = 10_000
+
+// ErrUnknownFormat is returned by New for formats other than the Format constants.
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Writer encodes companies to an underlying io.Writer.
+// Close must be called after the last company to flush buffered data.
+type Writer interface {
+	Write(c *models.Company) error
+	Close() error
+}
+
+// New returns a Writer encoding companies to w in the given format.
+func New(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	case FormatParquet:
+		return newParquetWriter(w), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// ContentType returns the media type of exports in format f.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+// Write writes c as a CSV record, preceded by the header on the first call.
+func (w *csvWriter) Write(c *models.Company) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	return w.w.Write([]string{
+		c.ID.String(),
+		deref(c.Name),
+		deref(c.Description),
+		strconv.Itoa(deref(c.AmountEmployees)),
+		strconv.FormatBool(deref(c.Registered)),
+		string(deref(c.Type)),
+		strconv.FormatInt(c.Version, 10),
+	})
+}
+
+// writeHeader writes the header unless it was written already.
+func (w *csvWriter) writeHeader() error {
+	if w.header {
+		return nil
+	}
+
+	w.header = true
+	return w.w.Write([]string{"id", "name", "description", "amount_of_employees", "registered", "type", "version"})
+}
+
+// Close flushes the buffered records. An export without companies still gets its header.
+func (w *csvWriter) Close() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(c *models.Company) error {
+	return w.enc.Encode(c)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
+
+// parquetRow is the Parquet schema of an exported company.
+type parquetRow struct {
+	ID              string  `parquet:"id"`
+	Name            string  `parquet:"name"`
+	Description     *string `parquet:"description,optional"`
+	AmountEmployees int64   `parquet:"amount_of_employees"`
+	Registered      bool    `parquet:"registered"`
+	Type            string  `parquet:"type"`
+	Version         int64   `parquet:"version"`
+}
+
+type parquetWriter struct {
+	w   *parquet.GenericWriter[parquetRow]
+	row []parquetRow
+}
+
+func newParquetWriter(w io.Writer) *parquetWriter {
+	return &parquetWriter{
+		w: parquet.NewGenericWriter[parquetRow](w,
+			parquet.MaxRowsPerRowGroup(parquetRowGroupSize),
+			parquet.Compression(&parquet.Snappy),
+		),
+		row: make([]parquetRow, 1),
+	}
+}
+
+func (w *parquetWriter) Write(c *models.Company) error {
+	w.row[0] = parquetRow{
+		ID:              c.ID.String(),
+		Name:            deref(c.Name),
+		Description:     c.Description,
+		AmountEmployees: int64(deref(c.AmountEmployees)),
+		Registered:      deref(c.Registered),
+		Type:            string(deref(c.Type)),
+		Version:         c.Version,
+	}
+
+	_, err := w.w.Write(w.row)
+	return err
+}
+
+// Close writes the last row group and the Parquet footer.
+func (w *parquetWriter) Close() error {
+	return w.w.Close()
+}
+
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
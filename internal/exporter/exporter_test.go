@@ -0,0 +1,91 @@
+package exporter_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/exporter"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+func companies() []models.Company {
+	name, description, employees, registered, ctype := "Acme", "Sample", 42, true, models.Corporation
+	id := uuid.MustParse("6f1c2f7e-3c3a-4a57-9d55-2f1a0d9e1c11")
+	return []models.Company{
+		{ID: id, Name: &name, Description: &description, AmountEmployees: &employees, Registered: &registered,
+			Type: &ctype, Version: 2},
+		{ID: id, Name: &name, AmountEmployees: &employees, Registered: &registered, Type: &ctype, Version: 1},
+	}
+}
+
+func export(t *testing.T, format exporter.Format, rows []models.Company) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := exporter.New(format, &buf)
+	require.NoError(t, err)
+	for i := range rows {
+		require.NoError(t, w.Write(&rows[i]))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestExport_CSV(t *testing.T) {
+	header := "id,name,description,amount_of_employees,registered,type,version\n"
+
+	tests := []struct {
+		name     string
+		rows     []models.Company
+		expected string
+	}{
+		{
+			name: "companies",
+			rows: companies(),
+			expected: header +
+				"6f1c2f7e-3c3a-4a57-9d55-2f1a0d9e1c11,Acme,Sample,42,true,Corporation,2\n" +
+				"6f1c2f7e-3c3a-4a57-9d55-2f1a0d9e1c11,Acme,,42,true,Corporation,1\n",
+		},
+		{
+			name:     "no companies",
+			expected: header,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, string(export(t, exporter.FormatCSV, tt.rows)))
+		})
+	}
+}
+
+func TestExport_NDJSON(t *testing.T) {
+	out := export(t, exporter.FormatNDJSON, companies())
+	require.Equal(t, 2, bytes.Count(out, []byte("\n")))
+	require.Contains(t, string(out), `"description":"Sample"`)
+}
+
+func TestExport_Parquet(t *testing.T) {
+	out := export(t, exporter.FormatParquet, companies())
+
+	type row struct {
+		Name        string  `parquet:"name"`
+		Description *string `parquet:"description,optional"`
+		Version     int64   `parquet:"version"`
+	}
+	rows, err := parquet.Read[row](bytes.NewReader(out), int64(len(out)))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, "Sample", *rows[0].Description)
+	require.Nil(t, rows[1].Description)
+	require.Equal(t, int64(1), rows[1].Version)
+}
+
+func TestNew_UnknownFormat(t *testing.T) {
+	_, err := exporter.New("xlsx", &bytes.Buffer{})
+	require.ErrorIs(t, err, exporter.ErrUnknownFormat)
+}
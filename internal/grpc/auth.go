@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/auth"
+	companiesv1 "github.com/dagherghinescu/companies/internal/grpc/gen/companies/v1"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// authenticator checks the bearer token in the "authorization" metadata of calls
+// to methods requiring a role, and adds the principal to the call context.
+type authenticator struct {
+	cfg *middleware.JWTConfig
+}
+
+func (a *authenticator) unary(
+	ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (any, error) {
+	ctx, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authenticator) stream(
+	srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+) error {
+	ctx, err := a.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+func (a *authenticator) authorize(ctx context.Context, fullMethod string) (context.Context, error) {
+	role, ok := methodRole(fullMethod)
+	if !ok {
+		return ctx, nil
+	}
+
+	var header string
+	if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+		header = values[0]
+	}
+	principal, err := middleware.Authenticate(ctx, a.cfg, header)
+	if err != nil {
+		return nil, err
+	}
+	if !principal.HasRole(role) {
+		return nil, app.ErrForbidden
+	}
+
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+// methodRole returns the role required to call a method, matching the routes of
+// the REST API: ListCompanies requires viewer like /companies, and Watch like
+// /companies/stream. Methods without a role are public.
+func methodRole(fullMethod string) (models.Role, bool) {
+	switch fullMethod {
+	case companiesv1.CompanyService_CreateCompany_FullMethodName,
+		companiesv1.CompanyService_PatchCompany_FullMethodName:
+		return models.RoleEditor, true
+	case companiesv1.CompanyService_DeleteCompany_FullMethodName:
+		return models.RoleAdmin, true
+	case companiesv1.CompanyService_ListCompanies_FullMethodName,
+		companiesv1.CompanyService_Watch_FullMethodName:
+		return models.RoleViewer, true
+	default:
+		return "", false
+	}
+}
+
+// contextStream replaces the context of a server stream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
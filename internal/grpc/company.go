@@ -0,0 +1,245 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/changefeed"
+	companiesv1 "github.com/dagherghinescu/companies/internal/grpc/gen/companies/v1"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+var errNoFields = app.Invalid("no fields to update")
+
+// GetCompany returns a company by ID.
+func (s *Server) GetCompany(
+	ctx context.Context, req *companiesv1.GetCompanyRequest,
+) (*companiesv1.GetCompanyResponse, error) {
+	id, err := parseID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	company, err := s.app.GetCompany(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &companiesv1.GetCompanyResponse{Company: toProtoCompany(company)}, nil
+}
+
+// ListCompanies returns a page of companies matching the filters of req.
+func (s *Server) ListCompanies(
+	ctx context.Context, req *companiesv1.ListCompaniesRequest,
+) (*companiesv1.ListCompaniesResponse, error) {
+	filter, err := listFilter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.app.ListCompanies(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &companiesv1.ListCompaniesResponse{
+		Companies:  make([]*companiesv1.Company, len(page.Companies)),
+		NextCursor: page.NextCursor,
+	}
+	for i := range page.Companies {
+		resp.Companies[i] = toProtoCompany(&page.Companies[i])
+	}
+	return resp, nil
+}
+
+// CreateCompany validates and creates the company of req.
+func (s *Server) CreateCompany(
+	ctx context.Context, req *companiesv1.CreateCompanyRequest,
+) (*companiesv1.CreateCompanyResponse, error) {
+	company := fromProtoFields(req.GetCompany())
+	if err := company.Validate(); err != nil {
+		return nil, err
+	}
+
+	company.ID = uuid.New()
+	if err := s.app.CreateCompany(ctx, company); err != nil {
+		return nil, err
+	}
+	return &companiesv1.CreateCompanyResponse{Company: toProtoCompany(company)}, nil
+}
+
+// PatchCompany updates the fields set in req.
+func (s *Server) PatchCompany(
+	ctx context.Context, req *companiesv1.PatchCompanyRequest,
+) (*companiesv1.PatchCompanyResponse, error) {
+	id, err := parseID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	input := fromProtoFields(req.GetCompany())
+	updates := input.PatchFields()
+	if len(updates) == 0 {
+		return nil, errNoFields
+	}
+	if err := input.ValidatePatch(); err != nil {
+		return nil, err
+	}
+
+	company, err := s.app.PatchCompany(ctx, id, updates, req.IfMatch)
+	if err != nil {
+		return nil, err
+	}
+	return &companiesv1.PatchCompanyResponse{Company: toProtoCompany(company)}, nil
+}
+
+// DeleteCompany soft deletes a company.
+func (s *Server) DeleteCompany(
+	ctx context.Context, req *companiesv1.DeleteCompanyRequest,
+) (*companiesv1.DeleteCompanyResponse, error) {
+	id, err := parseID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.app.DeleteCompany(ctx, id, req.IfMatch); err != nil {
+		return nil, err
+	}
+	return &companiesv1.DeleteCompanyResponse{}, nil
+}
+
+// Watch streams the changes committed from now on that match the filters of req.
+// Response headers are sent as soon as the stream is subscribed to the changes.
+// A watcher falling too far behind is ended with RESOURCE_EXHAUSTED and should
+// catch up by reading the companies again before watching anew. Streams end
+// with UNAVAILABLE when the service shuts down.
+func (s *Server) Watch(
+	req *companiesv1.WatchRequest, stream grpc.ServerStreamingServer[companiesv1.WatchResponse],
+) error {
+	filter, err := watchFilter(req)
+	if err != nil {
+		return err
+	}
+
+	sub := s.app.Changes.Subscribe("")
+	defer sub.Cancel()
+
+	// Once the headers are received, the client can tell that no later change will be missed.
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case c, ok := <-sub.Changes:
+			if !ok {
+				return subscriptionEnded(sub)
+			}
+			if !filter.Match(&c) {
+				continue
+			}
+			if err := stream.Send(toProtoChange(&c)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// subscriptionEnded returns the status ending a Watch whose subscription was closed.
+func subscriptionEnded(sub *changefeed.Subscription) error {
+	if sub.Lagged() {
+		return status.Error(codes.ResourceExhausted, "watcher fell behind")
+	}
+	return status.Error(codes.Unavailable, "server is shutting down")
+}
+
+// listFilter builds a repository.ListFilter from req, validated like the query
+// parameters of GET /companies.
+func listFilter(req *companiesv1.ListCompaniesRequest) (repository.ListFilter, error) {
+	f := repository.ListFilter{
+		NamePrefix: req.GetNamePrefix(),
+		Registered: req.Registered,
+		Limit:      uint64(req.GetLimit()),
+		Cursor:     req.GetCursor(),
+	}
+
+	if req.GetType() != companiesv1.CompanyType_COMPANY_TYPE_UNSPECIFIED {
+		t := fromProtoType(req.GetType())
+		if !t.Valid() {
+			return f, app.Invalid(fmt.Sprintf("invalid type %q", req.GetType()))
+		}
+		f.Type = &t
+	}
+
+	if err := employeeRange(req, &f); err != nil {
+		return f, err
+	}
+
+	sort := req.GetSort()
+	if sort == "" {
+		sort = string(repository.SortByName)
+	}
+	f.Descending = strings.HasPrefix(sort, "-")
+	f.Sort = repository.SortField(strings.TrimPrefix(sort, "-"))
+	if f.Sort != repository.SortByName && f.Sort != repository.SortByEmployees {
+		return f, app.Invalid(fmt.Sprintf("invalid sort %q", sort))
+	}
+
+	if f.Limit > repository.MaxListLimit {
+		return f, app.Invalid(fmt.Sprintf("limit must be at most %d", repository.MaxListLimit))
+	}
+	return f, nil
+}
+
+// employeeRange reads min_employees and max_employees of req into f.
+func employeeRange(req *companiesv1.ListCompaniesRequest, f *repository.ListFilter) error {
+	var err error
+	if f.MinEmployees, err = nonNegative("min_employees", req.MinEmployees); err != nil {
+		return err
+	}
+	if f.MaxEmployees, err = nonNegative("max_employees", req.MaxEmployees); err != nil {
+		return err
+	}
+	if f.MinEmployees != nil && f.MaxEmployees != nil && *f.MinEmployees > *f.MaxEmployees {
+		return app.Invalid("min_employees must not be greater than max_employees")
+	}
+	return nil
+}
+
+// nonNegative converts an optional non-negative integer field.
+func nonNegative(name string, v *int32) (*int, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if *v < 0 {
+		return nil, app.Invalid(name + " must be a non-negative integer")
+	}
+
+	n := int(*v)
+	return &n, nil
+}
+
+// watchFilter converts the filters of req.
+func watchFilter(req *companiesv1.WatchRequest) (*changefeed.Filter, error) {
+	f := &changefeed.Filter{}
+	for _, s := range req.GetCompanyIds() {
+		id, err := parseID(s)
+		if err != nil {
+			return nil, err
+		}
+		f.CompanyIDs = append(f.CompanyIDs, id)
+	}
+	for _, t := range req.GetTypes() {
+		f.Types = append(f.Types, fromProtoType(t))
+	}
+	return f, nil
+}
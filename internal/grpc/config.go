@@ -0,0 +1,19 @@
+package grpc
+
+import (
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Config holds settings for the gRPC server.
+type Config struct {
+	Addr string `envconfig:"ADDR" default:":9090"`
+}
+
+// EnvConfig loads config from environment variables into Config.
+func EnvConfig() (*Config, error) {
+	var cfg Config
+	if err := envconfig.Process("GRPC", &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
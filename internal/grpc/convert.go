@@ -0,0 +1,129 @@
+package grpc
+
+import (
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/changefeed"
+	companiesv1 "github.com/dagherghinescu/companies/internal/grpc/gen/companies/v1"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// errInvalidCompanyID matches the error of the REST API for malformed IDs.
+var errInvalidCompanyID = app.Invalid("invalid company id")
+
+func parseID(s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, errInvalidCompanyID
+	}
+	return id, nil
+}
+
+func toProtoCompany(c *models.Company) *companiesv1.Company {
+	if c == nil {
+		return nil
+	}
+
+	pb := &companiesv1.Company{
+		Id:          c.ID.String(),
+		Description: c.Description,
+		Version:     c.Version,
+	}
+	if c.Name != nil {
+		pb.Name = *c.Name
+	}
+	if c.AmountEmployees != nil {
+		pb.AmountOfEmployees = int32(*c.AmountEmployees)
+	}
+	if c.Registered != nil {
+		pb.Registered = *c.Registered
+	}
+	if c.Type != nil {
+		pb.Type = toProtoType(*c.Type)
+	}
+	return pb
+}
+
+// fromProtoFields returns a company holding the fields set in f.
+func fromProtoFields(f *companiesv1.CompanyFields) *models.Company {
+	c := &models.Company{}
+	if f == nil {
+		return c
+	}
+
+	c.Name = f.Name
+	c.Description = f.Description
+	c.Registered = f.Registered
+	if f.AmountOfEmployees != nil {
+		n := int(*f.AmountOfEmployees)
+		c.AmountEmployees = &n
+	}
+	if f.Type != companiesv1.CompanyType_COMPANY_TYPE_UNSPECIFIED {
+		t := fromProtoType(f.Type)
+		c.Type = &t
+	}
+	return c
+}
+
+func toProtoType(t models.CompanyType) companiesv1.CompanyType {
+	switch t {
+	case models.Corporation:
+		return companiesv1.CompanyType_COMPANY_TYPE_CORPORATION
+	case models.NonProfit:
+		return companiesv1.CompanyType_COMPANY_TYPE_NON_PROFIT
+	case models.Cooperative:
+		return companiesv1.CompanyType_COMPANY_TYPE_COOPERATIVE
+	case models.SoleProprietorship:
+		return companiesv1.CompanyType_COMPANY_TYPE_SOLE_PROPRIETORSHIP
+	default:
+		return companiesv1.CompanyType_COMPANY_TYPE_UNSPECIFIED
+	}
+}
+
+// fromProtoType returns the company type of t. Unknown values give an invalid
+// type, so they fail validation like unknown types sent to the REST API.
+func fromProtoType(t companiesv1.CompanyType) models.CompanyType {
+	switch t {
+	case companiesv1.CompanyType_COMPANY_TYPE_CORPORATION:
+		return models.Corporation
+	case companiesv1.CompanyType_COMPANY_TYPE_NON_PROFIT:
+		return models.NonProfit
+	case companiesv1.CompanyType_COMPANY_TYPE_COOPERATIVE:
+		return models.Cooperative
+	case companiesv1.CompanyType_COMPANY_TYPE_SOLE_PROPRIETORSHIP:
+		return models.SoleProprietorship
+	default:
+		return models.CompanyType(t.String())
+	}
+}
+
+func toProtoAction(a models.AuditAction) companiesv1.ChangeAction {
+	switch a {
+	case models.ActionCreated:
+		return companiesv1.ChangeAction_CHANGE_ACTION_CREATED
+	case models.ActionUpdated:
+		return companiesv1.ChangeAction_CHANGE_ACTION_UPDATED
+	case models.ActionDeleted:
+		return companiesv1.ChangeAction_CHANGE_ACTION_DELETED
+	case models.ActionRestored:
+		return companiesv1.ChangeAction_CHANGE_ACTION_RESTORED
+	case models.ActionPurged:
+		return companiesv1.ChangeAction_CHANGE_ACTION_PURGED
+	default:
+		return companiesv1.ChangeAction_CHANGE_ACTION_UNSPECIFIED
+	}
+}
+
+func toProtoChange(c *changefeed.Change) *companiesv1.WatchResponse {
+	return &companiesv1.WatchResponse{
+		Id:        c.ID,
+		Action:    toProtoAction(c.Action),
+		CompanyId: c.CompanyID.String(),
+		Actor:     c.Actor,
+		Before:    toProtoCompany(c.Before),
+		After:     toProtoCompany(c.After),
+		Time:      timestamppb.New(c.Time),
+	}
+}
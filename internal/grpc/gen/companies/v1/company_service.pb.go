@@ -0,0 +1,1180 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: companies/v1/company_service.proto
+
+package companiesv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CompanyType int32
+
+const (
+	CompanyType_COMPANY_TYPE_UNSPECIFIED         CompanyType = 0
+	CompanyType_COMPANY_TYPE_CORPORATION         CompanyType = 1
+	CompanyType_COMPANY_TYPE_NON_PROFIT          CompanyType = 2
+	CompanyType_COMPANY_TYPE_COOPERATIVE         CompanyType = 3
+	CompanyType_COMPANY_TYPE_SOLE_PROPRIETORSHIP CompanyType = 4
+)
+
+// Enum value maps for CompanyType.
+var (
+	CompanyType_name = map[int32]string{
+		0: "COMPANY_TYPE_UNSPECIFIED",
+		1: "COMPANY_TYPE_CORPORATION",
+		2: "COMPANY_TYPE_NON_PROFIT",
+		3: "COMPANY_TYPE_COOPERATIVE",
+		4: "COMPANY_TYPE_SOLE_PROPRIETORSHIP",
+	}
+	CompanyType_value = map[string]int32{
+		"COMPANY_TYPE_UNSPECIFIED":         0,
+		"COMPANY_TYPE_CORPORATION":         1,
+		"COMPANY_TYPE_NON_PROFIT":          2,
+		"COMPANY_TYPE_COOPERATIVE":         3,
+		"COMPANY_TYPE_SOLE_PROPRIETORSHIP": 4,
+	}
+)
+
+func (x CompanyType) Enum() *CompanyType {
+	p := new(CompanyType)
+	*p = x
+	return p
+}
+
+func (x CompanyType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CompanyType) Descriptor() protoreflect.EnumDescriptor {
+	return file_companies_v1_company_service_proto_enumTypes[0].Descriptor()
+}
+
+func (CompanyType) Type() protoreflect.EnumType {
+	return &file_companies_v1_company_service_proto_enumTypes[0]
+}
+
+func (x CompanyType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CompanyType.Descriptor instead.
+func (CompanyType) EnumDescriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{0}
+}
+
+type ChangeAction int32
+
+const (
+	ChangeAction_CHANGE_ACTION_UNSPECIFIED ChangeAction = 0
+	ChangeAction_CHANGE_ACTION_CREATED     ChangeAction = 1
+	ChangeAction_CHANGE_ACTION_UPDATED     ChangeAction = 2
+	ChangeAction_CHANGE_ACTION_DELETED     ChangeAction = 3
+	ChangeAction_CHANGE_ACTION_RESTORED    ChangeAction = 4
+	ChangeAction_CHANGE_ACTION_PURGED      ChangeAction = 5
+)
+
+// Enum value maps for ChangeAction.
+var (
+	ChangeAction_name = map[int32]string{
+		0: "CHANGE_ACTION_UNSPECIFIED",
+		1: "CHANGE_ACTION_CREATED",
+		2: "CHANGE_ACTION_UPDATED",
+		3: "CHANGE_ACTION_DELETED",
+		4: "CHANGE_ACTION_RESTORED",
+		5: "CHANGE_ACTION_PURGED",
+	}
+	ChangeAction_value = map[string]int32{
+		"CHANGE_ACTION_UNSPECIFIED": 0,
+		"CHANGE_ACTION_CREATED":     1,
+		"CHANGE_ACTION_UPDATED":     2,
+		"CHANGE_ACTION_DELETED":     3,
+		"CHANGE_ACTION_RESTORED":    4,
+		"CHANGE_ACTION_PURGED":      5,
+	}
+)
+
+func (x ChangeAction) Enum() *ChangeAction {
+	p := new(ChangeAction)
+	*p = x
+	return p
+}
+
+func (x ChangeAction) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ChangeAction) Descriptor() protoreflect.EnumDescriptor {
+	return file_companies_v1_company_service_proto_enumTypes[1].Descriptor()
+}
+
+func (ChangeAction) Type() protoreflect.EnumType {
+	return &file_companies_v1_company_service_proto_enumTypes[1]
+}
+
+func (x ChangeAction) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ChangeAction.Descriptor instead.
+func (ChangeAction) EnumDescriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{1}
+}
+
+type Company struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Id                string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name              string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Description       *string                `protobuf:"bytes,3,opt,name=description,proto3,oneof" json:"description,omitempty"`
+	AmountOfEmployees int32                  `protobuf:"varint,4,opt,name=amount_of_employees,json=amountOfEmployees,proto3" json:"amount_of_employees,omitempty"`
+	Registered        bool                   `protobuf:"varint,5,opt,name=registered,proto3" json:"registered,omitempty"`
+	Type              CompanyType            `protobuf:"varint,6,opt,name=type,proto3,enum=companies.v1.CompanyType" json:"type,omitempty"`
+	// version is incremented on every change, see if_match.
+	Version       int64 `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Company) Reset() {
+	*x = Company{}
+	mi := &file_companies_v1_company_service_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Company) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Company) ProtoMessage() {}
+
+func (x *Company) ProtoReflect() protoreflect.Message {
+	mi := &file_companies_v1_company_service_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Company.ProtoReflect.Descriptor instead.
+func (*Company) Descriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Company) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Company) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Company) GetDescription() string {
+	if x != nil && x.Description != nil {
+		return *x.Description
+	}
+	return ""
+}
+
+func (x *Company) GetAmountOfEmployees() int32 {
+	if x != nil {
+		return x.AmountOfEmployees
+	}
+	return 0
+}
+
+func (x *Company) GetRegistered() bool {
+	if x != nil {
+		return x.Registered
+	}
+	return false
+}
+
+func (x *Company) GetType() CompanyType {
+	if x != nil {
+		return x.Type
+	}
+	return CompanyType_COMPANY_TYPE_UNSPECIFIED
+}
+
+func (x *Company) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// CompanyFields are the writable fields of a company. Unset fields are left out
+// of a creation or a patch.
+type CompanyFields struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Name              *string                `protobuf:"bytes,1,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	Description       *string                `protobuf:"bytes,2,opt,name=description,proto3,oneof" json:"description,omitempty"`
+	AmountOfEmployees *int32                 `protobuf:"varint,3,opt,name=amount_of_employees,json=amountOfEmployees,proto3,oneof" json:"amount_of_employees,omitempty"`
+	Registered        *bool                  `protobuf:"varint,4,opt,name=registered,proto3,oneof" json:"registered,omitempty"`
+	Type              CompanyType            `protobuf:"varint,5,opt,name=type,proto3,enum=companies.v1.CompanyType" json:"type,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *CompanyFields) Reset() {
+	*x = CompanyFields{}
+	mi := &file_companies_v1_company_service_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompanyFields) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompanyFields) ProtoMessage() {}
+
+func (x *CompanyFields) ProtoReflect() protoreflect.Message {
+	mi := &file_companies_v1_company_service_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompanyFields.ProtoReflect.Descriptor instead.
+func (*CompanyFields) Descriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CompanyFields) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (x *CompanyFields) GetDescription() string {
+	if x != nil && x.Description != nil {
+		return *x.Description
+	}
+	return ""
+}
+
+func (x *CompanyFields) GetAmountOfEmployees() int32 {
+	if x != nil && x.AmountOfEmployees != nil {
+		return *x.AmountOfEmployees
+	}
+	return 0
+}
+
+func (x *CompanyFields) GetRegistered() bool {
+	if x != nil && x.Registered != nil {
+		return *x.Registered
+	}
+	return false
+}
+
+func (x *CompanyFields) GetType() CompanyType {
+	if x != nil {
+		return x.Type
+	}
+	return CompanyType_COMPANY_TYPE_UNSPECIFIED
+}
+
+type GetCompanyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCompanyRequest) Reset() {
+	*x = GetCompanyRequest{}
+	mi := &file_companies_v1_company_service_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCompanyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCompanyRequest) ProtoMessage() {}
+
+func (x *GetCompanyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_companies_v1_company_service_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCompanyRequest.ProtoReflect.Descriptor instead.
+func (*GetCompanyRequest) Descriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetCompanyRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type GetCompanyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Company       *Company               `protobuf:"bytes,1,opt,name=company,proto3" json:"company,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCompanyResponse) Reset() {
+	*x = GetCompanyResponse{}
+	mi := &file_companies_v1_company_service_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCompanyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCompanyResponse) ProtoMessage() {}
+
+func (x *GetCompanyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_companies_v1_company_service_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCompanyResponse.ProtoReflect.Descriptor instead.
+func (*GetCompanyResponse) Descriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetCompanyResponse) GetCompany() *Company {
+	if x != nil {
+		return x.Company
+	}
+	return nil
+}
+
+type ListCompaniesRequest struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Type         CompanyType            `protobuf:"varint,1,opt,name=type,proto3,enum=companies.v1.CompanyType" json:"type,omitempty"`
+	Registered   *bool                  `protobuf:"varint,2,opt,name=registered,proto3,oneof" json:"registered,omitempty"`
+	MinEmployees *int32                 `protobuf:"varint,3,opt,name=min_employees,json=minEmployees,proto3,oneof" json:"min_employees,omitempty"`
+	MaxEmployees *int32                 `protobuf:"varint,4,opt,name=max_employees,json=maxEmployees,proto3,oneof" json:"max_employees,omitempty"`
+	NamePrefix   string                 `protobuf:"bytes,5,opt,name=name_prefix,json=namePrefix,proto3" json:"name_prefix,omitempty"`
+	// sort is "name" (the default) or "amount_of_employees", prefixed with "-" for descending.
+	Sort string `protobuf:"bytes,6,opt,name=sort,proto3" json:"sort,omitempty"`
+	// limit defaults to 20 and is at most 100.
+	Limit uint32 `protobuf:"varint,7,opt,name=limit,proto3" json:"limit,omitempty"`
+	// cursor is the next_cursor of the previous page.
+	Cursor        string `protobuf:"bytes,8,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCompaniesRequest) Reset() {
+	*x = ListCompaniesRequest{}
+	mi := &file_companies_v1_company_service_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCompaniesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCompaniesRequest) ProtoMessage() {}
+
+func (x *ListCompaniesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_companies_v1_company_service_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCompaniesRequest.ProtoReflect.Descriptor instead.
+func (*ListCompaniesRequest) Descriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListCompaniesRequest) GetType() CompanyType {
+	if x != nil {
+		return x.Type
+	}
+	return CompanyType_COMPANY_TYPE_UNSPECIFIED
+}
+
+func (x *ListCompaniesRequest) GetRegistered() bool {
+	if x != nil && x.Registered != nil {
+		return *x.Registered
+	}
+	return false
+}
+
+func (x *ListCompaniesRequest) GetMinEmployees() int32 {
+	if x != nil && x.MinEmployees != nil {
+		return *x.MinEmployees
+	}
+	return 0
+}
+
+func (x *ListCompaniesRequest) GetMaxEmployees() int32 {
+	if x != nil && x.MaxEmployees != nil {
+		return *x.MaxEmployees
+	}
+	return 0
+}
+
+func (x *ListCompaniesRequest) GetNamePrefix() string {
+	if x != nil {
+		return x.NamePrefix
+	}
+	return ""
+}
+
+func (x *ListCompaniesRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *ListCompaniesRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListCompaniesRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type ListCompaniesResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Companies []*Company             `protobuf:"bytes,1,rep,name=companies,proto3" json:"companies,omitempty"`
+	// next_cursor is empty on the last page.
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCompaniesResponse) Reset() {
+	*x = ListCompaniesResponse{}
+	mi := &file_companies_v1_company_service_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCompaniesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCompaniesResponse) ProtoMessage() {}
+
+func (x *ListCompaniesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_companies_v1_company_service_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCompaniesResponse.ProtoReflect.Descriptor instead.
+func (*ListCompaniesResponse) Descriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListCompaniesResponse) GetCompanies() []*Company {
+	if x != nil {
+		return x.Companies
+	}
+	return nil
+}
+
+func (x *ListCompaniesResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type CreateCompanyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Company       *CompanyFields         `protobuf:"bytes,1,opt,name=company,proto3" json:"company,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateCompanyRequest) Reset() {
+	*x = CreateCompanyRequest{}
+	mi := &file_companies_v1_company_service_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateCompanyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateCompanyRequest) ProtoMessage() {}
+
+func (x *CreateCompanyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_companies_v1_company_service_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateCompanyRequest.ProtoReflect.Descriptor instead.
+func (*CreateCompanyRequest) Descriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CreateCompanyRequest) GetCompany() *CompanyFields {
+	if x != nil {
+		return x.Company
+	}
+	return nil
+}
+
+type CreateCompanyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Company       *Company               `protobuf:"bytes,1,opt,name=company,proto3" json:"company,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateCompanyResponse) Reset() {
+	*x = CreateCompanyResponse{}
+	mi := &file_companies_v1_company_service_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateCompanyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateCompanyResponse) ProtoMessage() {}
+
+func (x *CreateCompanyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_companies_v1_company_service_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateCompanyResponse.ProtoReflect.Descriptor instead.
+func (*CreateCompanyResponse) Descriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CreateCompanyResponse) GetCompany() *Company {
+	if x != nil {
+		return x.Company
+	}
+	return nil
+}
+
+type PatchCompanyRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Id      string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Company *CompanyFields         `protobuf:"bytes,2,opt,name=company,proto3" json:"company,omitempty"`
+	// if_match makes the patch fail with FAILED_PRECONDITION unless the company is still at this version.
+	IfMatch       *int64 `protobuf:"varint,3,opt,name=if_match,json=ifMatch,proto3,oneof" json:"if_match,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PatchCompanyRequest) Reset() {
+	*x = PatchCompanyRequest{}
+	mi := &file_companies_v1_company_service_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PatchCompanyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PatchCompanyRequest) ProtoMessage() {}
+
+func (x *PatchCompanyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_companies_v1_company_service_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PatchCompanyRequest.ProtoReflect.Descriptor instead.
+func (*PatchCompanyRequest) Descriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *PatchCompanyRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PatchCompanyRequest) GetCompany() *CompanyFields {
+	if x != nil {
+		return x.Company
+	}
+	return nil
+}
+
+func (x *PatchCompanyRequest) GetIfMatch() int64 {
+	if x != nil && x.IfMatch != nil {
+		return *x.IfMatch
+	}
+	return 0
+}
+
+type PatchCompanyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Company       *Company               `protobuf:"bytes,1,opt,name=company,proto3" json:"company,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PatchCompanyResponse) Reset() {
+	*x = PatchCompanyResponse{}
+	mi := &file_companies_v1_company_service_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PatchCompanyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PatchCompanyResponse) ProtoMessage() {}
+
+func (x *PatchCompanyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_companies_v1_company_service_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PatchCompanyResponse.ProtoReflect.Descriptor instead.
+func (*PatchCompanyResponse) Descriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *PatchCompanyResponse) GetCompany() *Company {
+	if x != nil {
+		return x.Company
+	}
+	return nil
+}
+
+type DeleteCompanyRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// if_match makes the delete fail with FAILED_PRECONDITION unless the company is still at this version.
+	IfMatch       *int64 `protobuf:"varint,2,opt,name=if_match,json=ifMatch,proto3,oneof" json:"if_match,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteCompanyRequest) Reset() {
+	*x = DeleteCompanyRequest{}
+	mi := &file_companies_v1_company_service_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteCompanyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteCompanyRequest) ProtoMessage() {}
+
+func (x *DeleteCompanyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_companies_v1_company_service_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteCompanyRequest.ProtoReflect.Descriptor instead.
+func (*DeleteCompanyRequest) Descriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DeleteCompanyRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *DeleteCompanyRequest) GetIfMatch() int64 {
+	if x != nil && x.IfMatch != nil {
+		return *x.IfMatch
+	}
+	return 0
+}
+
+type DeleteCompanyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteCompanyResponse) Reset() {
+	*x = DeleteCompanyResponse{}
+	mi := &file_companies_v1_company_service_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteCompanyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteCompanyResponse) ProtoMessage() {}
+
+func (x *DeleteCompanyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_companies_v1_company_service_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteCompanyResponse.ProtoReflect.Descriptor instead.
+func (*DeleteCompanyResponse) Descriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{11}
+}
+
+// WatchRequest narrows down the changes streamed by Watch.
+// Empty lists match every company.
+type WatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CompanyIds    []string               `protobuf:"bytes,1,rep,name=company_ids,json=companyIds,proto3" json:"company_ids,omitempty"`
+	Types         []CompanyType          `protobuf:"varint,2,rep,packed,name=types,proto3,enum=companies.v1.CompanyType" json:"types,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_companies_v1_company_service_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_companies_v1_company_service_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *WatchRequest) GetCompanyIds() []string {
+	if x != nil {
+		return x.CompanyIds
+	}
+	return nil
+}
+
+func (x *WatchRequest) GetTypes() []CompanyType {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+type WatchResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id increases with every change.
+	Id        uint64       `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Action    ChangeAction `protobuf:"varint,2,opt,name=action,proto3,enum=companies.v1.ChangeAction" json:"action,omitempty"`
+	CompanyId string       `protobuf:"bytes,3,opt,name=company_id,json=companyId,proto3" json:"company_id,omitempty"`
+	Actor     string       `protobuf:"bytes,4,opt,name=actor,proto3" json:"actor,omitempty"`
+	// before is unset for creations and after once the company is deleted or purged.
+	Before        *Company               `protobuf:"bytes,5,opt,name=before,proto3" json:"before,omitempty"`
+	After         *Company               `protobuf:"bytes,6,opt,name=after,proto3" json:"after,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=time,proto3" json:"time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchResponse) Reset() {
+	*x = WatchResponse{}
+	mi := &file_companies_v1_company_service_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchResponse) ProtoMessage() {}
+
+func (x *WatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_companies_v1_company_service_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchResponse.ProtoReflect.Descriptor instead.
+func (*WatchResponse) Descriptor() ([]byte, []int) {
+	return file_companies_v1_company_service_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *WatchResponse) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *WatchResponse) GetAction() ChangeAction {
+	if x != nil {
+		return x.Action
+	}
+	return ChangeAction_CHANGE_ACTION_UNSPECIFIED
+}
+
+func (x *WatchResponse) GetCompanyId() string {
+	if x != nil {
+		return x.CompanyId
+	}
+	return ""
+}
+
+func (x *WatchResponse) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+func (x *WatchResponse) GetBefore() *Company {
+	if x != nil {
+		return x.Before
+	}
+	return nil
+}
+
+func (x *WatchResponse) GetAfter() *Company {
+	if x != nil {
+		return x.After
+	}
+	return nil
+}
+
+func (x *WatchResponse) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+var File_companies_v1_company_service_proto protoreflect.FileDescriptor
+
+const file_companies_v1_company_service_proto_rawDesc = "" +
+	"\n" +
+	"\"companies/v1/company_service.proto\x12\fcompanies.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xfd\x01\n" +
+	"\aCompany\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12%\n" +
+	"\vdescription\x18\x03 \x01(\tH\x00R\vdescription\x88\x01\x01\x12.\n" +
+	"\x13amount_of_employees\x18\x04 \x01(\x05R\x11amountOfEmployees\x12\x1e\n" +
+	"\n" +
+	"registered\x18\x05 \x01(\bR\n" +
+	"registered\x12-\n" +
+	"\x04type\x18\x06 \x01(\x0e2\x19.companies.v1.CompanyTypeR\x04type\x12\x18\n" +
+	"\aversion\x18\a \x01(\x03R\aversionB\x0e\n" +
+	"\f_description\"\x98\x02\n" +
+	"\rCompanyFields\x12\x17\n" +
+	"\x04name\x18\x01 \x01(\tH\x00R\x04name\x88\x01\x01\x12%\n" +
+	"\vdescription\x18\x02 \x01(\tH\x01R\vdescription\x88\x01\x01\x123\n" +
+	"\x13amount_of_employees\x18\x03 \x01(\x05H\x02R\x11amountOfEmployees\x88\x01\x01\x12#\n" +
+	"\n" +
+	"registered\x18\x04 \x01(\bH\x03R\n" +
+	"registered\x88\x01\x01\x12-\n" +
+	"\x04type\x18\x05 \x01(\x0e2\x19.companies.v1.CompanyTypeR\x04typeB\a\n" +
+	"\x05_nameB\x0e\n" +
+	"\f_descriptionB\x16\n" +
+	"\x14_amount_of_employeesB\r\n" +
+	"\v_registered\"#\n" +
+	"\x11GetCompanyRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"E\n" +
+	"\x12GetCompanyResponse\x12/\n" +
+	"\acompany\x18\x01 \x01(\v2\x15.companies.v1.CompanyR\acompany\"\xd4\x02\n" +
+	"\x14ListCompaniesRequest\x12-\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x19.companies.v1.CompanyTypeR\x04type\x12#\n" +
+	"\n" +
+	"registered\x18\x02 \x01(\bH\x00R\n" +
+	"registered\x88\x01\x01\x12(\n" +
+	"\rmin_employees\x18\x03 \x01(\x05H\x01R\fminEmployees\x88\x01\x01\x12(\n" +
+	"\rmax_employees\x18\x04 \x01(\x05H\x02R\fmaxEmployees\x88\x01\x01\x12\x1f\n" +
+	"\vname_prefix\x18\x05 \x01(\tR\n" +
+	"namePrefix\x12\x12\n" +
+	"\x04sort\x18\x06 \x01(\tR\x04sort\x12\x14\n" +
+	"\x05limit\x18\a \x01(\rR\x05limit\x12\x16\n" +
+	"\x06cursor\x18\b \x01(\tR\x06cursorB\r\n" +
+	"\v_registeredB\x10\n" +
+	"\x0e_min_employeesB\x10\n" +
+	"\x0e_max_employees\"m\n" +
+	"\x15ListCompaniesResponse\x123\n" +
+	"\tcompanies\x18\x01 \x03(\v2\x15.companies.v1.CompanyR\tcompanies\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"M\n" +
+	"\x14CreateCompanyRequest\x125\n" +
+	"\acompany\x18\x01 \x01(\v2\x1b.companies.v1.CompanyFieldsR\acompany\"H\n" +
+	"\x15CreateCompanyResponse\x12/\n" +
+	"\acompany\x18\x01 \x01(\v2\x15.companies.v1.CompanyR\acompany\"\x89\x01\n" +
+	"\x13PatchCompanyRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x125\n" +
+	"\acompany\x18\x02 \x01(\v2\x1b.companies.v1.CompanyFieldsR\acompany\x12\x1e\n" +
+	"\bif_match\x18\x03 \x01(\x03H\x00R\aifMatch\x88\x01\x01B\v\n" +
+	"\t_if_match\"G\n" +
+	"\x14PatchCompanyResponse\x12/\n" +
+	"\acompany\x18\x01 \x01(\v2\x15.companies.v1.CompanyR\acompany\"S\n" +
+	"\x14DeleteCompanyRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1e\n" +
+	"\bif_match\x18\x02 \x01(\x03H\x00R\aifMatch\x88\x01\x01B\v\n" +
+	"\t_if_match\"\x17\n" +
+	"\x15DeleteCompanyResponse\"`\n" +
+	"\fWatchRequest\x12\x1f\n" +
+	"\vcompany_ids\x18\x01 \x03(\tR\n" +
+	"companyIds\x12/\n" +
+	"\x05types\x18\x02 \x03(\x0e2\x19.companies.v1.CompanyTypeR\x05types\"\x94\x02\n" +
+	"\rWatchResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x122\n" +
+	"\x06action\x18\x02 \x01(\x0e2\x1a.companies.v1.ChangeActionR\x06action\x12\x1d\n" +
+	"\n" +
+	"company_id\x18\x03 \x01(\tR\tcompanyId\x12\x14\n" +
+	"\x05actor\x18\x04 \x01(\tR\x05actor\x12-\n" +
+	"\x06before\x18\x05 \x01(\v2\x15.companies.v1.CompanyR\x06before\x12+\n" +
+	"\x05after\x18\x06 \x01(\v2\x15.companies.v1.CompanyR\x05after\x12.\n" +
+	"\x04time\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\x04time*\xaa\x01\n" +
+	"\vCompanyType\x12\x1c\n" +
+	"\x18COMPANY_TYPE_UNSPECIFIED\x10\x00\x12\x1c\n" +
+	"\x18COMPANY_TYPE_CORPORATION\x10\x01\x12\x1b\n" +
+	"\x17COMPANY_TYPE_NON_PROFIT\x10\x02\x12\x1c\n" +
+	"\x18COMPANY_TYPE_COOPERATIVE\x10\x03\x12$\n" +
+	" COMPANY_TYPE_SOLE_PROPRIETORSHIP\x10\x04*\xb4\x01\n" +
+	"\fChangeAction\x12\x1d\n" +
+	"\x19CHANGE_ACTION_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15CHANGE_ACTION_CREATED\x10\x01\x12\x19\n" +
+	"\x15CHANGE_ACTION_UPDATED\x10\x02\x12\x19\n" +
+	"\x15CHANGE_ACTION_DELETED\x10\x03\x12\x1a\n" +
+	"\x16CHANGE_ACTION_RESTORED\x10\x04\x12\x18\n" +
+	"\x14CHANGE_ACTION_PURGED\x10\x052\x8a\x04\n" +
+	"\x0eCompanyService\x12O\n" +
+	"\n" +
+	"GetCompany\x12\x1f.companies.v1.GetCompanyRequest\x1a .companies.v1.GetCompanyResponse\x12X\n" +
+	"\rListCompanies\x12\".companies.v1.ListCompaniesRequest\x1a#.companies.v1.ListCompaniesResponse\x12X\n" +
+	"\rCreateCompany\x12\".companies.v1.CreateCompanyRequest\x1a#.companies.v1.CreateCompanyResponse\x12U\n" +
+	"\fPatchCompany\x12!.companies.v1.PatchCompanyRequest\x1a\".companies.v1.PatchCompanyResponse\x12X\n" +
+	"\rDeleteCompany\x12\".companies.v1.DeleteCompanyRequest\x1a#.companies.v1.DeleteCompanyResponse\x12B\n" +
+	"\x05Watch\x12\x1a.companies.v1.WatchRequest\x1a\x1b.companies.v1.WatchResponse0\x01BPZNgithub.com/dagherghinescu/companies/internal/grpc/gen/companies/v1;companiesv1b\x06proto3"
+
+var (
+	file_companies_v1_company_service_proto_rawDescOnce sync.Once
+	file_companies_v1_company_service_proto_rawDescData []byte
+)
+
+func file_companies_v1_company_service_proto_rawDescGZIP() []byte {
+	file_companies_v1_company_service_proto_rawDescOnce.Do(func() {
+		file_companies_v1_company_service_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_companies_v1_company_service_proto_rawDesc), len(file_companies_v1_company_service_proto_rawDesc)))
+	})
+	return file_companies_v1_company_service_proto_rawDescData
+}
+
+var file_companies_v1_company_service_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_companies_v1_company_service_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_companies_v1_company_service_proto_goTypes = []any{
+	(CompanyType)(0),              // 0: companies.v1.CompanyType
+	(ChangeAction)(0),             // 1: companies.v1.ChangeAction
+	(*Company)(nil),               // 2: companies.v1.Company
+	(*CompanyFields)(nil),         // 3: companies.v1.CompanyFields
+	(*GetCompanyRequest)(nil),     // 4: companies.v1.GetCompanyRequest
+	(*GetCompanyResponse)(nil),    // 5: companies.v1.GetCompanyResponse
+	(*ListCompaniesRequest)(nil),  // 6: companies.v1.ListCompaniesRequest
+	(*ListCompaniesResponse)(nil), // 7: companies.v1.ListCompaniesResponse
+	(*CreateCompanyRequest)(nil),  // 8: companies.v1.CreateCompanyRequest
+	(*CreateCompanyResponse)(nil), // 9: companies.v1.CreateCompanyResponse
+	(*PatchCompanyRequest)(nil),   // 10: companies.v1.PatchCompanyRequest
+	(*PatchCompanyResponse)(nil),  // 11: companies.v1.PatchCompanyResponse
+	(*DeleteCompanyRequest)(nil),  // 12: companies.v1.DeleteCompanyRequest
+	(*DeleteCompanyResponse)(nil), // 13: companies.v1.DeleteCompanyResponse
+	(*WatchRequest)(nil),          // 14: companies.v1.WatchRequest
+	(*WatchResponse)(nil),         // 15: companies.v1.WatchResponse
+	(*timestamppb.Timestamp)(nil), // 16: google.protobuf.Timestamp
+}
+var file_companies_v1_company_service_proto_depIdxs = []int32{
+	0,  // 0: companies.v1.Company.type:type_name -> companies.v1.CompanyType
+	0,  // 1: companies.v1.CompanyFields.type:type_name -> companies.v1.CompanyType
+	2,  // 2: companies.v1.GetCompanyResponse.company:type_name -> companies.v1.Company
+	0,  // 3: companies.v1.ListCompaniesRequest.type:type_name -> companies.v1.CompanyType
+	2,  // 4: companies.v1.ListCompaniesResponse.companies:type_name -> companies.v1.Company
+	3,  // 5: companies.v1.CreateCompanyRequest.company:type_name -> companies.v1.CompanyFields
+	2,  // 6: companies.v1.CreateCompanyResponse.company:type_name -> companies.v1.Company
+	3,  // 7: companies.v1.PatchCompanyRequest.company:type_name -> companies.v1.CompanyFields
+	2,  // 8: companies.v1.PatchCompanyResponse.company:type_name -> companies.v1.Company
+	0,  // 9: companies.v1.WatchRequest.types:type_name -> companies.v1.CompanyType
+	1,  // 10: companies.v1.WatchResponse.action:type_name -> companies.v1.ChangeAction
+	2,  // 11: companies.v1.WatchResponse.before:type_name -> companies.v1.Company
+	2,  // 12: companies.v1.WatchResponse.after:type_name -> companies.v1.Company
+	16, // 13: companies.v1.WatchResponse.time:type_name -> google.protobuf.Timestamp
+	4,  // 14: companies.v1.CompanyService.GetCompany:input_type -> companies.v1.GetCompanyRequest
+	6,  // 15: companies.v1.CompanyService.ListCompanies:input_type -> companies.v1.ListCompaniesRequest
+	8,  // 16: companies.v1.CompanyService.CreateCompany:input_type -> companies.v1.CreateCompanyRequest
+	10, // 17: companies.v1.CompanyService.PatchCompany:input_type -> companies.v1.PatchCompanyRequest
+	12, // 18: companies.v1.CompanyService.DeleteCompany:input_type -> companies.v1.DeleteCompanyRequest
+	14, // 19: companies.v1.CompanyService.Watch:input_type -> companies.v1.WatchRequest
+	5,  // 20: companies.v1.CompanyService.GetCompany:output_type -> companies.v1.GetCompanyResponse
+	7,  // 21: companies.v1.CompanyService.ListCompanies:output_type -> companies.v1.ListCompaniesResponse
+	9,  // 22: companies.v1.CompanyService.CreateCompany:output_type -> companies.v1.CreateCompanyResponse
+	11, // 23: companies.v1.CompanyService.PatchCompany:output_type -> companies.v1.PatchCompanyResponse
+	13, // 24: companies.v1.CompanyService.DeleteCompany:output_type -> companies.v1.DeleteCompanyResponse
+	15, // 25: companies.v1.CompanyService.Watch:output_type -> companies.v1.WatchResponse
+	20, // [20:26] is the sub-list for method output_type
+	14, // [14:20] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_companies_v1_company_service_proto_init() }
+func file_companies_v1_company_service_proto_init() {
+	if File_companies_v1_company_service_proto != nil {
+		return
+	}
+	file_companies_v1_company_service_proto_msgTypes[0].OneofWrappers = []any{}
+	file_companies_v1_company_service_proto_msgTypes[1].OneofWrappers = []any{}
+	file_companies_v1_company_service_proto_msgTypes[4].OneofWrappers = []any{}
+	file_companies_v1_company_service_proto_msgTypes[8].OneofWrappers = []any{}
+	file_companies_v1_company_service_proto_msgTypes[10].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_companies_v1_company_service_proto_rawDesc), len(file_companies_v1_company_service_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_companies_v1_company_service_proto_goTypes,
+		DependencyIndexes: file_companies_v1_company_service_proto_depIdxs,
+		EnumInfos:         file_companies_v1_company_service_proto_enumTypes,
+		MessageInfos:      file_companies_v1_company_service_proto_msgTypes,
+	}.Build()
+	File_companies_v1_company_service_proto = out.File
+	file_companies_v1_company_service_proto_goTypes = nil
+	file_companies_v1_company_service_proto_depIdxs = nil
+}
@@ -0,0 +1,67 @@
+// Package grpc serves the companies API over gRPC, next to the REST API.
+package grpc
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	companiesv1 "github.com/dagherghinescu/companies/internal/grpc/gen/companies/v1"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Server implements companiesv1.CompanyServiceServer on top of app.App.
+type Server struct {
+	companiesv1.UnimplementedCompanyServiceServer
+	app *app.App
+}
+
+// NewServer creates a gRPC server serving the CompanyService of appl.
+// Calls are authenticated like JWTMiddleware does for HTTP requests, and
+// errors are converted to gRPC statuses, logging unexpected ones.
+func NewServer(appl *app.App, jwtCfg *middleware.JWTConfig, l *zap.Logger) *grpc.Server {
+	a := &authenticator{cfg: jwtCfg}
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryStatus(l), a.unary),
+		grpc.ChainStreamInterceptor(streamStatus(l), a.stream),
+	)
+	companiesv1.RegisterCompanyServiceServer(srv, &Server{app: appl})
+	return srv
+}
+
+// StartServer serves srv on lis until ctx is canceled, then stops it gracefully,
+// cancelling the calls still running after shutdownTimeout. It returns once srv is stopped.
+func StartServer(ctx context.Context, l *zap.Logger, srv *grpc.Server, lis net.Listener) error {
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+		l.Info("Shutting down gRPC server...")
+
+		stopped := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(shutdownTimeout):
+			l.Warn("gRPC graceful shutdown timed out")
+			srv.Stop()
+		}
+	}()
+
+	if err := srv.Serve(lis); err != nil {
+		return err
+	}
+	// Serve returns as soon as GracefulStop closes the listener, before the calls are done.
+	<-shutdown
+	return nil
+}
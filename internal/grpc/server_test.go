@@ -0,0 +1,271 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/auth"
+	grpcapi "github.com/dagherghinescu/companies/internal/grpc"
+	companiesv1 "github.com/dagherghinescu/companies/internal/grpc/gen/companies/v1"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+const testSecret = "test-secret"
+
+// memoryRepo keeps companies in memory. Methods the tests do not use are left
+// to the nil embedded interface.
+type memoryRepo struct {
+	repository.Company
+
+	mu        sync.Mutex
+	companies map[uuid.UUID]*models.Company
+}
+
+func (r *memoryRepo) InTx(_ context.Context, fn func(tx repository.Company) error) error {
+	return fn(r)
+}
+
+func (r *memoryRepo) GetByID(_ context.Context, id uuid.UUID) (*models.Company, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.companies[id]
+	if !ok {
+		return nil, app.ErrCompanyNotFound
+	}
+	copied := *c
+	return &copied, nil
+}
+
+func (r *memoryRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Company, error) {
+	return r.GetByID(ctx, id)
+}
+
+func (r *memoryRepo) Create(_ context.Context, c *models.Company) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *c
+	r.companies[c.ID] = &copied
+	return nil
+}
+
+func (r *memoryRepo) Delete(_ context.Context, id uuid.UUID, _ string, _ *int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.companies, id)
+	return nil
+}
+
+func (r *memoryRepo) List(_ context.Context, _ repository.ListFilter) (*repository.CompanyPage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	page := &repository.CompanyPage{}
+	for _, c := range r.companies {
+		page.Companies = append(page.Companies, *c)
+	}
+	return page, nil
+}
+
+func (r *memoryRepo) AddOutboxEvent(_ context.Context, _ string, _ []byte) error {
+	return nil
+}
+
+func (r *memoryRepo) AddAudit(_ context.Context, e *models.AuditEntry) error {
+	e.CreatedAt = time.Now()
+	return nil
+}
+
+func newClient(t *testing.T) (companiesv1.CompanyServiceClient, *app.App) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	repo := &memoryRepo{companies: make(map[uuid.UUID]*models.Company)}
+	appl := app.New(zap.NewNop(), repo, &app.Config{})
+	srv := grpcapi.NewServer(appl, &middleware.JWTConfig{Secret: testSecret}, zap.NewNop())
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = grpcapi.StartServer(ctx, zap.NewNop(), srv, lis) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+		cancel()
+	})
+
+	return companiesv1.NewCompanyServiceClient(conn), appl
+}
+
+func withToken(t *testing.T, role models.Role) context.Context {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		Roles: []models.Role{role},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	s, err := token.SignedString([]byte(testSecret))
+	require.NoError(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+s)
+}
+
+func validFields() *companiesv1.CompanyFields {
+	return &companiesv1.CompanyFields{
+		Name:              proto.String("Acme"),
+		AmountOfEmployees: proto.Int32(10),
+		Registered:        proto.Bool(true),
+		Type:              companiesv1.CompanyType_COMPANY_TYPE_CORPORATION,
+	}
+}
+
+func TestServer_CreateCompany(t *testing.T) {
+	tests := []struct {
+		name           string
+		ctx            func(t *testing.T) context.Context
+		fields         *companiesv1.CompanyFields
+		expectedCode   codes.Code
+		expectedFields []string
+	}{
+		{
+			name:         "missing token",
+			ctx:          func(*testing.T) context.Context { return context.Background() },
+			fields:       validFields(),
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "role too low",
+			ctx:          func(t *testing.T) context.Context { return withToken(t, models.RoleViewer) },
+			fields:       validFields(),
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:           "invalid fields",
+			ctx:            func(t *testing.T) context.Context { return withToken(t, models.RoleEditor) },
+			fields:         &companiesv1.CompanyFields{Name: proto.String(""), Type: companiesv1.CompanyType(42)},
+			expectedCode:   codes.InvalidArgument,
+			expectedFields: []string{"amount_of_employees", "registered", "name", "type"},
+		},
+		{
+			name:         "created",
+			ctx:          func(t *testing.T) context.Context { return withToken(t, models.RoleEditor) },
+			fields:       validFields(),
+			expectedCode: codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newClient(t)
+
+			resp, err := client.CreateCompany(tt.ctx(t), &companiesv1.CreateCompanyRequest{Company: tt.fields})
+			require.Equal(t, tt.expectedCode, status.Code(err), err)
+			if err != nil {
+				var fields []string
+				for _, d := range status.Convert(err).Details() {
+					for _, v := range d.(*errdetails.BadRequest).GetFieldViolations() {
+						fields = append(fields, v.GetField())
+					}
+				}
+				require.Equal(t, tt.expectedFields, fields)
+				return
+			}
+
+			req := &companiesv1.GetCompanyRequest{Id: resp.GetCompany().GetId()}
+			got, err := client.GetCompany(context.Background(), req)
+			require.NoError(t, err)
+			require.True(t, proto.Equal(resp.GetCompany(), got.GetCompany()))
+			require.Equal(t, int64(1), got.GetCompany().GetVersion())
+		})
+	}
+}
+
+func TestServer_GetCompany(t *testing.T) {
+	client, _ := newClient(t)
+
+	_, err := client.GetCompany(context.Background(), &companiesv1.GetCompanyRequest{Id: "not-a-uuid"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetCompany(context.Background(), &companiesv1.GetCompanyRequest{Id: uuid.NewString()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_ListCompanies(t *testing.T) {
+	client, _ := newClient(t)
+
+	_, err := client.ListCompanies(context.Background(), &companiesv1.ListCompaniesRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	resp, err := client.ListCompanies(withToken(t, models.RoleViewer), &companiesv1.ListCompaniesRequest{})
+	require.NoError(t, err)
+	require.Empty(t, resp.GetCompanies())
+}
+
+func TestServer_Watch(t *testing.T) {
+	client, appl := newClient(t)
+	editor := withToken(t, models.RoleEditor)
+
+	watchCtx, stopWatching := context.WithCancel(withToken(t, models.RoleViewer))
+	defer stopWatching()
+	stream, err := client.Watch(watchCtx, &companiesv1.WatchRequest{
+		Types: []companiesv1.CompanyType{companiesv1.CompanyType_COMPANY_TYPE_NON_PROFIT},
+	})
+	require.NoError(t, err)
+	_, err = stream.Header() // changes are streamed once the headers are sent
+	require.NoError(t, err)
+
+	_, err = client.CreateCompany(editor, &companiesv1.CreateCompanyRequest{Company: validFields()})
+	require.NoError(t, err)
+
+	fields := validFields()
+	fields.Name = proto.String("Charity")
+	fields.Type = companiesv1.CompanyType_COMPANY_TYPE_NON_PROFIT
+	created, err := client.CreateCompany(editor, &companiesv1.CreateCompanyRequest{Company: fields})
+	require.NoError(t, err)
+	_, err = client.DeleteCompany(withToken(t, models.RoleAdmin),
+		&companiesv1.DeleteCompanyRequest{Id: created.GetCompany().GetId()})
+	require.NoError(t, err)
+
+	change, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, companiesv1.ChangeAction_CHANGE_ACTION_CREATED, change.GetAction())
+	require.Equal(t, created.GetCompany().GetId(), change.GetCompanyId())
+	require.Equal(t, "user-1", change.GetActor())
+	require.Nil(t, change.GetBefore())
+	require.True(t, proto.Equal(created.GetCompany(), change.GetAfter()))
+
+	change, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, companiesv1.ChangeAction_CHANGE_ACTION_DELETED, change.GetAction())
+	require.Nil(t, change.GetAfter())
+
+	appl.Changes.Close()
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
@@ -0,0 +1,90 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dagherghinescu/companies/internal/app"
+)
+
+// unaryStatus converts the errors of unary calls with toStatus.
+func unaryStatus(l *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, toStatus(l, info.FullMethod, err)
+		}
+		return resp, nil
+	}
+}
+
+// streamStatus converts the errors of streaming calls with toStatus.
+func streamStatus(l *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return toStatus(l, info.FullMethod, err)
+		}
+		return nil
+	}
+}
+
+// toStatus converts err into a gRPC status error, like Problems does for HTTP responses.
+// Errors of a known app.Kind get their matching code, with the invalid fields of
+// validation errors as BadRequest details. Status errors are returned as they are;
+// context errors get their own code and any other error is logged and answered with an opaque INTERNAL status.
+func toStatus(l *zap.Logger, method string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	appErr := app.AsError(err)
+	if appErr == nil {
+		l.Error("gRPC call failed", zap.Error(err), zap.String("method", method))
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	st := status.New(kindCode(appErr.Kind), err.Error())
+	if len(appErr.Fields) == 0 {
+		return st.Err()
+	}
+
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(appErr.Fields))
+	for i, f := range appErr.Fields {
+		violations[i] = &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message}
+	}
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// kindCode returns the gRPC code of errors of kind k.
+func kindCode(k app.Kind) codes.Code {
+	switch k {
+	case app.KindNotFound:
+		return codes.NotFound
+	case app.KindConflict:
+		return codes.AlreadyExists
+	case app.KindValidation, app.KindUnprocessable:
+		return codes.InvalidArgument
+	case app.KindPreconditionFailed:
+		return codes.FailedPrecondition
+	case app.KindUnauthorized:
+		return codes.Unauthenticated
+	case app.KindForbidden:
+		return codes.PermissionDenied
+	case app.KindTooLarge:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
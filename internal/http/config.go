@@ -12,6 +12,8 @@ type Config struct {
 	ReadHeaderTimeout time.Duration `envconfig:"READ_HEADER_TIMEOUT" default:"5s"`
 	ReadTimeout       time.Duration `envconfig:"READ_TIMEOUT" default:"10s"`
 	WriteTimeout      time.Duration `envconfig:"WRITE_TIMEOUT" default:"10s"`
+	// ValidateOpenAPI checks requests and responses against the OpenAPI document.
+	ValidateOpenAPI bool `envconfig:"VALIDATE_OPENAPI" default:"false"`
 }
 
 // EnvConfig loads config from environment variables into HTTPConfig.
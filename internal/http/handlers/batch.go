@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/auth"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+type batchRequest struct {
+	Mode       app.BatchMode    `json:"mode"`
+	Operations []batchOperation `json:"operations"`
+}
+
+// batchOperation is one operation of a batch request. Data holds the company to
+// create or the fields to patch, in the same shape as the POST and PATCH bodies.
+type batchOperation struct {
+	Op      app.BatchOp     `json:"op"`
+	ID      string          `json:"id,omitempty"`
+	IfMatch *int64          `json:"if_match,omitempty"`
+	Data    *models.Company `json:"data,omitempty"`
+}
+
+type batchResult struct {
+	Index   int             `json:"index"`
+	Status  int             `json:"status"`
+	ID      *uuid.UUID      `json:"id,omitempty"`
+	Company *models.Company `json:"company,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+var errUnknownAction = app.NewError(app.KindNotFound, "unknown action")
+
+// CompanyAction returns a handler for the custom methods of the companies
+// collection, addressed as /companies:<action>. The only action is batch.
+func CompanyAction(appl *app.App) gin.HandlerFunc {
+	batch := BatchCompanies(appl)
+
+	return func(c *gin.Context) {
+		switch c.Param("action") {
+		case ":batch":
+			batch(c)
+		default:
+			_ = c.Error(errUnknownAction)
+		}
+	}
+}
+
+// BatchCompanies returns a handler that applies a list of create, patch and delete
+// operations. In atomic mode (the default) either all operations are applied or
+// none; in best_effort mode each one is applied on its own. The response holds the
+// status of each operation, and is 207 Multi-Status when any of them failed.
+// Delete operations require the admin role.
+func BatchCompanies(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input batchRequest
+		if err := c.ShouldBindJSON(&input); err != nil {
+			_ = c.Error(bindError(err))
+			return
+		}
+
+		if input.Mode == "" {
+			input.Mode = app.BatchAtomic
+		}
+		if !input.Mode.Valid() {
+			_ = c.Error(app.Invalid("mode must be atomic or best_effort"))
+			return
+		}
+
+		ops, err := batchOperations(input.Operations)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		if !mayDelete(c, ops) {
+			_ = c.Error(app.NewError(app.KindForbidden, "delete operations require the admin role"))
+			return
+		}
+
+		results, err := appl.ExecuteBatch(c.Request.Context(), input.Mode, ops)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		status := http.StatusOK
+		out := make([]batchResult, len(results))
+		for i, r := range results {
+			out[i] = batchResponse(appl, i, ops[i], r)
+			if r.Err != nil {
+				status = http.StatusMultiStatus
+			}
+		}
+
+		c.JSON(status, gin.H{"results": out})
+	}
+}
+
+// batchOperations validates the operations of a batch request and converts them for the app.
+func batchOperations(in []batchOperation) ([]app.BatchOperation, error) {
+	ops := make([]app.BatchOperation, len(in))
+	for i, op := range in {
+		var err error
+		if ops[i], err = toBatchOperation(op); err != nil {
+			return nil, operationError(i, err)
+		}
+	}
+
+	return ops, nil
+}
+
+// operationError prefixes the message and invalid fields of err with the operation index.
+func operationError(i int, err error) error {
+	prefix := fmt.Sprintf("operations[%d]", i)
+
+	var validationErr *models.ValidationError
+	if !errors.As(err, &validationErr) {
+		return app.Invalid(prefix + ": " + err.Error())
+	}
+
+	fields := make([]models.FieldError, len(validationErr.Fields))
+	for j, f := range validationErr.Fields {
+		fields[j] = models.FieldError{Field: prefix + ".data." + f.Field, Message: f.Message}
+	}
+	return &app.Error{Kind: app.KindValidation, Message: prefix + ": " + err.Error(), Fields: fields}
+}
+
+func toBatchOperation(in batchOperation) (app.BatchOperation, error) {
+	op := app.BatchOperation{Op: in.Op, IfMatch: in.IfMatch}
+
+	switch in.Op {
+	case app.OpCreate:
+		if in.Data == nil {
+			return op, errors.New("data is required")
+		}
+		if err := in.Data.Validate(); err != nil {
+			return op, err
+		}
+		company := *in.Data
+		company.ID = uuid.New()
+		op.Company = &company
+		op.ID = company.ID
+		return op, nil
+	case app.OpPatch:
+		if in.Data == nil {
+			return op, errors.New("data is required")
+		}
+		if op.Fields = in.Data.PatchFields(); len(op.Fields) == 0 {
+			return op, errors.New("no fields to update")
+		}
+		if err := in.Data.ValidatePatch(); err != nil {
+			return op, err
+		}
+	case app.OpDelete:
+	default:
+		return op, errors.New("op must be create, patch or delete")
+	}
+
+	id, err := uuid.Parse(in.ID)
+	if err != nil {
+		return op, errors.New("invalid company id")
+	}
+	op.ID = id
+	return op, nil
+}
+
+// mayDelete reports whether the principal may run the delete operations among ops.
+func mayDelete(c *gin.Context, ops []app.BatchOperation) bool {
+	for _, op := range ops {
+		if op.Op != app.OpDelete {
+			continue
+		}
+
+		p, ok := auth.PrincipalFrom(c.Request.Context())
+		return ok && p.HasRole(models.RoleAdmin)
+	}
+	return true
+}
+
+func batchResponse(appl *app.App, i int, op app.BatchOperation, r app.BatchResult) batchResult {
+	out := batchResult{Index: i, ID: &op.ID, Company: r.Company}
+
+	switch r.Err {
+	case nil:
+		switch op.Op {
+		case app.OpCreate:
+			out.Status = http.StatusCreated
+		case app.OpDelete:
+			out.Status = http.StatusNoContent
+		default:
+			out.Status = http.StatusOK
+		}
+	case app.ErrCompanyNotFound:
+		out.Status, out.Error = http.StatusNotFound, "company not found"
+	case app.ErrCompanyAlreadyExists:
+		out.Status, out.Error = http.StatusConflict, "company with that name already exists"
+	case app.ErrPreconditionFailed:
+		out.Status, out.Error = http.StatusPreconditionFailed, r.Err.Error()
+	case app.ErrBatchAborted:
+		out.Status, out.Error = http.StatusFailedDependency, r.Err.Error()
+	default:
+		appl.Logger.Error("batch operation failed", zap.Int("index", i), zap.Error(r.Err))
+		out.Status, out.Error = http.StatusInternalServerError, "internal server error"
+	}
+
+	return out
+}
@@ -0,0 +1,178 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/auth"
+	"github.com/dagherghinescu/companies/internal/http/handlers"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+func TestBatchCompaniesHandler(t *testing.T) {
+	id := uuid.New()
+	missing := uuid.New()
+	newCompany := `{"name":"New","amount_of_employees":1,"registered":true,"type":"Corporation"}`
+	takenCompany := `{"name":"Taken","amount_of_employees":1,"registered":true,"type":"Corporation"}`
+
+	newRepo := func() *mockCompanyRepo {
+		return &mockCompanyRepo{
+			CreateFn: func(_ context.Context, c *models.Company) error {
+				if *c.Name == "Taken" {
+					return &pq.Error{Code: "23505"}
+				}
+				return nil
+			},
+			GetByIDFn: func(_ context.Context, got uuid.UUID) (*models.Company, error) {
+				if got == missing {
+					return nil, sql.ErrNoRows
+				}
+				return &models.Company{ID: got, Name: ptrString("Acme"), Version: 2}, nil
+			},
+			PatchFn: func(_ context.Context, _ uuid.UUID, _ map[string]interface{}, _ *int64) error {
+				return nil
+			},
+			DeleteFn: func(_ context.Context, _ uuid.UUID, _ string, _ *int64) error {
+				return nil
+			},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		body             string
+		roles            []models.Role
+		expectedCode     int
+		expectedStatuses []int
+	}{
+		{
+			name: "atomic success",
+			body: `{"operations":[
+				{"op":"create","data":` + newCompany + `},
+				{"op":"patch","id":"` + id.String() + `","data":{"amount_of_employees":5}},
+				{"op":"delete","id":"` + id.String() + `"}
+			]}`,
+			roles:            []models.Role{models.RoleAdmin},
+			expectedCode:     http.StatusOK,
+			expectedStatuses: []int{http.StatusCreated, http.StatusOK, http.StatusNoContent},
+		},
+		{
+			name: "atomic failure aborts the others",
+			body: `{"operations":[
+				{"op":"create","data":` + newCompany + `},
+				{"op":"create","data":` + takenCompany + `},
+				{"op":"patch","id":"` + id.String() + `","data":{"name":"Other"}}
+			]}`,
+			roles:        []models.Role{models.RoleEditor},
+			expectedCode: http.StatusMultiStatus,
+			expectedStatuses: []int{
+				http.StatusFailedDependency, http.StatusConflict, http.StatusFailedDependency,
+			},
+		},
+		{
+			name: "best effort",
+			body: `{"mode":"best_effort","operations":[
+				{"op":"patch","id":"` + missing.String() + `","data":{"name":"Other"}},
+				{"op":"create","data":` + newCompany + `}
+			]}`,
+			roles:            []models.Role{models.RoleEditor},
+			expectedCode:     http.StatusMultiStatus,
+			expectedStatuses: []int{http.StatusNotFound, http.StatusCreated},
+		},
+		{
+			name:         "delete requires admin",
+			body:         `{"operations":[{"op":"delete","id":"` + id.String() + `"}]}`,
+			roles:        []models.Role{models.RoleEditor},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "invalid operation",
+			body:         `{"operations":[{"op":"upsert","data":` + newCompany + `}]}`,
+			roles:        []models.Role{models.RoleEditor},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid mode",
+			body:         `{"mode":"eventually","operations":[{"op":"create","data":` + newCompany + `}]}`,
+			roles:        []models.Role{models.RoleEditor},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid company",
+			body:         `{"operations":[{"op":"create","data":{"name":"Acme","amount_of_employees":-1}}]}`,
+			roles:        []models.Role{models.RoleEditor},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "empty batch",
+			body:         `{"operations":[]}`,
+			roles:        []models.Role{models.RoleEditor},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.Problems(zap.NewNop()))
+
+			appl := app.New(zap.NewNop(), newRepo(), &app.Config{})
+
+			router.POST("/companies:action", func(c *gin.Context) {
+				p := &auth.Principal{UserID: "user-1", Roles: tt.roles}
+				c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), p))
+			}, handlers.CompanyAction(appl))
+
+			req, _ := http.NewRequest(http.MethodPost, "/companies:batch", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if tt.expectedStatuses == nil {
+				return
+			}
+
+			var resp struct {
+				Results []struct {
+					Status int `json:"status"`
+				} `json:"results"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			statuses := make([]int, len(resp.Results))
+			for i, r := range resp.Results {
+				statuses[i] = r.Status
+			}
+			require.Equal(t, tt.expectedStatuses, statuses)
+		})
+	}
+}
+
+func TestCompanyActionHandler_UnknownAction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Problems(zap.NewNop()))
+
+	appl := app.New(zap.NewNop(), &mockCompanyRepo{}, &app.Config{})
+	router.POST("/companies:action", handlers.CompanyAction(appl))
+
+	req, _ := http.NewRequest(http.MethodPost, "/companies:merge", strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
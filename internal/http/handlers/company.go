@@ -1,20 +1,26 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"go.uber.org/zap"
 
 	"github.com/dagherghinescu/companies/internal/app"
 	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
 )
 
 // GetCompany returns a handler that retrieves a company by its UUID.
 // It reads the company ID from the request path, validates it,
 // calls the application service, and responds with the company data
 // or an appropriate HTTP error.
+// With the as_of query parameter (RFC 3339) the company is returned as it was at that time;
+// the route requires the viewer role for such reads.
 func GetCompany(appl *app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		idStr := c.Param("id")
@@ -22,26 +28,86 @@ func GetCompany(appl *app.App) gin.HandlerFunc {
 		// Validate UUID
 		id, err := uuid.Parse(idStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid company id"})
+			_ = c.Error(errInvalidCompanyID)
+			return
+		}
+
+		if asOf, ok := c.GetQuery("as_of"); ok {
+			t, err := time.Parse(time.RFC3339, asOf)
+			if err != nil {
+				_ = c.Error(app.Invalid("as_of must be an RFC 3339 timestamp"))
+				return
+			}
+
+			getCompanyAsOf(c, appl, id, t)
 			return
 		}
 
 		company, err := appl.GetCompany(c.Request.Context(), id)
 		if err != nil {
-			switch err {
-			case app.ErrCompanyNotFound:
-				c.JSON(http.StatusNotFound, gin.H{"error": "company not found"})
-			default:
-				appl.Logger.Error("get failed", zap.Error(err))
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-			}
+			_ = c.Error(err)
 			return
 		}
 
+		c.Header("ETag", etag(company.Version))
 		c.JSON(http.StatusOK, company)
 	}
 }
 
+func getCompanyAsOf(c *gin.Context, appl *app.App, id uuid.UUID, t time.Time) {
+	company, err := appl.CompanyAsOf(c.Request.Context(), id, t)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, company)
+}
+
+// ListCompanies returns a handler that lists companies.
+// Results can be filtered with the type, registered, min_employees,
+// max_employees and name_prefix query parameters, ordered with sort
+// (name or amount_of_employees, prefixed with "-" for descending) and
+// paginated with limit and the cursor returned by the previous page.
+func ListCompanies(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := parseListFilter(c)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		page, err := appl.ListCompanies(c.Request.Context(), filter)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// SearchCompanies returns a handler that searches companies by name and description.
+// The q query parameter holds the search terms and limit caps the number of hits.
+// Each hit carries the matched company, its relevance rank and highlighted snippets.
+func SearchCompanies(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := queryLimit(c)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		hits, err := appl.SearchCompanies(c.Request.Context(), c.Query("q"), limit)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"hits": hits})
+	}
+}
+
 // CreateCompany returns a handler that creates a new company.
 // It binds and validates the incoming JSON payload, delegates
 // creation to the application service, and responds with the
@@ -50,94 +116,298 @@ func CreateCompany(appl *app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var input models.Company
 		if err := c.ShouldBindJSON(&input); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			_ = c.Error(bindError(err))
+			return
+		}
+		if err := input.Validate(); err != nil {
+			_ = c.Error(err)
 			return
 		}
 
 		input.ID = uuid.New()
 		if err := appl.CreateCompany(c.Request.Context(), &input); err != nil {
-			if err == app.ErrCompanyAlreadyExists {
-				c.JSON(http.StatusConflict, gin.H{"error": "company with that name already exists"})
-				return
-			}
-			appl.Logger.Error("error creating the company", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			_ = c.Error(err)
+			return
 		}
 
+		c.Header("ETag", etag(input.Version))
 		c.JSON(http.StatusCreated, input)
 	}
 }
 
-// UpdateCompany returns a handler for fully updating a company resource.
+// UpdateCompany returns a handler for partially updating a company resource.
 // It parses the UUID from the path, binds the JSON body, and passes
-// the updated data to the application service.
+// the updated data to the application service. An If-Match header holding
+// the ETag from a previous read makes the update fail with 412 if the
+// company was changed in the meantime.
 func UpdateCompany(appl *app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		idStr := c.Param("id")
 
 		id, err := uuid.Parse(idStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid company id"})
+			_ = c.Error(errInvalidCompanyID)
 			return
 		}
 
 		var input models.Company
 		if err := c.ShouldBindJSON(&input); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			_ = c.Error(bindError(err))
 			return
 		}
 
-		updates := make(map[string]interface{})
-
-		if input.Name != nil {
-			updates["name"] = input.Name
-		}
-		if input.Description != nil {
-			updates["description"] = input.Description
-		}
-		if input.AmountEmployees != nil {
-			updates["amount_of_employees"] = input.AmountEmployees
-		}
-		if input.Registered != nil {
-			updates["registered"] = input.Registered
+		updates := input.PatchFields()
+		if len(updates) == 0 {
+			_ = c.Error(errNoFields)
+			return
 		}
-		if input.Type != nil {
-			updates["type"] = input.Type
+		if err := input.ValidatePatch(); err != nil {
+			_ = c.Error(err)
+			return
 		}
 
-		if len(updates) == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
+		ifMatch, err := ifMatchVersion(c.Request.Context(), appl, id, c.GetHeader("If-Match"))
+		if err != nil {
+			_ = c.Error(err)
 			return
 		}
 
-		err = appl.PatchCompany(c.Request.Context(), id, updates)
+		company, err := appl.PatchCompany(c.Request.Context(), id, updates, ifMatch)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			appl.Logger.Error("error patching company", zap.Error(err))
+			_ = c.Error(err)
 			return
 		}
 
-		c.JSON(http.StatusOK, updates)
+		c.Header("ETag", etag(company.Version))
+		c.JSON(http.StatusOK, company)
 	}
 }
 
 // DeleteCompany returns a handler that deletes a company by ID.
-// It expects the company UUID as a path parameter.
+// It expects the company UUID as a path parameter and honours If-Match
+// the same way UpdateCompany does.
 func DeleteCompany(appl *app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		idStr := c.Param("id")
 
 		id, err := uuid.Parse(idStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid company id"})
+			_ = c.Error(errInvalidCompanyID)
 			return
 		}
 
-		if err := appl.DeleteCompany(c.Request.Context(), id); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ifMatch, err := ifMatchVersion(c.Request.Context(), appl, id, c.GetHeader("If-Match"))
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		if err := appl.DeleteCompany(c.Request.Context(), id, ifMatch); err != nil {
+			_ = c.Error(err)
 			return
 		}
 
 		c.Status(http.StatusNoContent)
 	}
 }
+
+// parseListFilter builds a repository.ListFilter from the request query string.
+func parseListFilter(c *gin.Context) (repository.ListFilter, error) {
+	f, err := parseListConditions(c)
+	if err != nil {
+		return f, err
+	}
+
+	if f.Limit, err = queryLimit(c); err != nil {
+		return f, err
+	}
+
+	f.Cursor = c.Query("cursor")
+
+	return f, nil
+}
+
+// parseListConditions builds a repository.ListFilter from the filter and sort query
+// parameters, leaving out limit and cursor.
+func parseListConditions(c *gin.Context) (repository.ListFilter, error) {
+	var f repository.ListFilter
+
+	if v := c.Query("type"); v != "" {
+		t := models.CompanyType(v)
+		if !t.Valid() {
+			return f, app.Invalid(fmt.Sprintf("invalid type %q", v))
+		}
+		f.Type = &t
+	}
+
+	if v := c.Query("registered"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, app.Invalid("registered must be a boolean")
+		}
+		f.Registered = &b
+	}
+
+	if err := parseEmployeeRange(c, &f); err != nil {
+		return f, err
+	}
+
+	f.NamePrefix = c.Query("name_prefix")
+
+	if err := parseSort(c, &f); err != nil {
+		return f, err
+	}
+
+	return f, nil
+}
+
+// parseEmployeeRange reads the min_employees and max_employees query parameters into f.
+func parseEmployeeRange(c *gin.Context, f *repository.ListFilter) error {
+	var err error
+	if f.MinEmployees, err = queryInt(c, "min_employees"); err != nil {
+		return err
+	}
+	if f.MaxEmployees, err = queryInt(c, "max_employees"); err != nil {
+		return err
+	}
+	if f.MinEmployees != nil && f.MaxEmployees != nil && *f.MinEmployees > *f.MaxEmployees {
+		return app.Invalid("min_employees must not be greater than max_employees")
+	}
+	return nil
+}
+
+// parseSort reads the sort query parameter into f.
+func parseSort(c *gin.Context, f *repository.ListFilter) error {
+	sort := c.DefaultQuery("sort", string(repository.SortByName))
+	f.Descending = strings.HasPrefix(sort, "-")
+	f.Sort = repository.SortField(strings.TrimPrefix(sort, "-"))
+	if f.Sort != repository.SortByName && f.Sort != repository.SortByEmployees {
+		return app.Invalid(fmt.Sprintf("invalid sort %q", sort))
+	}
+	return nil
+}
+
+// queryInt parses an optional non-negative integer query parameter.
+func queryInt(c *gin.Context, key string) (*int, error) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return nil, app.Invalid(key + " must be a non-negative integer")
+	}
+	return &n, nil
+}
+
+// queryLimit parses the optional limit query parameter. Zero means the repository default.
+func queryLimit(c *gin.Context) (uint64, error) {
+	v := c.Query("limit")
+	if v == "" {
+		return 0, nil
+	}
+
+	limit, err := strconv.ParseUint(v, 10, 64)
+	if err != nil || limit == 0 || limit > repository.MaxListLimit {
+		return 0, app.Invalid(fmt.Sprintf("limit must be between 1 and %d", repository.MaxListLimit))
+	}
+	return limit, nil
+}
+
+// RestoreCompany returns a handler that restores a soft deleted company.
+// It responds with the restored company, or 404 if no deleted company has that ID.
+func RestoreCompany(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			_ = c.Error(errInvalidCompanyID)
+			return
+		}
+
+		company, err := appl.RestoreCompany(c.Request.Context(), id)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.Header("ETag", etag(company.Version))
+		c.JSON(http.StatusOK, company)
+	}
+}
+
+// CompanyHistory returns a handler that lists the changes made to a company, newest first.
+// Each entry holds the actor, action, timestamp and the company before and after the change.
+// Results are paginated with limit and the cursor returned by the previous page.
+func CompanyHistory(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			_ = c.Error(errInvalidCompanyID)
+			return
+		}
+
+		limit, err := queryLimit(c)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		page, err := appl.CompanyHistory(c.Request.Context(), id, limit, c.Query("cursor"))
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// DiffCompany returns a handler that lists the fields of a company that changed
+// between the from and to query parameters (RFC 3339). to defaults to now.
+func DiffCompany(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			_ = c.Error(errInvalidCompanyID)
+			return
+		}
+
+		from, err := time.Parse(time.RFC3339, c.Query("from"))
+		if err != nil {
+			_ = c.Error(app.Invalid("from must be an RFC 3339 timestamp"))
+			return
+		}
+
+		to := time.Now().UTC()
+		if s, ok := c.GetQuery("to"); ok {
+			if to, err = time.Parse(time.RFC3339, s); err != nil {
+				_ = c.Error(app.Invalid("to must be an RFC 3339 timestamp"))
+				return
+			}
+		}
+
+		diff, err := appl.DiffCompany(c.Request.Context(), id, from, to)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, diff)
+	}
+}
+
+// PurgeCompanies returns a handler that permanently removes the companies
+// deleted longer ago than the configured retention period, along with the
+// snapshots of them kept by the audit trail, the outbox and webhook deliveries.
+func PurgeCompanies(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		purged, err := appl.PurgeCompanies(c.Request.Context())
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"purged": purged})
+	}
+}
@@ -3,47 +3,99 @@ package handlers_test
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
 	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/auth"
 	"github.com/dagherghinescu/companies/internal/http/handlers"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
 	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
 )
 
 type mockCompanyRepo struct {
 	GetByIDFn func(ctx context.Context, id uuid.UUID) (*models.Company, error)
+	ListFn    func(ctx context.Context, f repository.ListFilter) (*repository.CompanyPage, error)
+	SearchFn  func(ctx context.Context, q string, limit uint64) ([]repository.SearchHit, error)
 	CreateFn  func(ctx context.Context, c *models.Company) error
-	PatchFn   func(ctx context.Context, id uuid.UUID, fields map[string]interface{}) error
-	DeleteFn  func(ctx context.Context, id uuid.UUID) error
+	PatchFn   func(ctx context.Context, id uuid.UUID, fields map[string]interface{}, version *int64) error
+	DeleteFn  func(ctx context.Context, id uuid.UUID, deletedBy string, version *int64) error
+	RestoreFn func(ctx context.Context, id uuid.UUID) error
+	PurgeFn   func(ctx context.Context, deletedBefore time.Time) ([]uuid.UUID, error)
+	HistoryFn func(ctx context.Context, id uuid.UUID, limit uint64, cursor string) (*repository.HistoryPage, error)
+	AuditAtFn func(ctx context.Context, id uuid.UUID, t time.Time) (*models.AuditEntry, error)
+	TakenFn   func(ctx context.Context, names []string) ([]string, error)
+	ExportFn  func(ctx context.Context, f repository.ListFilter, fn func(c *models.Company) error) error
+	audit     []models.AuditEntry
 }
 
 func (m *mockCompanyRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Company, error) {
 	return m.GetByIDFn(ctx, id)
 }
+func (m *mockCompanyRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Company, error) {
+	return m.GetByIDFn(ctx, id)
+}
+func (m *mockCompanyRepo) List(ctx context.Context, f repository.ListFilter) (*repository.CompanyPage, error) {
+	return m.ListFn(ctx, f)
+}
+func (m *mockCompanyRepo) Search(ctx context.Context, q string, limit uint64) ([]repository.SearchHit, error) {
+	return m.SearchFn(ctx, q, limit)
+}
+func (m *mockCompanyRepo) Export(
+	ctx context.Context, f repository.ListFilter, fn func(c *models.Company) error,
+) error {
+	return m.ExportFn(ctx, f, fn)
+}
+func (m *mockCompanyRepo) TakenNames(ctx context.Context, names []string) ([]string, error) {
+	return m.TakenFn(ctx, names)
+}
 func (m *mockCompanyRepo) Create(ctx context.Context, c *models.Company) error {
 	return m.CreateFn(ctx, c)
 }
-func (m *mockCompanyRepo) Patch(ctx context.Context, id uuid.UUID, fields map[string]interface{}) error {
-	return m.PatchFn(ctx, id, fields)
+func (m *mockCompanyRepo) Patch(
+	ctx context.Context, id uuid.UUID, fields map[string]interface{}, version *int64,
+) error {
+	return m.PatchFn(ctx, id, fields, version)
 }
-func (m *mockCompanyRepo) Delete(ctx context.Context, id uuid.UUID) error {
-	return m.DeleteFn(ctx, id)
+func (m *mockCompanyRepo) Delete(ctx context.Context, id uuid.UUID, deletedBy string, version *int64) error {
+	return m.DeleteFn(ctx, id, deletedBy, version)
+}
+func (m *mockCompanyRepo) Restore(ctx context.Context, id uuid.UUID) error {
+	return m.RestoreFn(ctx, id)
+}
+func (m *mockCompanyRepo) Purge(ctx context.Context, deletedBefore time.Time) ([]uuid.UUID, error) {
+	return m.PurgeFn(ctx, deletedBefore)
+}
+func (m *mockCompanyRepo) AddOutboxEvent(_ context.Context, _ string, _ []byte) error {
+	return nil
+}
+func (m *mockCompanyRepo) AddAudit(_ context.Context, e *models.AuditEntry) error {
+	m.audit = append(m.audit, *e)
+	return nil
+}
+func (m *mockCompanyRepo) History(
+	ctx context.Context, id uuid.UUID, limit uint64, cursor string,
+) (*repository.HistoryPage, error) {
+	return m.HistoryFn(ctx, id, limit, cursor)
+}
+func (m *mockCompanyRepo) AuditAt(ctx context.Context, id uuid.UUID, t time.Time) (*models.AuditEntry, error) {
+	return m.AuditAtFn(ctx, id, t)
+}
+func (m *mockCompanyRepo) InTx(_ context.Context, fn func(tx repository.Company) error) error {
+	return fn(m)
 }
-
-// MockProducer does nothing
-type mockProducer struct{}
-
-func (m *mockProducer) Publish(_ context.Context, _ string, _ any) error { return nil }
-func (m *mockProducer) Close() error                                     { return nil }
 
 func TestGetCompanyHandler(t *testing.T) {
 	id := uuid.New()
@@ -91,19 +143,46 @@ func TestGetCompanyHandler(t *testing.T) {
 			},
 			expectedCode: http.StatusInternalServerError,
 		},
+		{
+			name:  "as of",
+			param: id.String() + "?as_of=2026-01-01T00:00:00Z",
+			mockSetup: func(m *mockCompanyRepo) {
+				m.AuditAtFn = func(_ context.Context, _ uuid.UUID, at time.Time) (*models.AuditEntry, error) {
+					require.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), at)
+					return &models.AuditEntry{After: company}, nil
+				}
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "as of before creation",
+			param: id.String() + "?as_of=2020-01-01T00:00:00Z",
+			mockSetup: func(m *mockCompanyRepo) {
+				m.AuditAtFn = func(_ context.Context, _ uuid.UUID, _ time.Time) (*models.AuditEntry, error) {
+					return nil, sql.ErrNoRows
+				}
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid as of",
+			param:        id.String() + "?as_of=yesterday",
+			mockSetup:    func(_ *mockCompanyRepo) {},
+			expectedCode: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			gin.SetMode(gin.TestMode)
 			router := gin.New()
+			router.Use(middleware.Problems(zap.NewNop()))
 
 			mockRepo := &mockCompanyRepo{}
-			mockProducer := &mockProducer{}
 			tt.mockSetup(mockRepo)
 
 			logger := zap.NewNop()
-			appl := app.New(logger, mockRepo, mockProducer)
+			appl := app.New(logger, mockRepo, &app.Config{})
 
 			router.GET("/companies/:id", handlers.GetCompany(appl))
 
@@ -116,8 +195,168 @@ func TestGetCompanyHandler(t *testing.T) {
 	}
 }
 
+func TestListCompaniesHandler(t *testing.T) {
+	company := models.Company{ID: uuid.New(), Name: ptrString("Acme")}
+
+	tests := []struct {
+		name         string
+		query        string
+		mockSetup    func(repo *mockCompanyRepo)
+		expectedCode int
+	}{
+		{
+			name:  "success",
+			query: "?type=Corporation&registered=true&min_employees=1&max_employees=100&sort=-name&limit=10",
+			mockSetup: func(m *mockCompanyRepo) {
+				m.ListFn = func(_ context.Context, f repository.ListFilter) (*repository.CompanyPage, error) {
+					require.Equal(t, models.Corporation, *f.Type)
+					require.True(t, *f.Registered)
+					require.Equal(t, 1, *f.MinEmployees)
+					require.Equal(t, 100, *f.MaxEmployees)
+					require.Equal(t, repository.SortByName, f.Sort)
+					require.True(t, f.Descending)
+					require.Equal(t, uint64(10), f.Limit)
+					return &repository.CompanyPage{Companies: []models.Company{company}}, nil
+				}
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid type",
+			query:        "?type=Unknown",
+			mockSetup:    func(_ *mockCompanyRepo) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid employee range",
+			query:        "?min_employees=10&max_employees=1",
+			mockSetup:    func(_ *mockCompanyRepo) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid sort",
+			query:        "?sort=description",
+			mockSetup:    func(_ *mockCompanyRepo) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:  "invalid cursor",
+			query: "?cursor=garbage",
+			mockSetup: func(m *mockCompanyRepo) {
+				m.ListFn = func(_ context.Context, _ repository.ListFilter) (*repository.CompanyPage, error) {
+					return nil, repository.ErrInvalidCursor
+				}
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "internal error",
+			mockSetup: func(m *mockCompanyRepo) {
+				m.ListFn = func(_ context.Context, _ repository.ListFilter) (*repository.CompanyPage, error) {
+					return nil, errors.New("db error")
+				}
+			},
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.Problems(zap.NewNop()))
+
+			mockRepo := &mockCompanyRepo{}
+			tt.mockSetup(mockRepo)
+
+			logger := zap.NewNop()
+			appl := app.New(logger, mockRepo, &app.Config{})
+
+			router.GET("/companies", handlers.ListCompanies(appl))
+
+			req, _ := http.NewRequest(http.MethodGet, "/companies"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestSearchCompaniesHandler(t *testing.T) {
+	hit := repository.SearchHit{Company: models.Company{ID: uuid.New(), Name: ptrString("Acme")}, Rank: 0.5}
+
+	tests := []struct {
+		name         string
+		query        string
+		mockSetup    func(repo *mockCompanyRepo)
+		expectedCode int
+	}{
+		{
+			name:  "success",
+			query: "?q=acme&limit=5",
+			mockSetup: func(m *mockCompanyRepo) {
+				m.SearchFn = func(_ context.Context, q string, limit uint64) ([]repository.SearchHit, error) {
+					require.Equal(t, "acme", q)
+					require.Equal(t, uint64(5), limit)
+					return []repository.SearchHit{hit}, nil
+				}
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "missing query",
+			query:        "?q=%20",
+			mockSetup:    func(_ *mockCompanyRepo) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid limit",
+			query:        "?q=acme&limit=0",
+			mockSetup:    func(_ *mockCompanyRepo) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:  "internal error",
+			query: "?q=acme",
+			mockSetup: func(m *mockCompanyRepo) {
+				m.SearchFn = func(_ context.Context, _ string, _ uint64) ([]repository.SearchHit, error) {
+					return nil, errors.New("db error")
+				}
+			},
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.Problems(zap.NewNop()))
+
+			mockRepo := &mockCompanyRepo{}
+			tt.mockSetup(mockRepo)
+
+			logger := zap.NewNop()
+			appl := app.New(logger, mockRepo, &app.Config{})
+
+			router.GET("/companies/search", handlers.SearchCompanies(appl))
+
+			req, _ := http.NewRequest(http.MethodGet, "/companies/search"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
 func TestCreateCompanyHandler(t *testing.T) {
-	company := models.Company{Name: ptrString("Acme")}
+	employees, registered, ctype := 10, true, models.Corporation
+	company := models.Company{Name: ptrString("Acme"), AmountEmployees: &employees, Registered: &registered,
+		Type: &ctype}
+	invalid := company
+	invalid.Name = ptrString("A name that is far too long")
 
 	tests := []struct {
 		name         string
@@ -141,6 +380,12 @@ func TestCreateCompanyHandler(t *testing.T) {
 			mockSetup:    func(_ *mockCompanyRepo) {},
 			expectedCode: http.StatusBadRequest,
 		},
+		{
+			name:         "invalid company",
+			body:         invalid,
+			mockSetup:    func(_ *mockCompanyRepo) {},
+			expectedCode: http.StatusBadRequest,
+		},
 		{
 			name: "conflict",
 			body: company,
@@ -167,13 +412,13 @@ func TestCreateCompanyHandler(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			gin.SetMode(gin.TestMode)
 			router := gin.New()
+			router.Use(middleware.Problems(zap.NewNop()))
 
 			mockRepo := &mockCompanyRepo{}
-			mockProducer := &mockProducer{}
 			tt.mockSetup(mockRepo)
 
 			logger := zap.NewNop()
-			appl := app.New(logger, mockRepo, mockProducer)
+			appl := app.New(logger, mockRepo, &app.Config{})
 
 			router.POST("/companies", handlers.CreateCompany(appl))
 
@@ -189,5 +434,396 @@ func TestCreateCompanyHandler(t *testing.T) {
 	}
 }
 
+func TestUpdateCompanyHandler(t *testing.T) {
+	id := uuid.New()
+	body := map[string]interface{}{"description": "Updated"}
+
+	tests := []struct {
+		name         string
+		ifMatch      string
+		mockSetup    func(repo *mockCompanyRepo)
+		expectedCode int
+		expectedETag string
+		expectedType string
+	}{
+		{
+			name:    "success with matching version",
+			ifMatch: `"3"`,
+			mockSetup: func(m *mockCompanyRepo) {
+				m.PatchFn = func(_ context.Context, _ uuid.UUID, _ map[string]interface{}, version *int64) error {
+					require.Equal(t, int64(3), *version)
+					return nil
+				}
+				m.GetByIDFn = func(_ context.Context, _ uuid.UUID) (*models.Company, error) {
+					return &models.Company{ID: id, Name: ptrString("Acme"), Version: 4}, nil
+				}
+			},
+			expectedCode: http.StatusOK,
+			expectedETag: `"4"`,
+		},
+		{
+			name: "success without If-Match",
+			mockSetup: func(m *mockCompanyRepo) {
+				m.PatchFn = func(_ context.Context, _ uuid.UUID, _ map[string]interface{}, version *int64) error {
+					require.Nil(t, version)
+					return nil
+				}
+				m.GetByIDFn = func(_ context.Context, _ uuid.UUID) (*models.Company, error) {
+					return &models.Company{ID: id, Name: ptrString("Acme"), Version: 2}, nil
+				}
+			},
+			expectedCode: http.StatusOK,
+			expectedETag: `"2"`,
+		},
+		{
+			name:    "stale version",
+			ifMatch: `"1"`,
+			mockSetup: func(m *mockCompanyRepo) {
+				m.GetByIDFn = func(_ context.Context, _ uuid.UUID) (*models.Company, error) {
+					return &models.Company{ID: id, Name: ptrString("Acme"), Version: 2}, nil
+				}
+				m.PatchFn = func(_ context.Context, _ uuid.UUID, _ map[string]interface{}, _ *int64) error {
+					return repository.ErrVersionMismatch
+				}
+			},
+			expectedCode: http.StatusPreconditionFailed,
+		},
+		{
+			name: "not found",
+			mockSetup: func(m *mockCompanyRepo) {
+				m.GetByIDFn = func(_ context.Context, _ uuid.UUID) (*models.Company, error) {
+					return nil, sql.ErrNoRows
+				}
+			},
+			expectedCode: http.StatusNotFound,
+			expectedType: "/problems/not-found",
+		},
+		{
+			name:    "several entity tags with the current version",
+			ifMatch: `"1", W/"3", "2"`,
+			mockSetup: func(m *mockCompanyRepo) {
+				m.GetByIDFn = func(_ context.Context, _ uuid.UUID) (*models.Company, error) {
+					return &models.Company{ID: id, Name: ptrString("Acme"), Version: 2}, nil
+				}
+				m.PatchFn = func(_ context.Context, _ uuid.UUID, _ map[string]interface{}, version *int64) error {
+					require.Equal(t, int64(2), *version)
+					return nil
+				}
+			},
+			expectedCode: http.StatusOK,
+			expectedETag: `"2"`,
+		},
+		{
+			name:    "several stale entity tags",
+			ifMatch: `"1", "3"`,
+			mockSetup: func(m *mockCompanyRepo) {
+				m.GetByIDFn = func(_ context.Context, _ uuid.UUID) (*models.Company, error) {
+					return &models.Company{ID: id, Name: ptrString("Acme"), Version: 2}, nil
+				}
+				m.PatchFn = func(_ context.Context, _ uuid.UUID, _ map[string]interface{}, version *int64) error {
+					require.Equal(t, int64(-1), *version)
+					return repository.ErrVersionMismatch
+				}
+			},
+			expectedCode: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.Problems(zap.NewNop()))
+
+			mockRepo := &mockCompanyRepo{}
+			tt.mockSetup(mockRepo)
+
+			logger := zap.NewNop()
+			appl := app.New(logger, mockRepo, &app.Config{})
+
+			router.PATCH("/companies/:id", handlers.UpdateCompany(appl))
+
+			bodyBytes, _ := json.Marshal(body)
+			req, _ := http.NewRequest(http.MethodPatch, "/companies/"+id.String(), bytes.NewReader(bodyBytes))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code)
+			require.Equal(t, tt.expectedETag, w.Header().Get("ETag"))
+			if tt.expectedType != "" {
+				var problem middleware.Problem
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+				require.Equal(t, tt.expectedType, problem.Type)
+				require.Equal(t, tt.expectedCode, problem.Status)
+			}
+		})
+	}
+}
+
+func TestRestoreCompanyHandler(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name         string
+		mockSetup    func(repo *mockCompanyRepo)
+		expectedCode int
+	}{
+		{
+			name: "success",
+			mockSetup: func(m *mockCompanyRepo) {
+				m.RestoreFn = func(_ context.Context, _ uuid.UUID) error { return nil }
+				m.GetByIDFn = func(_ context.Context, _ uuid.UUID) (*models.Company, error) {
+					return &models.Company{ID: id, Name: ptrString("Acme"), Version: 3}, nil
+				}
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "not deleted",
+			mockSetup: func(m *mockCompanyRepo) {
+				m.RestoreFn = func(_ context.Context, _ uuid.UUID) error { return sql.ErrNoRows }
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name: "name taken meanwhile",
+			mockSetup: func(m *mockCompanyRepo) {
+				m.RestoreFn = func(_ context.Context, _ uuid.UUID) error { return &pq.Error{Code: "23505"} }
+			},
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.Problems(zap.NewNop()))
+
+			mockRepo := &mockCompanyRepo{}
+			tt.mockSetup(mockRepo)
+
+			logger := zap.NewNop()
+			appl := app.New(logger, mockRepo, &app.Config{})
+
+			router.POST("/companies/:id/restore", handlers.RestoreCompany(appl))
+
+			req, _ := http.NewRequest(http.MethodPost, "/companies/"+id.String()+"/restore", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestPurgeCompaniesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Problems(zap.NewNop()))
+
+	retention := 30 * 24 * time.Hour
+	mockRepo := &mockCompanyRepo{
+		PurgeFn: func(_ context.Context, deletedBefore time.Time) ([]uuid.UUID, error) {
+			require.WithinDuration(t, time.Now().Add(-retention), deletedBefore, time.Minute)
+			return []uuid.UUID{uuid.New(), uuid.New()}, nil
+		},
+	}
+
+	appl := app.New(zap.NewNop(), mockRepo, &app.Config{PurgeRetention: retention})
+	router.POST("/companies/purge", handlers.PurgeCompanies(appl))
+
+	req, _ := http.NewRequest(http.MethodPost, "/companies/purge", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"purged":2}`, w.Body.String())
+}
+
+func TestCompanyHistoryHandler(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name         string
+		query        string
+		mockSetup    func(repo *mockCompanyRepo)
+		expectedCode int
+	}{
+		{
+			name:  "success",
+			query: "?limit=5",
+			mockSetup: func(m *mockCompanyRepo) {
+				m.HistoryFn = func(
+					_ context.Context, _ uuid.UUID, limit uint64, _ string,
+				) (*repository.HistoryPage, error) {
+					require.Equal(t, uint64(5), limit)
+					return &repository.HistoryPage{Entries: []models.AuditEntry{{CompanyID: id}}}, nil
+				}
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "unknown company",
+			mockSetup: func(m *mockCompanyRepo) {
+				m.HistoryFn = func(
+					_ context.Context, _ uuid.UUID, _ uint64, _ string,
+				) (*repository.HistoryPage, error) {
+					return &repository.HistoryPage{}, nil
+				}
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:  "invalid cursor",
+			query: "?cursor=garbage",
+			mockSetup: func(m *mockCompanyRepo) {
+				m.HistoryFn = func(
+					_ context.Context, _ uuid.UUID, _ uint64, _ string,
+				) (*repository.HistoryPage, error) {
+					return nil, repository.ErrInvalidCursor
+				}
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.Problems(zap.NewNop()))
+
+			mockRepo := &mockCompanyRepo{}
+			tt.mockSetup(mockRepo)
+
+			logger := zap.NewNop()
+			appl := app.New(logger, mockRepo, &app.Config{})
+
+			router.GET("/companies/:id/history", handlers.CompanyHistory(appl))
+
+			req, _ := http.NewRequest(http.MethodGet, "/companies/"+id.String()+"/history"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestDiffCompanyHandler(t *testing.T) {
+	id := uuid.New()
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		query        string
+		states       map[time.Time]*models.Company
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:  "renamed",
+			query: "?from=2026-01-01T00:00:00Z&to=2026-02-01T00:00:00Z",
+			states: map[time.Time]*models.Company{
+				from: {ID: id, Name: ptrString("Acme"), Version: 1},
+				to:   {ID: id, Name: ptrString("Acme 2"), Version: 2},
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"from":"2026-01-01T00:00:00Z","to":"2026-02-01T00:00:00Z",` +
+				`"changes":[{"field":"name","from":"Acme","to":"Acme 2"}]}`,
+		},
+		{
+			name:         "unknown company",
+			query:        "?from=2026-01-01T00:00:00Z&to=2026-02-01T00:00:00Z",
+			states:       map[time.Time]*models.Company{},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "from after to",
+			query:        "?from=2026-02-01T00:00:00Z&to=2026-01-01T00:00:00Z",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "missing from",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.Problems(zap.NewNop()))
+
+			mockRepo := &mockCompanyRepo{
+				AuditAtFn: func(_ context.Context, _ uuid.UUID, at time.Time) (*models.AuditEntry, error) {
+					if c, ok := tt.states[at]; ok {
+						return &models.AuditEntry{After: c}, nil
+					}
+					return nil, sql.ErrNoRows
+				},
+			}
+			appl := app.New(zap.NewNop(), mockRepo, &app.Config{})
+
+			router.GET("/companies/:id/diff", handlers.DiffCompany(appl))
+
+			req, _ := http.NewRequest(http.MethodGet, "/companies/"+id.String()+"/diff"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				require.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUpdateCompanyHandler_RecordsAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Problems(zap.NewNop()))
+
+	id := uuid.New()
+	version := int64(1)
+	mockRepo := &mockCompanyRepo{
+		GetByIDFn: func(_ context.Context, _ uuid.UUID) (*models.Company, error) {
+			return &models.Company{ID: id, Name: ptrString("Acme"), Version: version}, nil
+		},
+		PatchFn: func(_ context.Context, _ uuid.UUID, _ map[string]interface{}, _ *int64) error {
+			version++
+			return nil
+		},
+	}
+
+	appl := app.New(zap.NewNop(), mockRepo, &app.Config{})
+	router.PATCH("/companies/:id", func(c *gin.Context) {
+		principal := &auth.Principal{UserID: "user-1", Roles: []models.Role{models.RoleEditor}}
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+	}, handlers.UpdateCompany(appl))
+
+	req, _ := http.NewRequest(http.MethodPatch, "/companies/"+id.String(), bytes.NewReader([]byte(`{"name":"Acme"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, mockRepo.audit, 1)
+	entry := mockRepo.audit[0]
+	require.Equal(t, "user-1", entry.Actor)
+	require.Equal(t, models.ActionUpdated, entry.Action)
+	require.Equal(t, int64(1), entry.Before.Version)
+	require.Equal(t, int64(2), entry.After.Version)
+}
+
 // helper
 func ptrString(s string) *string { return &s }
+func ptrInt(n int) *int          { return &n }
+func ptrBool(b bool) *bool       { return &b }
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+var (
+	errInvalidCompanyID = app.Invalid("invalid company id")
+	errNoFields         = app.Invalid("no fields to update")
+	errInvalidWebhookID = app.Invalid("invalid webhook id")
+	errInvalidReplayID  = app.Invalid("invalid replay id")
+	errSchemaNotFound   = app.NewError(app.KindNotFound, "event schema not found")
+)
+
+// bindError converts a request body decoding error into a validation error,
+// naming the field when a value has the wrong JSON type.
+func bindError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &app.Error{
+			Kind:    app.KindValidation,
+			Message: "invalid request body",
+			Fields:  []models.FieldError{{Field: typeErr.Field, Message: "cannot be a JSON " + typeErr.Value}},
+		}
+	}
+	return app.Invalid("invalid request body: " + err.Error())
+}
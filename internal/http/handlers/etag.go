@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/dagherghinescu/companies/internal/app"
+)
+
+// unmatchableVersion is a version no company has, required by an If-Match header none
+// of whose entity tags can match.
+const unmatchableVersion = int64(-1)
+
+// etag formats a company version as a strong entity tag.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseIfMatch returns the versions listed by an If-Match header.
+// It returns nil when the header is absent or "*", since any existing company matches.
+// A weak or malformed tag can never match a strong comparison, so it is left out;
+// a header made only of those yields an empty list, which no company matches.
+func parseIfMatch(header string) []int64 {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	versions := make([]int64, 0, 1)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+			continue
+		}
+		if version, err := strconv.ParseInt(strings.Trim(tag, `"`), 10, 64); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+// ifMatchVersion returns the version a write of company id requires under an If-Match
+// header, or nil when the write is unconditional. When the header lists several entity
+// tags, it requires the current version of the company if one of them matches it, so
+// that the write still fails with 412 Precondition Failed if the company changes first.
+func ifMatchVersion(ctx context.Context, appl *app.App, id uuid.UUID, header string) (*int64, error) {
+	versions := parseIfMatch(header)
+	switch {
+	case versions == nil:
+		return nil, nil
+	case len(versions) == 1:
+		return &versions[0], nil
+	}
+
+	unmatchable := unmatchableVersion
+	if len(versions) == 0 {
+		return &unmatchable, nil
+	}
+
+	company, err := appl.GetCompany(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(versions, company.Version) {
+		return &company.Version, nil
+	}
+	return &unmatchable, nil
+}
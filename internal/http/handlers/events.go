@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dagherghinescu/companies/internal/events"
+)
+
+// EventSchema serves the JSON Schema of the data of an event type, the one
+// referenced by the dataschema attribute of its events.
+func EventSchema() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schema, ok := events.Schema(c.Param("name"))
+		if !ok {
+			_ = c.Error(errSchemaNotFound)
+			return
+		}
+
+		c.Data(http.StatusOK, "application/schema+json", schema)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/exporter"
+)
+
+// exportErrorTrailer is set when an export fails after the response has started,
+// since the status code can no longer be changed by then.
+const exportErrorTrailer = "X-Export-Error"
+
+// ExportCompanies returns a handler that streams every company matching the listing
+// filters as a file download. The format query parameter selects csv (the default),
+// ndjson or parquet, and limit caps the number of rows; cursor is ignored.
+func ExportCompanies(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := parseListConditions(c)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		if filter.Limit, err = exportLimit(c); err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		format := exporter.Format(c.DefaultQuery("format", string(exporter.FormatCSV)))
+		w, err := exporter.New(format, c.Writer)
+		if err != nil {
+			_ = c.Error(app.Invalid("format must be csv, ndjson or parquet"))
+			return
+		}
+
+		fileName := fmt.Sprintf("companies-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+		c.Header("Content-Type", format.ContentType())
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+		c.Header("Trailer", exportErrorTrailer)
+
+		err = appl.ExportCompanies(c.Request.Context(), filter, w.Write)
+		if err == nil {
+			err = w.Close()
+		}
+		if err == nil {
+			c.Status(http.StatusOK)
+			return
+		}
+
+		if !c.Writer.Written() {
+			c.Header("Content-Disposition", "")
+			c.Header("Trailer", "")
+			_ = c.Error(err)
+			return
+		}
+		appl.Logger.Error("export failed", zap.Error(err))
+		c.Writer.Header().Set(exportErrorTrailer, "export failed")
+	}
+}
+
+// exportLimit parses the optional limit query parameter of an export. Unlike the limit
+// of a listing it has no maximum; zero, when it is absent, exports every company.
+func exportLimit(c *gin.Context) (uint64, error) {
+	v := c.Query("limit")
+	if v == "" {
+		return 0, nil
+	}
+
+	limit, err := strconv.ParseUint(v, 10, 64)
+	if err != nil || limit == 0 {
+		return 0, app.Invalid("limit must be a positive integer")
+	}
+	return limit, nil
+}
@@ -0,0 +1,114 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/http/handlers"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+func TestExportCompaniesHandler(t *testing.T) {
+	company := &models.Company{ID: uuid.New(), Name: ptrString("Acme")}
+
+	tests := []struct {
+		name         string
+		query        string
+		limit        uint64
+		exportErr    error
+		expectedCode int
+		expectedType string
+		expectedFile string
+	}{
+		{
+			name:         "csv by default",
+			query:        "?type=Corporation",
+			expectedCode: http.StatusOK,
+			expectedType: "text/csv",
+			expectedFile: ".csv",
+		},
+		{
+			name:         "ndjson",
+			query:        "?format=ndjson",
+			expectedCode: http.StatusOK,
+			expectedType: "application/x-ndjson",
+			expectedFile: ".ndjson",
+		},
+		{
+			name:         "with a limit",
+			query:        "?format=ndjson&limit=5000",
+			limit:        5000,
+			expectedCode: http.StatusOK,
+			expectedType: "application/x-ndjson",
+			expectedFile: ".ndjson",
+		},
+		{
+			name:         "invalid limit",
+			query:        "?limit=0",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown format",
+			query:        "?format=xlsx",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid filter",
+			query:        "?min_employees=many",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "database error",
+			exportErr:    errors.New("db error"),
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.Problems(zap.NewNop()))
+
+			mockRepo := &mockCompanyRepo{
+				ExportFn: func(_ context.Context, f repository.ListFilter, fn func(c *models.Company) error) error {
+					require.Equal(t, tt.limit, f.Limit)
+					if tt.exportErr != nil {
+						return tt.exportErr
+					}
+					return fn(company)
+				},
+			}
+			appl := app.New(zap.NewNop(), mockRepo, &app.Config{})
+
+			router.GET("/companies/export", handlers.ExportCompanies(appl))
+
+			req, _ := http.NewRequest(http.MethodGet, "/companies/export"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode != http.StatusOK {
+				require.Empty(t, w.Header().Get("Content-Disposition"))
+				return
+			}
+			require.Equal(t, tt.expectedType, w.Header().Get("Content-Type"))
+			disposition := w.Header().Get("Content-Disposition")
+			require.True(t, strings.HasPrefix(disposition, `attachment; filename=companies-`), disposition)
+			require.True(t, strings.HasSuffix(disposition, tt.expectedFile), disposition)
+			require.Contains(t, w.Body.String(), "Acme")
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/importer"
+)
+
+// ImportCompanies returns a handler that creates companies from a CSV or NDJSON upload.
+// The file is either the request body or the "file" part of a multipart form, and is
+// streamed row by row. The format is taken from the format query parameter, or else
+// from the content type or file extension. With dry_run=true rows are only validated.
+// The response is a report of the accepted rows and the errors of the rejected ones.
+func ImportCompanies(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dryRun := false
+		if v, ok := c.GetQuery("dry_run"); ok {
+			var err error
+			if dryRun, err = strconv.ParseBool(v); err != nil {
+				_ = c.Error(app.Invalid("dry_run must be true or false"))
+				return
+			}
+		}
+
+		file, format, err := importUpload(c)
+		if err != nil {
+			_ = c.Error(app.Invalid(err.Error()))
+			return
+		}
+
+		src, err := importer.New(format, file)
+		if err != nil {
+			if errors.Is(err, importer.ErrUnknownFormat) {
+				_ = c.Error(app.Invalid("format must be csv or ndjson"))
+				return
+			}
+			_ = c.Error(app.Invalid(err.Error()))
+			return
+		}
+
+		report, err := appl.ImportCompanies(c.Request.Context(), src, dryRun)
+		if err != nil {
+			c.Error(err).SetMeta(gin.H{"report": report})
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// importUpload returns the uploaded file of an import request and its format.
+func importUpload(c *gin.Context) (io.Reader, importer.Format, error) {
+	format := importer.Format(c.Query("format"))
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		if format == "" {
+			format = importFormat(mediaType, "")
+		}
+		return c.Request.Body, format, nil
+	}
+
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, "", errors.New("missing file part")
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		if format == "" {
+			partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			format = importFormat(partType, part.FileName())
+		}
+		return part, format, nil
+	}
+}
+
+// importFormat guesses the format of an upload from its media type or file name.
+func importFormat(mediaType, fileName string) importer.Format {
+	switch {
+	case mediaType == "text/csv", strings.HasSuffix(fileName, ".csv"):
+		return importer.FormatCSV
+	case mediaType == "application/x-ndjson", mediaType == "application/ndjson",
+		strings.HasSuffix(fileName, ".ndjson"), strings.HasSuffix(fileName, ".jsonl"):
+		return importer.FormatNDJSON
+	default:
+		return ""
+	}
+}
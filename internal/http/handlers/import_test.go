@@ -0,0 +1,165 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/http/handlers"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+func TestImportCompaniesHandler(t *testing.T) {
+	csvFile := "name,amount_of_employees,registered,type\n" +
+		"Acme,10,true,Corporation\n" +
+		"Globex,-5,true,Corporation\n" +
+		"Taken,3,false,NonProfit\n" +
+		"Acme,4,true,Cooperative\n"
+	// Acme is repeated in the second chunk of 100 rows, after the first one was created.
+	largeFile := "name,amount_of_employees,registered,type\nAcme,10,true,Corporation\n"
+	for i := range 100 {
+		largeFile += fmt.Sprintf("Company %d,10,true,Corporation\n", i)
+	}
+	largeFile += "Acme,4,true,Cooperative\n"
+	ndjsonFile := `{"name":"Acme","amount_of_employees":10,"registered":true,"type":"Corporation"}` + "\n"
+
+	multipartBody := func(fileName, content string) (*bytes.Buffer, string) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		part, err := mw.CreateFormFile("file", fileName)
+		require.NoError(t, err)
+		_, err = part.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, mw.Close())
+		return &buf, mw.FormDataContentType()
+	}
+
+	tests := []struct {
+		name            string
+		query           string
+		body            func() (*bytes.Buffer, string)
+		expectedCode    int
+		expectedCreated int
+		expectedReport  *app.ImportReport
+	}{
+		{
+			name: "csv body",
+			body: func() (*bytes.Buffer, string) {
+				return bytes.NewBufferString(csvFile), "text/csv"
+			},
+			expectedCode:    http.StatusOK,
+			expectedCreated: 1,
+			expectedReport:  &app.ImportReport{Rows: 4, Accepted: 1, Rejected: 3},
+		},
+		{
+			name:  "dry run",
+			query: "?dry_run=true",
+			body: func() (*bytes.Buffer, string) {
+				return bytes.NewBufferString(csvFile), "text/csv"
+			},
+			expectedCode:   http.StatusOK,
+			expectedReport: &app.ImportReport{DryRun: true, Rows: 4, Accepted: 1, Rejected: 3},
+		},
+		{
+			name: "name taken by an earlier chunk",
+			body: func() (*bytes.Buffer, string) {
+				return bytes.NewBufferString(largeFile), "text/csv"
+			},
+			expectedCode:    http.StatusOK,
+			expectedCreated: 101,
+			expectedReport: &app.ImportReport{Rows: 102, Accepted: 101, Rejected: 1, Errors: []app.ImportError{
+				{Line: 103, Error: app.ErrCompanyAlreadyExists.Error()},
+			}},
+		},
+		{
+			name: "ndjson multipart",
+			body: func() (*bytes.Buffer, string) {
+				return multipartBody("companies.ndjson", ndjsonFile)
+			},
+			expectedCode:    http.StatusOK,
+			expectedCreated: 1,
+			expectedReport:  &app.ImportReport{Rows: 1, Accepted: 1},
+		},
+		{
+			name: "unknown format",
+			body: func() (*bytes.Buffer, string) {
+				return bytes.NewBufferString(csvFile), "application/vnd.ms-excel"
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name: "invalid header",
+			body: func() (*bytes.Buffer, string) {
+				return bytes.NewBufferString("company,size\n"), "text/csv"
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.Problems(zap.NewNop()))
+
+			created := map[string]bool{"Taken": true}
+			mockRepo := &mockCompanyRepo{
+				TakenFn: func(_ context.Context, names []string) ([]string, error) {
+					var taken []string
+					for _, name := range names {
+						if created[name] {
+							taken = append(taken, name)
+						}
+					}
+					return taken, nil
+				},
+				CreateFn: func(_ context.Context, c *models.Company) error {
+					created[*c.Name] = true
+					return nil
+				},
+			}
+			appl := app.New(zap.NewNop(), mockRepo, &app.Config{})
+
+			router.POST("/companies/import", handlers.ImportCompanies(appl))
+
+			body, contentType := tt.body()
+			req, _ := http.NewRequest(http.MethodPost, "/companies/import"+tt.query, body)
+			req.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			require.Len(t, created, tt.expectedCreated+1)
+			if tt.expectedReport == nil {
+				return
+			}
+
+			var report app.ImportReport
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			require.Equal(t, tt.expectedReport.DryRun, report.DryRun)
+			require.Equal(t, tt.expectedReport.Rows, report.Rows)
+			require.Equal(t, tt.expectedReport.Accepted, report.Accepted)
+			require.Equal(t, tt.expectedReport.Rejected, report.Rejected)
+			require.Len(t, report.Errors, report.Rejected)
+			if tt.expectedReport.Errors != nil {
+				require.Equal(t, tt.expectedReport.Errors, report.Errors)
+			}
+			if strings.Contains(tt.name, "csv") {
+				require.Equal(t, []int{3, 4, 5}, []int{report.Errors[0].Line, report.Errors[1].Line,
+					report.Errors[2].Line})
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+)
+
+// JWKS publishes the public keys the access tokens are verified with, so that other
+// services can verify them without sharing a secret. Caches are told to refetch the
+// keys every few minutes, so that keys added ahead of a rotation are picked up in time.
+func JWKS(cfg *middleware.JWTConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, cfg.KeySet().JWKS())
+	}
+}
@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"database/sql"
-	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
 )
 
 type LoginRequest struct {
@@ -15,39 +19,44 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-func LoginHandler(db *sql.DB, secret string) gin.HandlerFunc {
+// LoginHandler exchanges username and password for an access token and the first
+// refresh token of a new family.
+func LoginHandler(db *sql.DB, cfg *middleware.JWTConfig, tokens repository.Tokens) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req LoginRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			_ = c.Error(bindError(err))
 			return
 		}
 
-		var userID, passwordHash string
-		err := db.QueryRow(
-			"SELECT id, password_hash FROM users WHERE username = $1",
+		var userID uuid.UUID
+		var passwordHash string
+		var roles []string
+		err := db.QueryRowContext(c.Request.Context(),
+			"SELECT id, password_hash, roles FROM users WHERE username = $1",
 			req.Username,
-		).Scan(&userID, &passwordHash)
+		).Scan(&userID, &passwordHash, pq.Array(&roles))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			_ = c.Error(app.ErrInvalidCredentials)
 			return
 		}
 
 		if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)) != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			_ = c.Error(app.ErrInvalidCredentials)
 			return
 		}
 
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-			"sub": userID,
-			"exp": time.Now().Add(24 * time.Hour).Unix(),
-		})
-		tokenString, err := token.SignedString([]byte(secret))
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate token"})
+		refresh, stored := newRefreshToken(cfg)
+		stored.FamilyID, stored.UserID = uuid.New(), userID
+		if err := tokens.CreateRefreshToken(c.Request.Context(), stored); err != nil {
+			_ = c.Error(err)
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"token": tokenString})
+		userRoles := make([]models.Role, 0, len(roles))
+		for _, r := range roles {
+			userRoles = append(userRoles, models.Role(r))
+		}
+		respondTokens(c, cfg, userID.String(), userRoles, refresh)
 	}
 }
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dagherghinescu/companies/internal/http/openapi"
+)
+
+// OpenAPIDocument serves the OpenAPI document of the API.
+func OpenAPIDocument() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", openapi.Document())
+	}
+}
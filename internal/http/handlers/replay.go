@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// replayInput is the body accepted when starting a replay.
+type replayInput struct {
+	Type       *models.CompanyType `json:"type"`
+	CompanyIDs []uuid.UUID         `json:"company_ids"`
+}
+
+// StartReplay returns a handler that requests the re-publication of the company events.
+// The replay is published in the background; the response is the replay to follow it with.
+func StartReplay(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input replayInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			_ = c.Error(bindError(err))
+			return
+		}
+
+		r := &models.Replay{Type: input.Type, CompanyIDs: input.CompanyIDs}
+		if err := appl.StartReplay(c.Request.Context(), r); err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.Header("Location", "/replays/"+r.ID.String())
+		c.JSON(http.StatusAccepted, r)
+	}
+}
+
+// ListReplays returns a handler that lists the latest replays, newest first.
+func ListReplays(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := queryLimit(c)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		replays, err := appl.ListReplays(c.Request.Context(), limit)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"replays": replays})
+	}
+}
+
+// GetReplay returns a handler that returns a single replay and its progress.
+func GetReplay(appl *app.App) gin.HandlerFunc {
+	return replayAction(appl.GetReplay)
+}
+
+// PauseReplay returns a handler that stops a running replay after its current batch.
+func PauseReplay(appl *app.App) gin.HandlerFunc {
+	return replayAction(appl.PauseReplay)
+}
+
+// ResumeReplay returns a handler that continues a paused or failed replay where it stopped.
+func ResumeReplay(appl *app.App) gin.HandlerFunc {
+	return replayAction(appl.ResumeReplay)
+}
+
+// replayAction returns a handler that applies fn to the replay of the id path parameter
+// and responds with the replay fn returns.
+func replayAction(fn func(ctx context.Context, id uuid.UUID) (*models.Replay, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			_ = c.Error(errInvalidReplayID)
+			return
+		}
+
+		r, err := fn(c.Request.Context(), id)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, r)
+	}
+}
@@ -0,0 +1,165 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/http/handlers"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// mockReplayRepo keeps replays in memory.
+type mockReplayRepo struct {
+	replays map[uuid.UUID]models.Replay
+}
+
+func (m *mockReplayRepo) Create(_ context.Context, r *models.Replay) error {
+	m.replays[r.ID] = *r
+	return nil
+}
+func (m *mockReplayRepo) List(_ context.Context, _ uint64) ([]models.Replay, error) {
+	replays := make([]models.Replay, 0, len(m.replays))
+	for _, r := range m.replays {
+		replays = append(replays, r)
+	}
+	return replays, nil
+}
+func (m *mockReplayRepo) Get(_ context.Context, id uuid.UUID) (*models.Replay, error) {
+	r, ok := m.replays[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &r, nil
+}
+func (m *mockReplayRepo) SetStatus(
+	_ context.Context, id uuid.UUID, status models.ReplayStatus, from ...models.ReplayStatus,
+) error {
+	r, ok := m.replays[id]
+	if !ok || !slices.Contains(from, r.Status) {
+		return sql.ErrNoRows
+	}
+	r.Status, r.LastError = status, ""
+	m.replays[id] = r
+	return nil
+}
+
+func newReplayRouter(repo *mockReplayRepo) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Problems(zap.NewNop()))
+
+	appl := app.New(zap.NewNop(), &mockCompanyRepo{}, &app.Config{})
+	appl.Replays = repo
+
+	router.POST("/replays", handlers.StartReplay(appl))
+	router.GET("/replays", handlers.ListReplays(appl))
+	router.GET("/replays/:id", handlers.GetReplay(appl))
+	router.POST("/replays/:id/pause", handlers.PauseReplay(appl))
+	router.POST("/replays/:id/resume", handlers.ResumeReplay(appl))
+	return router
+}
+
+func TestStartReplayHandler(t *testing.T) {
+	tooMany := make([]string, app.MaxReplayCompanies+1)
+	for i := range tooMany {
+		tooMany[i] = `"` + uuid.NewString() + `"`
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{name: "every company", body: `{}`, expectedCode: http.StatusAccepted},
+		{
+			name:         "filtered",
+			body:         `{"type":"NonProfit","company_ids":["3f2c8d4e-6b1a-4f5e-9c7d-2a1b0e9f8d7c"]}`,
+			expectedCode: http.StatusAccepted,
+		},
+		{name: "unknown type", body: `{"type":"Guild"}`, expectedCode: http.StatusBadRequest},
+		{name: "invalid id", body: `{"company_ids":["42"]}`, expectedCode: http.StatusBadRequest},
+		{
+			name:         "too many ids",
+			body:         `{"company_ids":[` + strings.Join(tooMany, ",") + `]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockReplayRepo{replays: make(map[uuid.UUID]models.Replay)}
+			router := newReplayRouter(repo)
+
+			req, _ := http.NewRequest(http.MethodPost, "/replays", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if tt.expectedCode != http.StatusAccepted {
+				require.Empty(t, repo.replays)
+				return
+			}
+			require.Len(t, repo.replays, 1)
+			require.JSONEq(t, `"running"`, mustField(t, w.Body.Bytes(), "status"))
+			require.JSONEq(t, `"system"`, mustField(t, w.Body.Bytes(), "requested_by"))
+			require.Contains(t, w.Header().Get("Location"), "/replays/")
+		})
+	}
+}
+
+func TestReplayHandlers_Transitions(t *testing.T) {
+	running, paused, completed := uuid.New(), uuid.New(), uuid.New()
+	repo := &mockReplayRepo{replays: map[uuid.UUID]models.Replay{
+		running:   {ID: running, Status: models.ReplayRunning},
+		paused:    {ID: paused, Status: models.ReplayPaused},
+		completed: {ID: completed, Status: models.ReplayCompleted},
+	}}
+	router := newReplayRouter(repo)
+
+	tests := []struct {
+		name           string
+		target         string
+		expectedCode   int
+		expectedStatus string
+	}{
+		{name: "pause running", target: "/replays/" + running.String() + "/pause", expectedCode: http.StatusOK,
+			expectedStatus: `"paused"`},
+		{name: "pause paused", target: "/replays/" + paused.String() + "/pause", expectedCode: http.StatusOK,
+			expectedStatus: `"paused"`},
+		{name: "resume paused", target: "/replays/" + paused.String() + "/resume", expectedCode: http.StatusOK,
+			expectedStatus: `"running"`},
+		{name: "pause completed", target: "/replays/" + completed.String() + "/pause",
+			expectedCode: http.StatusConflict},
+		{name: "resume completed", target: "/replays/" + completed.String() + "/resume",
+			expectedCode: http.StatusConflict},
+		{name: "missing", target: "/replays/" + uuid.NewString() + "/resume", expectedCode: http.StatusNotFound},
+		{name: "invalid id", target: "/replays/42/pause", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, tt.target, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if tt.expectedStatus != "" {
+				require.JSONEq(t, tt.expectedStatus, mustField(t, w.Body.Bytes(), "status"))
+			}
+		})
+	}
+}
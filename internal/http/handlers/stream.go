@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/changefeed"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// actionReset tells stream clients that changes were missed, so the companies
+// they follow must be read again.
+const actionReset models.AuditAction = "reset"
+
+// wsWriteWait is how long a WebSocket client has to accept a message.
+const wsWriteWait = 10 * time.Second
+
+// changeEvent is a change as sent to stream clients.
+type changeEvent struct {
+	ID        string             `json:"id,omitempty"`
+	Action    models.AuditAction `json:"action"`
+	CompanyID string             `json:"company_id,omitempty"`
+	// Company is the company after the change, absent once it is deleted or purged.
+	Company *models.Company `json:"company,omitempty"`
+	Time    time.Time       `json:"time,omitzero"`
+}
+
+// StreamChanges returns a handler streaming the changes committed to companies
+// as Server-Sent Events, each holding a JSON changeEvent. Changes can be limited
+// to some companies with the company_id and type query parameters, both repeatable.
+// A client reconnecting with the Last-Event-ID header, or the last_event_id query
+// parameter, first receives the changes it missed; when they are no longer known
+// it receives a reset event instead. Idle streams are sent heartbeat comments.
+func StreamChanges(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, err := streamFilter(c)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		sub := appl.Changes.Subscribe(lastEventID(c))
+		defer sub.Cancel()
+
+		// The stream outlives the write timeout of the server.
+		_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		send := func(e *changeEvent) error {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %s\ndata: %s\n\n", e.ID, data); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+			return nil
+		}
+		heartbeat := func() error {
+			if _, err := c.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+			return nil
+		}
+
+		_ = streamChanges(c.Request.Context(), appl, sub, filter, send, heartbeat)
+	}
+}
+
+// StreamChangesWebSocket returns a handler streaming the same events as
+// StreamChanges over a WebSocket, one JSON text message per event, with the
+// same query parameters. Heartbeats are sent as pings; a client that does not
+// answer them in time is disconnected.
+func StreamChangesWebSocket(appl *app.App) gin.HandlerFunc {
+	// Clients authenticate with a bearer token rather than cookies, so a page of another
+	// origin cannot open the feed on behalf of a user: any origin may connect.
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+	return func(c *gin.Context) {
+		filter, err := streamFilter(c)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// Upgrade has replied with the error already.
+			return
+		}
+		defer conn.Close()
+
+		sub := appl.Changes.Subscribe(lastEventID(c))
+		defer sub.Cancel()
+
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		go readWebSocket(conn, 2*appl.Config.StreamHeartbeat, cancel)
+
+		send := func(e *changeEvent) error {
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			return conn.WriteJSON(e)
+		}
+		ping := func() error {
+			return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+		}
+
+		if err := streamChanges(ctx, appl, sub, filter, send, ping); err != nil || ctx.Err() != nil {
+			return
+		}
+
+		code, reason := websocket.CloseGoingAway, "server is shutting down"
+		if sub.Lagged() {
+			code, reason = websocket.CloseTryAgainLater, "client fell behind"
+		}
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason),
+			time.Now().Add(wsWriteWait))
+	}
+}
+
+// readWebSocket reads the messages of the client, which it must do to handle
+// pongs and closing, until the connection fails or no pong arrived for pongWait.
+// It calls done when it stops.
+func readWebSocket(conn *websocket.Conn, pongWait time.Duration, done func()) {
+	defer done()
+
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+// streamChanges sends the changes of sub that match filter: a reset event when
+// some were lost, the backlog, then the changes published while streaming.
+// heartbeat is called every StreamHeartbeat. It returns when ctx is done, the
+// subscription ends or sending fails.
+func streamChanges(
+	ctx context.Context, appl *app.App, sub *changefeed.Subscription, filter *changefeed.Filter,
+	send func(e *changeEvent) error, heartbeat func() error,
+) error {
+	if err := sendBacklog(appl, sub, filter, send); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(appl.Config.StreamHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := heartbeat(); err != nil {
+				return err
+			}
+		case change, ok := <-sub.Changes:
+			if !ok {
+				return nil
+			}
+			if err := sendChange(appl, filter, &change, send); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sendBacklog sends a reset event if changes were lost, then the missed changes that were kept.
+func sendBacklog(
+	appl *app.App, sub *changefeed.Subscription, filter *changefeed.Filter, send func(e *changeEvent) error,
+) error {
+	if sub.Gap {
+		if err := send(&changeEvent{ID: sub.Latest, Action: actionReset}); err != nil {
+			return err
+		}
+	}
+	for i := range sub.Backlog {
+		if err := sendChange(appl, filter, &sub.Backlog[i], send); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sendChange(appl *app.App, filter *changefeed.Filter, c *changefeed.Change, send func(e *changeEvent) error) error {
+	if !filter.Match(c) {
+		return nil
+	}
+	return send(&changeEvent{
+		ID:        appl.Changes.EventID(c),
+		Action:    c.Action,
+		CompanyID: c.CompanyID.String(),
+		Company:   c.After,
+		Time:      c.Time,
+	})
+}
+
+// streamFilter reads the company_id and type query parameters.
+func streamFilter(c *gin.Context) (*changefeed.Filter, error) {
+	f := &changefeed.Filter{}
+	for _, v := range c.QueryArray("company_id") {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, errInvalidCompanyID
+		}
+		f.CompanyIDs = append(f.CompanyIDs, id)
+	}
+	for _, v := range c.QueryArray("type") {
+		t := models.CompanyType(v)
+		if !t.Valid() {
+			return nil, app.Invalid(fmt.Sprintf("invalid type %q", v))
+		}
+		f.Types = append(f.Types, t)
+	}
+	return f, nil
+}
+
+// lastEventID returns the ID of the last event received by a reconnecting client.
+// Browsers cannot set headers on WebSockets, so it may also be a query parameter.
+func lastEventID(c *gin.Context) string {
+	if id := c.GetHeader("Last-Event-ID"); id != "" {
+		return id
+	}
+	return c.Query("last_event_id")
+}
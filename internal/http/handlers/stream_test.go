@@ -0,0 +1,182 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/changefeed"
+	"github.com/dagherghinescu/companies/internal/http/handlers"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+type streamEvent struct {
+	ID        string             `json:"id"`
+	Action    models.AuditAction `json:"action"`
+	CompanyID string             `json:"company_id"`
+	Company   *models.Company    `json:"company"`
+}
+
+func newStreamServer(t *testing.T, heartbeat time.Duration) (*httptest.Server, *app.App) {
+	t.Helper()
+
+	repo := &mockCompanyRepo{CreateFn: func(context.Context, *models.Company) error { return nil }}
+	appl := app.New(zap.NewNop(), repo, &app.Config{StreamHeartbeat: heartbeat})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Problems(zap.NewNop()))
+	router.GET("/companies/stream", handlers.StreamChanges(appl))
+	router.GET("/companies/stream/ws", handlers.StreamChangesWebSocket(appl))
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(func() {
+		appl.Changes.Close()
+		srv.Close()
+	})
+	return srv, appl
+}
+
+// openStream connects to the SSE endpoint and returns a function reading the next event,
+// skipping comments.
+func openStream(t *testing.T, url, lastEventID string) func() (id string, e streamEvent) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	r := bufio.NewReader(resp.Body)
+	return func() (string, streamEvent) {
+		var id string
+		var e streamEvent
+		for {
+			line, err := r.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "" && id != "":
+				return id, e
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e))
+			}
+		}
+	}
+}
+
+func company(name string, typ models.CompanyType) *models.Company {
+	return &models.Company{
+		ID:              uuid.New(),
+		Name:            ptrString(name),
+		AmountEmployees: ptrInt(10),
+		Registered:      ptrBool(true),
+		Type:            &typ,
+	}
+}
+
+func TestStreamChanges(t *testing.T) {
+	srv, appl := newStreamServer(t, time.Hour)
+	ctx := context.Background()
+
+	next := openStream(t, srv.URL+"/companies/stream?type=NonProfit", "")
+
+	require.NoError(t, appl.CreateCompany(ctx, company("Acme", models.Corporation)))
+	charity := company("Charity", models.NonProfit)
+	require.NoError(t, appl.CreateCompany(ctx, charity))
+
+	id, e := next()
+	require.Equal(t, id, e.ID)
+	require.Equal(t, models.ActionCreated, e.Action)
+	require.Equal(t, charity.ID.String(), e.CompanyID)
+	require.Equal(t, "Charity", *e.Company.Name)
+
+	// Resuming from the first change replays the second one only.
+	appl.Changes.Publish(changefeed.Change{Action: models.ActionDeleted, CompanyID: charity.ID, Before: charity})
+	deleted, e := next()
+	require.Equal(t, models.ActionDeleted, e.Action)
+	require.Nil(t, e.Company)
+
+	resumed := openStream(t, srv.URL+"/companies/stream", id)
+	resumedID, e := resumed()
+	require.Equal(t, deleted, resumedID)
+	require.Equal(t, models.ActionDeleted, e.Action)
+
+	// IDs of another process cannot be resumed from.
+	reset := openStream(t, srv.URL+"/companies/stream", "00000000-1")
+	latest, e := reset()
+	require.Equal(t, "reset", string(e.Action))
+
+	again := openStream(t, srv.URL+"/companies/stream", latest)
+	appl.Changes.Publish(changefeed.Change{Action: models.ActionRestored, CompanyID: charity.ID, After: charity})
+	_, e = again()
+	require.Equal(t, models.ActionRestored, e.Action, "resuming from a reset misses nothing")
+}
+
+func TestStreamChanges_Heartbeat(t *testing.T) {
+	srv, _ := newStreamServer(t, 10*time.Millisecond)
+
+	resp, err := http.Get(srv.URL + "/companies/stream")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, ": heartbeat\n", line)
+}
+
+func TestStreamChanges_InvalidFilter(t *testing.T) {
+	srv, _ := newStreamServer(t, time.Hour)
+
+	for _, query := range []string{"company_id=42", "type=Partnership"} {
+		resp, err := http.Get(srv.URL + "/companies/stream?" + query)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		require.Equal(t, middleware.ProblemContentType, resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestStreamChangesWebSocket(t *testing.T) {
+	srv, appl := newStreamServer(t, time.Hour)
+	acme := company("Acme", models.Corporation)
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/companies/stream/ws?company_id=" + acme.ID.String()
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	_ = resp.Body.Close()
+
+	require.NoError(t, appl.CreateCompany(context.Background(), company("Other", models.Corporation)))
+	require.NoError(t, appl.CreateCompany(context.Background(), acme))
+
+	var e streamEvent
+	require.NoError(t, conn.ReadJSON(&e))
+	require.Equal(t, acme.ID.String(), e.CompanyID)
+	require.NotEmpty(t, e.ID)
+
+	appl.Changes.Close()
+	_, _, err = conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/auth"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+// TokenResponse is the token pair issued at login and when refreshing.
+type TokenResponse struct {
+	// Token is the access token, valid for ExpiresIn seconds.
+	Token     string `json:"token"`
+	TokenType string `json:"token_type"`
+	ExpiresIn int64  `json:"expires_in"`
+	// RefreshToken can be exchanged once for a new pair.
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshToken exchanges a refresh token for a new access token and refresh token.
+// Presenting a refresh token that was already exchanged revokes its whole family,
+// since either the client or whoever stole the token holds its successor.
+func RefreshToken(cfg *middleware.JWTConfig, tokens repository.Tokens) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RefreshRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			_ = c.Error(bindError(err))
+			return
+		}
+
+		refresh, next := newRefreshToken(cfg)
+		used, err := tokens.RotateRefreshToken(c.Request.Context(), hashToken(req.RefreshToken), next)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			_ = c.Error(app.ErrInvalidRefreshToken)
+			return
+		case errors.Is(err, repository.ErrTokenReused):
+			_ = c.Error(app.ErrRefreshTokenReused)
+			return
+		case err != nil:
+			_ = c.Error(err)
+			return
+		}
+
+		respondTokens(c, cfg, used.UserID.String(), used.Roles, refresh)
+	}
+}
+
+// Logout revokes the access token of the request, so that it is rejected until it
+// expires, and the family of the refresh token in the body, if any.
+func Logout(tokens repository.Tokens) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req LogoutRequest
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			_ = c.Error(bindError(err))
+			return
+		}
+
+		principal, ok := auth.PrincipalFrom(c.Request.Context())
+		if !ok {
+			_ = c.Error(app.ErrUnauthenticated)
+			return
+		}
+
+		if err := revoke(c, tokens, principal, req.RefreshToken); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// revoke revokes the access token of principal and the family of refreshToken.
+func revoke(c *gin.Context, tokens repository.Tokens, principal *auth.Principal, refreshToken string) error {
+	if principal.TokenID != "" {
+		err := tokens.RevokeAccessToken(c.Request.Context(), principal.TokenID, principal.TokenExpiresAt)
+		if err != nil {
+			return err
+		}
+	}
+	if refreshToken == "" {
+		return nil
+	}
+
+	userID, err := uuid.Parse(principal.UserID)
+	if err != nil {
+		return app.ErrInvalidRefreshToken
+	}
+	return tokens.RevokeFamily(c.Request.Context(), userID, hashToken(refreshToken))
+}
+
+// respondTokens signs an access token for the user and sends it along with refresh.
+func respondTokens(c *gin.Context, cfg *middleware.JWTConfig, userID string, roles []models.Role, refresh string) {
+	access, err := signAccessToken(cfg, userID, roles)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, TokenResponse{
+		Token:        access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(cfg.AccessTokenTTL / time.Second),
+		RefreshToken: refresh,
+	})
+}
+
+// signAccessToken issues an access token for userID, identified by a random jti.
+func signAccessToken(cfg *middleware.JWTConfig, userID string, roles []models.Role) (string, error) {
+	now := time.Now()
+	claims := auth.Claims{
+		Roles: roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    cfg.Issuer,
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(cfg.AccessTokenTTL)),
+		},
+	}
+	if cfg.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{cfg.Audience}
+	}
+	return cfg.KeySet().Sign(claims)
+}
+
+// newRefreshToken generates a refresh token, returning it along with what is stored of it.
+func newRefreshToken(cfg *middleware.JWTConfig) (string, *repository.RefreshToken) {
+	token := rand.Text()
+	return token, &repository.RefreshToken{
+		ID:        uuid.New(),
+		Hash:      hashToken(token),
+		ExpiresAt: time.Now().Add(cfg.RefreshTokenTTL),
+	}
+}
+
+// hashToken returns the SHA-256 of a refresh token, as stored.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
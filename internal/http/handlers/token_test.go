@@ -0,0 +1,209 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/http/handlers"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+// storedToken is a refresh token kept by mockTokens.
+type storedToken struct {
+	repository.RefreshToken
+	used, revoked bool
+}
+
+// mockTokens keeps the refresh tokens by hash and the revoked jti in memory.
+type mockTokens struct {
+	refresh map[string]*storedToken
+	revoked map[string]time.Time
+}
+
+func (m *mockTokens) CreateRefreshToken(_ context.Context, t *repository.RefreshToken) error {
+	m.refresh[t.Hash] = &storedToken{RefreshToken: *t}
+	return nil
+}
+
+func (m *mockTokens) RotateRefreshToken(
+	_ context.Context, hash string, next *repository.RefreshToken,
+) (*repository.RefreshToken, error) {
+	t, ok := m.refresh[hash]
+	switch {
+	case !ok || t.revoked:
+		return nil, sql.ErrNoRows
+	case t.used:
+		m.revokeFamily(t.FamilyID)
+		return nil, repository.ErrTokenReused
+	}
+
+	t.used = true
+	next.FamilyID, next.UserID = t.FamilyID, t.UserID
+	m.refresh[next.Hash] = &storedToken{RefreshToken: *next}
+	used := t.RefreshToken
+	used.Roles = []models.Role{models.RoleEditor}
+	return &used, nil
+}
+
+func (m *mockTokens) RevokeFamily(_ context.Context, userID uuid.UUID, hash string) error {
+	if t, ok := m.refresh[hash]; ok && t.UserID == userID {
+		m.revokeFamily(t.FamilyID)
+	}
+	return nil
+}
+
+func (m *mockTokens) revokeFamily(family uuid.UUID) {
+	for _, t := range m.refresh {
+		if t.FamilyID == family {
+			t.revoked = true
+		}
+	}
+}
+
+func (m *mockTokens) RevokeAccessToken(_ context.Context, jti string, expiresAt time.Time) error {
+	m.revoked[jti] = expiresAt
+	return nil
+}
+
+func (m *mockTokens) IsRevoked(_ context.Context, jti string) (bool, error) {
+	_, ok := m.revoked[jti]
+	return ok, nil
+}
+
+// seedRefreshToken stores a refresh token of a new family, returning the token.
+func (m *mockTokens) seedRefreshToken(userID uuid.UUID) string {
+	token := uuid.NewString()
+	sum := sha256.Sum256([]byte(token))
+	m.refresh[hex.EncodeToString(sum[:])] = &storedToken{RefreshToken: repository.RefreshToken{
+		ID: uuid.New(), FamilyID: uuid.New(), UserID: userID, ExpiresAt: time.Now().Add(time.Hour),
+	}}
+	return token
+}
+
+func newTokenRouter(tokens *mockTokens) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Problems(zap.NewNop()))
+
+	cfg := &middleware.JWTConfig{
+		Secret: "secret", Issuer: "companies", Audience: "companies",
+		AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour, Denylist: tokens,
+	}
+	router.POST("/token/refresh", handlers.RefreshToken(cfg, tokens))
+	router.POST("/logout", middleware.JWTMiddleware(cfg), handlers.Logout(tokens))
+	router.GET("/me", middleware.JWTMiddleware(cfg), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func postJSON(router *gin.Engine, target, token, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, target, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func getMe(router *gin.Engine, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func refresh(
+	t *testing.T, router *gin.Engine, refreshToken string,
+) (*httptest.ResponseRecorder, handlers.TokenResponse) {
+	t.Helper()
+
+	w := postJSON(router, "/token/refresh", "", `{"refresh_token":"`+refreshToken+`"}`)
+	var resp handlers.TokenResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w, resp
+}
+
+func TestRefreshTokenHandler_Rotation(t *testing.T) {
+	tokens := &mockTokens{refresh: make(map[string]*storedToken), revoked: make(map[string]time.Time)}
+	router := newTokenRouter(tokens)
+	first := tokens.seedRefreshToken(uuid.New())
+
+	w, second := refresh(t, router, first)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NotEmpty(t, second.Token)
+	require.Equal(t, "Bearer", second.TokenType)
+	require.EqualValues(t, 60, second.ExpiresIn)
+	require.NotEqual(t, first, second.RefreshToken)
+
+	w, third := refresh(t, router, second.RefreshToken)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Replaying a used token revokes the family, including the latest token.
+	w, _ = refresh(t, router, first)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Contains(t, w.Body.String(), "already used")
+
+	w, _ = refresh(t, router, third.RefreshToken)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w, _ = refresh(t, router, "unknown")
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLogoutHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		withRefresh bool
+	}{
+		{name: "access token only"},
+		{name: "with refresh token", withRefresh: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := &mockTokens{refresh: make(map[string]*storedToken), revoked: make(map[string]time.Time)}
+			router := newTokenRouter(tokens)
+
+			w, pair := refresh(t, router, tokens.seedRefreshToken(uuid.New()))
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			require.Equal(t, http.StatusOK, getMe(router, pair.Token).Code)
+
+			var body string
+			if tt.withRefresh {
+				body = `{"refresh_token":"` + pair.RefreshToken + `"}`
+			}
+			w = postJSON(router, "/logout", pair.Token, body)
+			require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+			require.Len(t, tokens.revoked, 1)
+
+			// The access token is rejected from now on.
+			require.Equal(t, http.StatusUnauthorized, getMe(router, pair.Token).Code)
+
+			w, _ = refresh(t, router, pair.RefreshToken)
+			if tt.withRefresh {
+				require.Equal(t, http.StatusUnauthorized, w.Code)
+			} else {
+				require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			}
+		})
+	}
+}
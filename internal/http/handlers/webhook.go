@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// webhookInput is the body accepted when creating a webhook.
+type webhookInput struct {
+	URL    string               `json:"url"`
+	Events []models.AuditAction `json:"events"`
+}
+
+// CreateWebhook returns a handler that subscribes a URL to the company events.
+// The response holds the secret the deliveries are signed with; it cannot be read again.
+func CreateWebhook(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input webhookInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			_ = c.Error(bindError(err))
+			return
+		}
+
+		w := &models.Webhook{URL: input.URL, Events: input.Events}
+		if err := appl.CreateWebhook(c.Request.Context(), w); err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, w)
+	}
+}
+
+// ListWebhooks returns a handler that lists every webhook, oldest first.
+func ListWebhooks(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhooks, err := appl.ListWebhooks(c.Request.Context())
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"webhooks": webhooks})
+	}
+}
+
+// GetWebhook returns a handler that returns a single webhook.
+func GetWebhook(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			_ = c.Error(errInvalidWebhookID)
+			return
+		}
+
+		w, err := appl.GetWebhook(c.Request.Context(), id)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, w)
+	}
+}
+
+// DeleteWebhook returns a handler that unsubscribes a webhook and drops its delivery log.
+func DeleteWebhook(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			_ = c.Error(errInvalidWebhookID)
+			return
+		}
+
+		if err := appl.DeleteWebhook(c.Request.Context(), id); err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// EnableWebhook returns a handler that resumes the deliveries to a disabled webhook.
+func EnableWebhook(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			_ = c.Error(errInvalidWebhookID)
+			return
+		}
+
+		w, err := appl.EnableWebhook(c.Request.Context(), id)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, w)
+	}
+}
+
+// WebhookDeliveries returns a handler that lists the deliveries of a webhook, newest first.
+// Results are paginated with limit and the cursor returned by the previous page.
+func WebhookDeliveries(appl *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			_ = c.Error(errInvalidWebhookID)
+			return
+		}
+
+		limit, err := queryLimit(c)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		page, err := appl.WebhookDeliveries(c.Request.Context(), id, limit, c.Query("cursor"))
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, page)
+	}
+}
@@ -0,0 +1,238 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/http/handlers"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+// mockWebhookRepo keeps webhooks in memory.
+type mockWebhookRepo struct {
+	webhooks map[uuid.UUID]models.Webhook
+}
+
+func (m *mockWebhookRepo) Create(_ context.Context, w *models.Webhook) error {
+	m.webhooks[w.ID] = *w
+	return nil
+}
+func (m *mockWebhookRepo) List(_ context.Context) ([]models.Webhook, error) {
+	webhooks := make([]models.Webhook, 0, len(m.webhooks))
+	for _, w := range m.webhooks {
+		w.Secret = ""
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, nil
+}
+func (m *mockWebhookRepo) Get(_ context.Context, id uuid.UUID) (*models.Webhook, error) {
+	w, ok := m.webhooks[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	w.Secret = ""
+	return &w, nil
+}
+func (m *mockWebhookRepo) Delete(_ context.Context, id uuid.UUID) error {
+	if _, ok := m.webhooks[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.webhooks, id)
+	return nil
+}
+func (m *mockWebhookRepo) Enable(_ context.Context, id uuid.UUID) error {
+	w, ok := m.webhooks[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	w.DisabledAt, w.ConsecutiveFailures = nil, 0
+	m.webhooks[id] = w
+	return nil
+}
+func (m *mockWebhookRepo) Deliveries(
+	_ context.Context, _ uuid.UUID, _ uint64, cursor string,
+) (*repository.DeliveryPage, error) {
+	if cursor != "" {
+		return nil, repository.ErrInvalidCursor
+	}
+	return &repository.DeliveryPage{Deliveries: []models.WebhookDelivery{}}, nil
+}
+
+func newWebhookRouter(repo *mockWebhookRepo) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Problems(zap.NewNop()))
+
+	appl := app.New(zap.NewNop(), &mockCompanyRepo{}, &app.Config{})
+	appl.Webhooks = repo
+
+	router.POST("/webhooks", handlers.CreateWebhook(appl))
+	router.GET("/webhooks/:id", handlers.GetWebhook(appl))
+	router.DELETE("/webhooks/:id", handlers.DeleteWebhook(appl))
+	router.POST("/webhooks/:id/enable", handlers.EnableWebhook(appl))
+	router.GET("/webhooks/:id/deliveries", handlers.WebhookDeliveries(appl))
+	return router
+}
+
+func TestCreateWebhookHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedCode   int
+		expectedFields []string
+	}{
+		{
+			name:         "all events",
+			body:         `{"url":"https://partner.example.com/hooks"}`,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "some events",
+			body:         `{"url":"http://partner.example.com/hooks","events":["created","deleted"]}`,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:           "missing url",
+			body:           `{}`,
+			expectedCode:   http.StatusBadRequest,
+			expectedFields: []string{"url"},
+		},
+		{
+			name:           "invalid url and event",
+			body:           `{"url":"ftp://partner.example.com","events":["created","renamed"]}`,
+			expectedCode:   http.StatusBadRequest,
+			expectedFields: []string{"url", "events"},
+		},
+		{
+			name:           "relative url",
+			body:           `{"url":"/hooks"}`,
+			expectedCode:   http.StatusBadRequest,
+			expectedFields: []string{"url"},
+		},
+		{
+			name:           "cloud metadata endpoint",
+			body:           `{"url":"http://169.254.169.254/latest/meta-data/"}`,
+			expectedCode:   http.StatusBadRequest,
+			expectedFields: []string{"url"},
+		},
+		{
+			name:           "loopback",
+			body:           `{"url":"http://localhost:8080/hooks"}`,
+			expectedCode:   http.StatusBadRequest,
+			expectedFields: []string{"url"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockWebhookRepo{webhooks: make(map[uuid.UUID]models.Webhook)}
+			router := newWebhookRouter(repo)
+
+			req, _ := http.NewRequest(http.MethodPost, "/webhooks", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if tt.expectedCode != http.StatusCreated {
+				var problem struct {
+					Errors []models.FieldError `json:"errors"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+				var fields []string
+				for _, f := range problem.Errors {
+					fields = append(fields, f.Field)
+				}
+				require.Equal(t, tt.expectedFields, fields)
+				require.Empty(t, repo.webhooks)
+				return
+			}
+
+			var created models.Webhook
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+			require.Len(t, created.Secret, 64)
+			require.NotNil(t, created.Events)
+			require.Equal(t, repo.webhooks[created.ID].Secret, created.Secret)
+		})
+	}
+}
+
+func TestWebhookHandlers_NotFound(t *testing.T) {
+	repo := &mockWebhookRepo{webhooks: make(map[uuid.UUID]models.Webhook)}
+	router := newWebhookRouter(repo)
+	missing := "/webhooks/" + uuid.NewString()
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		expectedCode int
+	}{
+		{name: "get", method: http.MethodGet, target: missing, expectedCode: http.StatusNotFound},
+		{name: "delete", method: http.MethodDelete, target: missing, expectedCode: http.StatusNotFound},
+		{name: "enable", method: http.MethodPost, target: missing + "/enable", expectedCode: http.StatusNotFound},
+		{
+			name: "deliveries", method: http.MethodGet, target: missing + "/deliveries",
+			expectedCode: http.StatusNotFound,
+		},
+		{name: "invalid id", method: http.MethodGet, target: "/webhooks/42", expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.target, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestWebhookHandlers(t *testing.T) {
+	id := uuid.New()
+	repo := &mockWebhookRepo{webhooks: map[uuid.UUID]models.Webhook{
+		id: {ID: id, URL: "https://partner.example.com", Secret: "s", ConsecutiveFailures: 20},
+	}}
+	router := newWebhookRouter(repo)
+
+	req, _ := http.NewRequest(http.MethodPost, "/webhooks/"+id.String()+"/enable", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `"https://partner.example.com"`, mustField(t, w.Body.Bytes(), "url"))
+	require.JSONEq(t, `0`, mustField(t, w.Body.Bytes(), "consecutive_failures"))
+	require.NotContains(t, w.Body.String(), "secret")
+
+	req, _ = http.NewRequest(http.MethodGet, "/webhooks/"+id.String()+"/deliveries?cursor=bad", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest(http.MethodDelete, "/webhooks/"+id.String(), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Empty(t, repo.webhooks)
+}
+
+// mustField returns the raw JSON of a top level field of body.
+func mustField(t *testing.T, body []byte, name string) string {
+	t.Helper()
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &fields))
+	return string(fields[name])
+}
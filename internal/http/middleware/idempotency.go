@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header holding the client chosen idempotency key.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from a previous request.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize bounds the request bodies buffered to compute fingerprints.
+	maxIdempotentBodySize = 10 << 20
+	// maxStoredResponseSize bounds the response bodies stored for replay. The key of a
+	// larger response is released instead, so a retry runs the request again.
+	maxStoredResponseSize = 10 << 20
+)
+
+var (
+	errKeyTooLong    = app.Invalid("Idempotency-Key is too long")
+	errBodyTooLarge  = app.NewError(app.KindTooLarge, "request body is too large")
+	errKeyInProgress = app.NewError(app.KindConflict, "a request with this Idempotency-Key is in progress")
+	errKeyReused     = app.NewError(app.KindUnprocessable, "Idempotency-Key was already used for a different request")
+)
+
+// Idempotency makes requests sent with an Idempotency-Key header safe to retry.
+// The first request with a key runs normally and its response is stored; retries
+// with the same key and the same method, URL and body get the stored response
+// back, while reusing the key for a different request is rejected with 422.
+// Server errors, responses over maxStoredResponseSize and requests whose handler
+// panicked are not stored, so such requests can be retried. Keys are scoped to
+// the principal, hence the middleware must run after JWTMiddleware.
+func Idempotency(store repository.Idempotency, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			abortWithError(c, errKeyTooLong)
+			return
+		}
+
+		fingerprint, err := requestFingerprint(c)
+		if err != nil {
+			abortWithError(c, errBodyTooLarge)
+			return
+		}
+
+		scope := ""
+		if p, ok := PrincipalFromContext(c); ok {
+			scope = p.UserID
+		}
+
+		ctx := c.Request.Context()
+		rec, err := store.Claim(ctx, scope, key, fingerprint, time.Now().Add(-ttl))
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			abortWithError(c, errKeyInProgress)
+			return
+		case err != nil:
+			abortWithError(c, err)
+			return
+		case rec != nil:
+			replay(c, rec, fingerprint)
+			return
+		}
+
+		// The outcome must be recorded even if the client went away meanwhile.
+		ctx = context.WithoutCancel(ctx)
+		completed := false
+		defer func() {
+			if !completed {
+				// The handler panicked: free the key rather than leave it in progress.
+				_ = store.Release(ctx, scope, key)
+			}
+		}()
+
+		w := &recordingWriter{ResponseWriter: c.Writer, max: maxStoredResponseSize}
+		c.Writer = w
+		c.Next()
+		// Render errors now, so that the problem is part of the stored response.
+		writeProblem(c)
+		completed = true
+
+		if err := record(ctx, store, scope, key, w); err != nil {
+			_ = c.Error(err)
+		}
+	}
+}
+
+// record stores the response recorded by w for key, or releases the key when the
+// response is a server error or too large to be stored.
+func record(ctx context.Context, store repository.Idempotency, scope, key string, w *recordingWriter) error {
+	if w.Status() >= http.StatusInternalServerError || w.truncated {
+		return store.Release(ctx, scope, key)
+	}
+	return store.Complete(ctx, scope, key, &repository.StoredResponse{
+		Status:      w.Status(),
+		ContentType: w.Header().Get("Content-Type"),
+		ETag:        w.Header().Get("ETag"),
+		Body:        w.body.Bytes(),
+	})
+}
+
+// requestFingerprint hashes the method, URL and body of the request,
+// leaving the body readable for the handlers.
+func requestFingerprint(c *gin.Context) (string, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodySize))
+	if err != nil {
+		return "", err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method + " " + c.Request.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// replay answers with the stored response of rec.
+func replay(c *gin.Context, rec *repository.IdempotencyRecord, fingerprint string) {
+	switch {
+	case rec.Fingerprint != fingerprint:
+		abortWithError(c, errKeyReused)
+		return
+	case rec.Response == nil:
+		abortWithError(c, errKeyInProgress)
+		return
+	}
+
+	resp := rec.Response
+	if resp.ContentType != "" {
+		c.Header("Content-Type", resp.ContentType)
+	}
+	if resp.ETag != "" {
+		c.Header("ETag", resp.ETag)
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Status(resp.Status)
+	c.Writer.WriteHeaderNow()
+	_, _ = c.Writer.Write(resp.Body)
+	c.Abort()
+}
+
+// recordingWriter keeps a copy of the response body, up to max bytes if max
+// is positive. Bodies over the limit are not kept and mark the copy truncated.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.record(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *recordingWriter) record(b []byte) {
+	if w.truncated {
+		return
+	}
+	if w.max > 0 && w.body.Len()+len(b) > w.max {
+		w.truncated = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(b)
+}
@@ -0,0 +1,251 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+// memoryStore is an in-memory repository.Idempotency ignoring expiry.
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]*repository.IdempotencyRecord
+}
+
+func (s *memoryStore) Claim(
+	_ context.Context, scope, key, fingerprint string, _ time.Time,
+) (*repository.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, ok := s.records[scope+"/"+key]; ok {
+		return rec, nil
+	}
+	s.records[scope+"/"+key] = &repository.IdempotencyRecord{Fingerprint: fingerprint}
+	return nil, nil
+}
+
+func (s *memoryStore) Complete(_ context.Context, scope, key string, resp *repository.StoredResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[scope+"/"+key].Response = resp
+	return nil
+}
+
+func (s *memoryStore) Release(_ context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, scope+"/"+key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	type request struct {
+		key          string
+		body         string
+		expectedCode int
+		expectedBody string
+		replayed     bool
+	}
+
+	tests := []struct {
+		name     string
+		status   int
+		requests []request
+		calls    int
+	}{
+		{
+			name:   "no key runs every request",
+			status: http.StatusCreated,
+			requests: []request{
+				{body: `{"name":"a"}`, expectedCode: http.StatusCreated, expectedBody: `{"call":1}`},
+				{body: `{"name":"a"}`, expectedCode: http.StatusCreated, expectedBody: `{"call":2}`},
+			},
+			calls: 2,
+		},
+		{
+			name:   "retry replays the first response",
+			status: http.StatusCreated,
+			requests: []request{
+				{key: "k1", body: `{"name":"a"}`, expectedCode: http.StatusCreated, expectedBody: `{"call":1}`},
+				{key: "k1", body: `{"name":"a"}`, expectedCode: http.StatusCreated, expectedBody: `{"call":1}`,
+					replayed: true},
+			},
+			calls: 1,
+		},
+		{
+			name:   "different body with the same key",
+			status: http.StatusCreated,
+			requests: []request{
+				{key: "k1", body: `{"name":"a"}`, expectedCode: http.StatusCreated, expectedBody: `{"call":1}`},
+				{key: "k1", body: `{"name":"b"}`, expectedCode: http.StatusUnprocessableEntity},
+			},
+			calls: 1,
+		},
+		{
+			name:   "different keys",
+			status: http.StatusCreated,
+			requests: []request{
+				{key: "k1", body: `{"name":"a"}`, expectedCode: http.StatusCreated, expectedBody: `{"call":1}`},
+				{key: "k2", body: `{"name":"a"}`, expectedCode: http.StatusCreated, expectedBody: `{"call":2}`},
+			},
+			calls: 2,
+		},
+		{
+			name:   "client errors are replayed",
+			status: http.StatusConflict,
+			requests: []request{
+				{key: "k1", body: `{"name":"a"}`, expectedCode: http.StatusConflict, expectedBody: `{"call":1}`},
+				{key: "k1", body: `{"name":"a"}`, expectedCode: http.StatusConflict, expectedBody: `{"call":1}`,
+					replayed: true},
+			},
+			calls: 1,
+		},
+		{
+			name:   "server errors can be retried",
+			status: http.StatusInternalServerError,
+			requests: []request{
+				{key: "k1", body: `{"name":"a"}`, expectedCode: http.StatusInternalServerError,
+					expectedBody: `{"call":1}`},
+				{key: "k1", body: `{"name":"a"}`, expectedCode: http.StatusInternalServerError,
+					expectedBody: `{"call":2}`},
+			},
+			calls: 2,
+		},
+		{
+			name:   "key too long",
+			status: http.StatusCreated,
+			requests: []request{
+				{key: strings.Repeat("k", 256), body: `{"name":"a"}`, expectedCode: http.StatusBadRequest},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.Problems(zap.NewNop()))
+
+			store := &memoryStore{records: map[string]*repository.IdempotencyRecord{}}
+			calls := 0
+			router.POST("/", middleware.Idempotency(store, time.Hour), func(c *gin.Context) {
+				calls++
+				c.Header("ETag", `"1"`)
+				c.JSON(tt.status, gin.H{"call": calls})
+			})
+
+			for _, r := range tt.requests {
+				req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(r.body))
+				if r.key != "" {
+					req.Header.Set(middleware.IdempotencyKeyHeader, r.key)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				require.Equal(t, r.expectedCode, w.Code)
+				if r.expectedBody != "" {
+					require.JSONEq(t, r.expectedBody, w.Body.String())
+					require.Equal(t, `"1"`, w.Header().Get("ETag"))
+				}
+				if r.replayed {
+					require.Equal(t, "true", w.Header().Get(middleware.IdempotentReplayedHeader))
+				} else {
+					require.Empty(t, w.Header().Get(middleware.IdempotentReplayedHeader))
+				}
+			}
+			require.Equal(t, tt.calls, calls)
+		})
+	}
+}
+
+func TestIdempotency_InProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Problems(zap.NewNop()))
+
+	store := &memoryStore{records: map[string]*repository.IdempotencyRecord{}}
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	router.POST("/", middleware.Idempotency(store, time.Hour), func(c *gin.Context) {
+		close(entered)
+		<-release
+		c.Status(http.StatusNoContent)
+	})
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+		req.Header.Set(middleware.IdempotencyKeyHeader, "k1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := make(chan int)
+	go func() { first <- send().Code }()
+	<-entered
+
+	require.Equal(t, http.StatusConflict, send().Code)
+
+	close(release)
+	require.Equal(t, http.StatusNoContent, <-first)
+	require.Equal(t, http.StatusNoContent, send().Code)
+}
+
+func TestIdempotency_ReleasesKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(c *gin.Context)
+	}{
+		{
+			name:    "handler panics",
+			handler: func(*gin.Context) { panic("boom") },
+		},
+		{
+			name: "response too large to store",
+			handler: func(c *gin.Context) {
+				c.String(http.StatusOK, strings.Repeat("x", 10<<20+1))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(gin.Recovery(), middleware.Problems(zap.NewNop()))
+
+			store := &memoryStore{records: map[string]*repository.IdempotencyRecord{}}
+			calls := 0
+			router.POST("/", middleware.Idempotency(store, time.Hour), func(c *gin.Context) {
+				calls++
+				tt.handler(c)
+			})
+
+			for range 2 {
+				req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+				req.Header.Set(middleware.IdempotencyKeyHeader, "k1")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				require.Empty(t, w.Header().Get(middleware.IdempotentReplayedHeader))
+			}
+
+			// The key was freed after the first request, so the retry ran the handler again.
+			require.Equal(t, 2, calls)
+			require.Empty(t, store.records)
+		})
+	}
+}
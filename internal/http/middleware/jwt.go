@@ -1,18 +1,55 @@
 package middleware
 
 import (
-	"net/http"
+	"context"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/kelseyhightower/envconfig"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/auth"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// principalKey is the gin context key holding the authenticated *auth.Principal.
+const principalKey = "principal"
+
+var (
+	errMissingAuthHeader = app.NewError(app.KindUnauthorized, "missing authorization header")
+	errInvalidAuthHeader = app.NewError(app.KindUnauthorized, "invalid authorization header")
+	errInvalidToken      = app.NewError(app.KindUnauthorized, "invalid token")
+	errRevokedToken      = app.NewError(app.KindUnauthorized, "token has been revoked")
+	errNoSigningKey      = errors.New("JWT_SIGNING_KEY_FILE or JWT_SECRET is required")
 )
 
+// Denylist reports whether an access token was revoked, by its jti claim.
+type Denylist interface {
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
 // JWTConfig holds the configuration needed for the jwt auth implementation.
-// Currently it's holding only a secret.
 type JWTConfig struct {
-	Secret string `envconfig:"SECRET" required:"true"`
+	// SigningKeyFile is the PEM private key, RSA or Ed25519, the tokens are signed with.
+	// VerificationKeyFiles are PEM public keys also accepted, during key rotations.
+	SigningKeyFile       string   `envconfig:"SIGNING_KEY_FILE"`
+	VerificationKeyFiles []string `envconfig:"VERIFICATION_KEY_FILES"`
+	// Secret signs and verifies HS256 tokens when no SigningKeyFile is set.
+	Secret string `envconfig:"SECRET"`
+	// Keys are loaded from the key files by EnvConfig. When nil, Secret is used.
+	Keys *auth.KeySet `ignored:"true"`
+	// Issuer and Audience are set in the iss and aud claims, and required from tokens when set.
+	Issuer   string `envconfig:"ISSUER" default:"companies"`
+	Audience string `envconfig:"AUDIENCE" default:"companies"`
+	// AccessTokenTTL is how long the access tokens are valid, RefreshTokenTTL how long
+	// a refresh token can be exchanged for a new pair.
+	AccessTokenTTL  time.Duration `envconfig:"ACCESS_TOKEN_TTL" default:"15m"`
+	RefreshTokenTTL time.Duration `envconfig:"REFRESH_TOKEN_TTL" default:"720h"`
+	// Denylist, when set, rejects the revoked access tokens and the tokens without a jti.
+	Denylist Denylist `ignored:"true"`
 }
 
 // EnvConfig loads config from environment variables into HTTPConfig.
@@ -21,31 +58,151 @@ func EnvConfig() (*JWTConfig, error) {
 	if err := envconfig.Process("JWT", &cfg); err != nil {
 		return nil, err
 	}
+
+	switch {
+	case cfg.SigningKeyFile != "":
+		keys, err := auth.LoadKeySet(cfg.SigningKeyFile, cfg.VerificationKeyFiles)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Keys = keys
+	case cfg.Secret == "":
+		return nil, errNoSigningKey
+	}
 	return &cfg, nil
 }
 
+// KeySet returns the keys tokens are signed and verified with.
+func (cfg *JWTConfig) KeySet() *auth.KeySet {
+	if cfg.Keys != nil {
+		return cfg.Keys
+	}
+	return auth.NewHMACKeySet(cfg.Secret)
+}
+
+// parserOptions are the claims validation options: tokens must expire, and be issued
+// by Issuer for Audience.
+func (cfg *JWTConfig) parserOptions() []jwt.ParserOption {
+	opts := []jwt.ParserOption{jwt.WithExpirationRequired()}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	return opts
+}
+
+// JWTMiddleware authenticates the request with the bearer token and stores the
+// resulting principal on both the gin context and the request context.
 func JWTMiddleware(cfg *JWTConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing authorization header"})
+		principal, err := Authenticate(c.Request.Context(), cfg, c.GetHeader("Authorization"))
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		setPrincipal(c, principal)
+		c.Next()
+	}
+}
+
+// QueryRequiresRole authenticates the requests carrying the query parameter param and
+// only lets them through if they hold role, like JWTMiddleware followed by RequireRole.
+// Requests without the parameter are let through as they are.
+func QueryRequiresRole(cfg *JWTConfig, param string, role models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.GetQuery(param); !ok {
+			c.Next()
 			return
 		}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header"})
+		principal, err := Authenticate(c.Request.Context(), cfg, c.GetHeader("Authorization"))
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+		if !principal.HasRole(role) {
+			abortWithError(c, app.ErrForbidden)
 			return
 		}
 
-		_, err := jwt.Parse(tokenString, func(_ *jwt.Token) (any, error) {
-			return []byte(cfg.Secret), nil
-		})
+		setPrincipal(c, principal)
+		c.Next()
+	}
+}
+
+// setPrincipal stores principal on both the gin context and the request context.
+func setPrincipal(c *gin.Context, principal *auth.Principal) {
+	c.Set(principalKey, principal)
+	c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+}
+
+// Authenticate returns the principal of the bearer token in authHeader, the value
+// of an Authorization header. The errors returned are KindUnauthorized app errors,
+// unless the denylist cannot be checked.
+func Authenticate(ctx context.Context, cfg *JWTConfig, authHeader string) (*auth.Principal, error) {
+	if authHeader == "" {
+		return nil, errMissingAuthHeader
+	}
+
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	if tokenString == authHeader {
+		return nil, errInvalidAuthHeader
+	}
+
+	var claims auth.Claims
+	err := cfg.KeySet().Parse(tokenString, &claims, cfg.parserOptions()...)
+	if err != nil || claims.Subject == "" {
+		return nil, errInvalidToken
+	}
+
+	if cfg.Denylist != nil {
+		if claims.ID == "" {
+			return nil, errInvalidToken
+		}
+		revoked, err := cfg.Denylist.IsRevoked(ctx, claims.ID)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return nil, err
+		}
+		if revoked {
+			return nil, errRevokedToken
+		}
+	}
+
+	principal := &auth.Principal{UserID: claims.Subject, Roles: claims.Roles, TokenID: claims.ID}
+	if claims.ExpiresAt != nil {
+		principal.TokenExpiresAt = claims.ExpiresAt.Time
+	}
+	return principal, nil
+}
+
+// RequireRole only lets through principals holding role or a role that includes it.
+// It must run after JWTMiddleware.
+func RequireRole(role models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFromContext(c)
+		if !ok {
+			abortWithError(c, app.ErrUnauthenticated)
+			return
+		}
+
+		if !principal.HasRole(role) {
+			abortWithError(c, app.ErrForbidden)
 			return
 		}
 
 		c.Next()
 	}
 }
+
+// PrincipalFromContext returns the principal authenticated by JWTMiddleware.
+func PrincipalFromContext(c *gin.Context) (*auth.Principal, bool) {
+	v, ok := c.Get(principalKey)
+	if !ok {
+		return nil, false
+	}
+	p, ok := v.(*auth.Principal)
+	return p, ok
+}
@@ -0,0 +1,242 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/auth"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+const testSecret = "test-secret"
+
+func signToken(t *testing.T, secret, sub string, roles ...models.Role) string {
+	t.Helper()
+	return signTokenID(t, secret, "", sub, roles...)
+}
+
+func signTokenID(t *testing.T, secret, jti, sub string, roles ...models.Role) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		Roles: roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   sub,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	s, err := token.SignedString([]byte(secret))
+	require.NoError(t, err)
+	return s
+}
+
+func TestJWTMiddleware_RequireRole(t *testing.T) {
+	tests := []struct {
+		name         string
+		header       string
+		expectedCode int
+	}{
+		{
+			name:         "missing header",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "wrong signature",
+			header:       "Bearer " + signToken(t, "other-secret", "user-1", models.RoleAdmin),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "role too low",
+			header:       "Bearer " + signToken(t, testSecret, "user-1", models.RoleViewer),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "exact role",
+			header:       "Bearer " + signToken(t, testSecret, "user-1", models.RoleEditor),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "higher role",
+			header:       "Bearer " + signToken(t, testSecret, "user-1", models.RoleAdmin),
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.Problems(zap.NewNop()))
+
+			cfg := &middleware.JWTConfig{Secret: testSecret}
+			router.POST("/", middleware.JWTMiddleware(cfg), middleware.RequireRole(models.RoleEditor),
+				func(c *gin.Context) {
+					p, ok := auth.PrincipalFrom(c.Request.Context())
+					require.True(t, ok)
+					require.Equal(t, "user-1", p.UserID)
+					c.Status(http.StatusOK)
+				})
+
+			req, _ := http.NewRequest(http.MethodPost, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+// denylist holds the revoked jti.
+type denylist map[string]bool
+
+func (d denylist) IsRevoked(_ context.Context, jti string) (bool, error) {
+	return d[jti], nil
+}
+
+func TestJWTMiddleware_Denylist(t *testing.T) {
+	tests := []struct {
+		name         string
+		jti          string
+		expectedCode int
+	}{
+		{name: "valid", jti: "token-1", expectedCode: http.StatusOK},
+		{name: "revoked", jti: "token-2", expectedCode: http.StatusUnauthorized},
+		{name: "no jti", expectedCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.Problems(zap.NewNop()))
+
+			cfg := &middleware.JWTConfig{Secret: testSecret, Denylist: denylist{"token-2": true}}
+			router.GET("/", middleware.JWTMiddleware(cfg), func(c *gin.Context) {
+				p, ok := middleware.PrincipalFromContext(c)
+				require.True(t, ok)
+				require.Equal(t, tt.jti, p.TokenID)
+				require.False(t, p.TokenExpiresAt.IsZero())
+				c.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+signTokenID(t, testSecret, tt.jti, "user-1"))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestAuthenticate_Claims(t *testing.T) {
+	cfg := &middleware.JWTConfig{Secret: testSecret, Issuer: "companies", Audience: "companies"}
+	valid := func() jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{
+			Issuer:    "companies",
+			Audience:  jwt.ClaimStrings{"companies"},
+			Subject:   "user-1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		claims      func(c *jwt.RegisteredClaims)
+		method      jwt.SigningMethod
+		expectedErr bool
+	}{
+		{name: "valid", claims: func(*jwt.RegisteredClaims) {}},
+		{name: "other issuer", claims: func(c *jwt.RegisteredClaims) { c.Issuer = "other" }, expectedErr: true},
+		{name: "other audience", claims: func(c *jwt.RegisteredClaims) { c.Audience = nil }, expectedErr: true},
+		{name: "no expiry", claims: func(c *jwt.RegisteredClaims) { c.ExpiresAt = nil }, expectedErr: true},
+		{
+			name:        "expired",
+			claims:      func(c *jwt.RegisteredClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour)) },
+			expectedErr: true,
+		},
+		{name: "other algorithm", claims: func(*jwt.RegisteredClaims) {}, method: jwt.SigningMethodHS512,
+			expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registered := valid()
+			tt.claims(&registered)
+			method := tt.method
+			if method == nil {
+				method = jwt.SigningMethodHS256
+			}
+			token, err := jwt.NewWithClaims(method, auth.Claims{RegisteredClaims: registered}).
+				SignedString([]byte(testSecret))
+			require.NoError(t, err)
+
+			principal, err := middleware.Authenticate(context.Background(), cfg, "Bearer "+token)
+			if tt.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "user-1", principal.UserID)
+		})
+	}
+}
+
+func TestQueryRequiresRole(t *testing.T) {
+	tests := []struct {
+		name         string
+		target       string
+		header       string
+		expectedCode int
+	}{
+		{name: "without the parameter", target: "/", expectedCode: http.StatusOK},
+		{name: "anonymous", target: "/?as_of=2026-01-01T00:00:00Z", expectedCode: http.StatusUnauthorized},
+		{
+			name:         "role too low",
+			target:       "/?as_of=2026-01-01T00:00:00Z",
+			header:       "Bearer " + signToken(t, testSecret, "user-1"),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "viewer",
+			target:       "/?as_of=2026-01-01T00:00:00Z",
+			header:       "Bearer " + signToken(t, testSecret, "user-1", models.RoleViewer),
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.Problems(zap.NewNop()))
+
+			cfg := &middleware.JWTConfig{Secret: testSecret}
+			router.GET("/", middleware.QueryRequiresRole(cfg, "as_of", models.RoleViewer), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
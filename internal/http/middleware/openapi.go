@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/http/openapi"
+)
+
+// maxValidatedResponse caps the response bodies checked by OpenAPIValidation,
+// so that large exports are streamed rather than buffered.
+const maxValidatedResponse = 1 << 20
+
+// OpenAPIValidation rejects requests that do not match the operation documented
+// for them in spec with a validation problem. Responses that do not match the
+// document are logged, not altered. Undocumented routes pass through unchecked,
+// and so do the responses to WebSocket upgrades.
+func OpenAPIValidation(spec *openapi.Spec, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op, params := spec.Find(c.Request.Method, c.Request.URL.Path)
+		if op == nil {
+			c.Next()
+			return
+		}
+
+		if err := op.ValidateRequest(c.Request, params); err != nil {
+			abortWithError(c, err)
+			return
+		}
+		if c.IsWebsocket() {
+			// The response is a connection upgrade, past which there is nothing to check.
+			c.Next()
+			return
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer, max: maxValidatedResponse}
+		c.Writer = w
+		c.Next()
+		writeProblem(c)
+
+		if w.truncated {
+			return
+		}
+		if err := op.ValidateResponse(w.Status(), w.Header().Get("Content-Type"), w.body.Bytes()); err != nil {
+			logger.Warn("response does not match the API specification",
+				zap.Error(err),
+				zap.String("method", c.Request.Method),
+				zap.String("path", op.Path),
+				zap.Int("status", w.Status()),
+				zap.String("request_id", RequestIDFromContext(c)),
+			)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/http/openapi"
+)
+
+func TestOpenAPIValidation(t *testing.T) {
+	const id = "3f2c8d4e-6b1a-4f5e-9c7d-2a1b0e9f8d7c"
+	company := `{"id":"` + id + `","name":"Acme","amount_of_employees":10,` +
+		`"registered":true,"type":"Corporation","version":1}`
+
+	tests := []struct {
+		name             string
+		method           string
+		target           string
+		body             string
+		response         string
+		expectedCode     int
+		expectedBody     string
+		expectedWarnings int
+	}{
+		{
+			name:         "valid exchange",
+			method:       http.MethodGet,
+			target:       "/companies/" + id,
+			response:     company,
+			expectedCode: http.StatusOK,
+			expectedBody: company,
+		},
+		{
+			name:         "invalid request",
+			method:       http.MethodPost,
+			target:       "/companies",
+			body:         `{"name":"Acme","amount_of_employees":10,"registered":true,"type":"Partnership"}`,
+			response:     company,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"/problems/validation","title":"Bad Request","status":400,` +
+				`"detail":"request does not match the API specification","instance":"/companies",` +
+				`"errors":[{"field":"type","message":"value must be one of 'Corporation', 'NonProfit', ` +
+				`'Cooperative', 'SoleProprietorship'"}]}`,
+		},
+		{
+			name:             "invalid response is logged",
+			method:           http.MethodGet,
+			target:           "/companies/" + id,
+			response:         `{"name":"Acme"}`,
+			expectedCode:     http.StatusOK,
+			expectedBody:     `{"name":"Acme"}`,
+			expectedWarnings: 1,
+		},
+		{
+			name:         "undocumented route",
+			method:       http.MethodGet,
+			target:       "/internal",
+			response:     `{}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{}`,
+		},
+	}
+
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.WarnLevel)
+			logger := zap.New(core)
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.Problems(logger), middleware.OpenAPIValidation(spec, logger))
+			handler := func(c *gin.Context) {
+				c.Data(http.StatusOK, "application/json", []byte(tt.response))
+			}
+			router.GET("/companies/:id", handler)
+			router.POST("/companies", handler)
+			router.GET("/internal", handler)
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code)
+			require.JSONEq(t, tt.expectedBody, w.Body.String())
+			require.Equal(t, tt.expectedWarnings, logs.Len())
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+const (
+	// ProblemContentType is the media type of error responses.
+	ProblemContentType = "application/problem+json"
+	// ProblemTypeBase prefixes the type URI of every problem, followed by its app.Kind.
+	ProblemTypeBase = "/problems/"
+
+	problemLoggerKey = "problem_logger"
+)
+
+// Problem is an RFC 7807 problem details object.
+// Extensions are rendered as additional members next to the standard ones.
+type Problem struct {
+	Type       string              `json:"type"`
+	Title      string              `json:"title"`
+	Status     int                 `json:"status"`
+	Detail     string              `json:"detail,omitempty"`
+	Instance   string              `json:"instance,omitempty"`
+	RequestID  string              `json:"request_id,omitempty"`
+	Errors     []models.FieldError `json:"errors,omitempty"`
+	Extensions map[string]any      `json:"-"`
+}
+
+// MarshalJSON merges the extension members into the problem object.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	data, err := json.Marshal(problem(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+
+	ext, err := json.Marshal(p.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(data[:len(data)-1], ','), ext[1:]...), nil
+}
+
+// Problems renders the last error added to the context with c.Error as an
+// application/problem+json response, unless a response was written already.
+// Errors of a known app.Kind get their matching status and type; any other
+// error is logged and answered with an opaque 500. A gin.H set as the error's
+// meta is added to the problem as extension members.
+func Problems(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(problemLoggerKey, logger)
+		c.Next()
+		writeProblem(c)
+	}
+}
+
+// Recovery recovers from panics in the handlers after it, printing the stack like
+// gin.Recovery, and leaves the panic as an error for Problems to render as an opaque 500.
+// It must be registered after Problems.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		abortWithError(c, fmt.Errorf("panic: %v", recovered))
+	})
+}
+
+// abortWithError stops the handler chain with err, to be rendered by Problems.
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// writeProblem renders the last error of c, if any, and if nothing was written yet.
+func writeProblem(c *gin.Context) {
+	last := c.Errors.Last()
+	if last == nil || c.Writer.Written() {
+		return
+	}
+
+	p := Problem{
+		Type:      "about:blank",
+		Status:    http.StatusInternalServerError,
+		Instance:  c.Request.URL.Path,
+		RequestID: RequestIDFromContext(c),
+	}
+	if appErr := app.AsError(last.Err); appErr != nil {
+		p.Type = ProblemTypeBase + string(appErr.Kind)
+		p.Status = kindStatus(appErr.Kind)
+		p.Detail = last.Err.Error()
+		p.Errors = appErr.Fields
+	} else if logger, ok := c.Value(problemLoggerKey).(*zap.Logger); ok {
+		logger.Error("request failed",
+			zap.Error(last.Err),
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.String("request_id", p.RequestID),
+		)
+	}
+	p.Title = http.StatusText(p.Status)
+	if meta, ok := last.Meta.(gin.H); ok {
+		p.Extensions = meta
+	}
+
+	if p.Status == http.StatusUnauthorized {
+		c.Header("WWW-Authenticate", "Bearer")
+	}
+	c.Render(p.Status, problemRender{problem: p})
+}
+
+// kindStatus returns the HTTP status of errors of kind k.
+func kindStatus(k app.Kind) int {
+	switch k {
+	case app.KindNotFound:
+		return http.StatusNotFound
+	case app.KindConflict:
+		return http.StatusConflict
+	case app.KindValidation:
+		return http.StatusBadRequest
+	case app.KindPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case app.KindUnauthorized:
+		return http.StatusUnauthorized
+	case app.KindForbidden:
+		return http.StatusForbidden
+	case app.KindUnprocessable:
+		return http.StatusUnprocessableEntity
+	case app.KindTooLarge:
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// problemRender writes a Problem with the problem+json content type.
+type problemRender struct {
+	problem Problem
+}
+
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return json.NewEncoder(w).Encode(r.problem)
+}
+
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ProblemContentType)
+}
@@ -0,0 +1,118 @@
+package middleware_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+func TestProblems(t *testing.T) {
+	tests := []struct {
+		name         string
+		handler      gin.HandlerFunc
+		requestID    string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "known kind",
+			handler:      func(c *gin.Context) { _ = c.Error(app.ErrCompanyNotFound) },
+			requestID:    "req-1",
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"type":"/problems/not-found","title":"Not Found","status":404,` +
+				`"detail":"company not found","instance":"/companies","request_id":"req-1"}`,
+		},
+		{
+			name: "validation fields",
+			handler: func(c *gin.Context) {
+				_ = c.Error((&models.Company{}).Validate())
+			},
+			requestID:    "req-1",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"/problems/validation","title":"Bad Request","status":400,` +
+				`"detail":"invalid company: name is required; amount_of_employees is required; ` +
+				`registered is required; type is required","instance":"/companies","request_id":"req-1",` +
+				`"errors":[{"field":"name","message":"is required"},` +
+				`{"field":"amount_of_employees","message":"is required"},` +
+				`{"field":"registered","message":"is required"},{"field":"type","message":"is required"}]}`,
+		},
+		{
+			name:         "unexpected error is not exposed",
+			handler:      func(c *gin.Context) { _ = c.Error(errors.New("connection refused")) },
+			requestID:    "req-1",
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"type":"about:blank","title":"Internal Server Error","status":500,` +
+				`"instance":"/companies","request_id":"req-1"}`,
+		},
+		{
+			name: "extension members",
+			handler: func(c *gin.Context) {
+				c.Error(app.ErrInvalidImport).SetMeta(gin.H{"report": gin.H{"rows": 2}})
+			},
+			requestID:    "req-1",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"type":"/problems/validation","title":"Bad Request","status":400,` +
+				`"detail":"invalid import file","instance":"/companies","request_id":"req-1","report":{"rows":2}}`,
+		},
+		{
+			name: "response already written",
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"ok": true})
+				_ = c.Error(errors.New("late failure"))
+			},
+			requestID:    "req-1",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"ok":true}`,
+		},
+		{
+			name:         "panic",
+			handler:      func(*gin.Context) { panic("nil map") },
+			requestID:    "req-1",
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"type":"about:blank","title":"Internal Server Error","status":500,` +
+				`"instance":"/companies","request_id":"req-1"}`,
+		},
+		{
+			name:         "generated request id",
+			handler:      func(c *gin.Context) { _ = c.Error(app.ErrForbidden) },
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.RequestID(), middleware.Problems(zap.NewNop()), middleware.Recovery())
+			router.GET("/companies", tt.handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/companies", nil)
+			if tt.requestID != "" {
+				req.Header.Set(middleware.RequestIDHeader, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedCode, w.Code)
+			require.NotEmpty(t, w.Header().Get(middleware.RequestIDHeader))
+			if tt.requestID != "" {
+				require.Equal(t, tt.requestID, w.Header().Get(middleware.RequestIDHeader))
+			}
+			if tt.expectedBody != "" {
+				require.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			if tt.expectedCode >= http.StatusBadRequest {
+				require.Equal(t, middleware.ProblemContentType, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
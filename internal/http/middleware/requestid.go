@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// RequestIDHeader carries the ID of a request, both ways.
+	RequestIDHeader = "X-Request-ID"
+
+	requestIDKey       = "request_id"
+	maxRequestIDLength = 128
+)
+
+// RequestID tags every request with an ID, taken from the X-Request-ID header
+// when the client sent a usable one and generated otherwise. The ID is echoed
+// in the response header and reported in problem responses.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID accepts IDs of up to maxRequestIDLength printable ASCII characters.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDFromContext returns the ID assigned by RequestID, or "" without it.
+func RequestIDFromContext(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
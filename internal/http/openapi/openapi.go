@@ -0,0 +1,274 @@
+// Package openapi holds the OpenAPI 3.1 document of the HTTP API and checks
+// requests and responses against it.
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// documentURL identifies the document to the schema compiler; it is never fetched.
+const documentURL = "https://companies.local/openapi.json"
+
+//go:embed openapi.json
+var document []byte
+
+// Document returns the OpenAPI document as JSON.
+func Document() []byte {
+	return document
+}
+
+// Spec is the OpenAPI document compiled for validation.
+type Spec struct {
+	operations []*Operation
+}
+
+// Operation is a method on a documented path.
+type Operation struct {
+	Method string
+	// Path is the path template, e.g. /companies/{id}.
+	Path string
+
+	pattern      *regexp.Regexp
+	params       []parameter
+	body         *jsonschema.Schema
+	bodyRequired bool
+	// responses maps status codes, or "default", to schemas by media type.
+	// A nil schema stands for a documented media type without a JSON body.
+	responses map[string]map[string]*jsonschema.Schema
+}
+
+type parameter struct {
+	name     string
+	in       string
+	required bool
+	typ      string
+	schema   *jsonschema.Schema
+}
+
+type rawDocument struct {
+	Paths      map[string]map[string]rawOperation `json:"paths"`
+	Components struct {
+		Parameters map[string]rawParameter `json:"parameters"`
+		Responses  map[string]rawResponse  `json:"responses"`
+	} `json:"components"`
+}
+
+type rawOperation struct {
+	Parameters  []rawParameter `json:"parameters"`
+	RequestBody *struct {
+		Required bool                       `json:"required"`
+		Content  map[string]json.RawMessage `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]rawResponse `json:"responses"`
+}
+
+type rawParameter struct {
+	Ref      string `json:"$ref"`
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+	Schema   struct {
+		Type string `json:"type"`
+	} `json:"schema"`
+}
+
+type rawResponse struct {
+	Ref     string                     `json:"$ref"`
+	Content map[string]json.RawMessage `json:"content"`
+}
+
+// Load compiles the schemas of every operation of the document.
+func Load() (*Spec, error) {
+	var raw rawDocument
+	if err := json.Unmarshal(document, &raw); err != nil {
+		return nil, fmt.Errorf("parsing openapi document: %w", err)
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(document))
+	if err != nil {
+		return nil, err
+	}
+	c := jsonschema.NewCompiler()
+	c.AssertFormat()
+	if err := c.AddResource(documentURL, doc); err != nil {
+		return nil, err
+	}
+
+	spec := &Spec{}
+	for path, methods := range raw.Paths {
+		for method, rawOp := range methods {
+			op, err := compileOperation(c, &raw, path, method, &rawOp)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+			spec.operations = append(spec.operations, op)
+		}
+	}
+
+	// Literal paths must win over templates, e.g. /companies/export over /companies/{id}.
+	sort.Slice(spec.operations, func(i, j int) bool {
+		a, b := spec.operations[i], spec.operations[j]
+		if pathParams(a) != pathParams(b) {
+			return pathParams(a) < pathParams(b)
+		}
+		return a.Path+a.Method < b.Path+b.Method
+	})
+	return spec, nil
+}
+
+func pathParams(op *Operation) int {
+	return strings.Count(op.Path, "{")
+}
+
+// Operations returns every documented operation.
+func (s *Spec) Operations() []*Operation {
+	return s.operations
+}
+
+// Find returns the operation documented for method on the request path,
+// along with the values of its path parameters. It returns nil if there is none.
+func (s *Spec) Find(method, path string) (*Operation, map[string]string) {
+	for _, op := range s.operations {
+		if op.Method != method {
+			continue
+		}
+		m := op.pattern.FindStringSubmatch(path)
+		if m == nil {
+			continue
+		}
+
+		values := make(map[string]string)
+		for i, name := range op.pattern.SubexpNames() {
+			if name != "" {
+				values[name] = m[i]
+			}
+		}
+		return op, values
+	}
+	return nil, nil
+}
+
+func compileOperation(c *jsonschema.Compiler, raw *rawDocument, path, method string, rawOp *rawOperation) (
+	*Operation, error,
+) {
+	base := "#/paths/" + pointerEscape(path) + "/" + method
+	op := &Operation{
+		Method:    strings.ToUpper(method),
+		Path:      path,
+		pattern:   pathPattern(path),
+		responses: make(map[string]map[string]*jsonschema.Schema),
+	}
+
+	var err error
+	if op.params, err = compileParameters(c, raw, base, rawOp.Parameters); err != nil {
+		return nil, err
+	}
+
+	if rb := rawOp.RequestBody; rb != nil {
+		if _, ok := rb.Content[jsonMediaType]; ok {
+			op.body, err = c.Compile(documentURL + base + "/requestBody/content/application~1json/schema")
+			if err != nil {
+				return nil, err
+			}
+			op.bodyRequired = rb.Required
+		}
+	}
+
+	for status, resp := range rawOp.Responses {
+		loc := base + "/responses/" + status
+		if resp.Ref != "" {
+			loc = resp.Ref
+			resp = raw.Components.Responses[strings.TrimPrefix(resp.Ref, "#/components/responses/")]
+		}
+
+		schemas, err := compileContent(c, loc, resp.Content)
+		if err != nil {
+			return nil, err
+		}
+		op.responses[status] = schemas
+	}
+
+	return op, nil
+}
+
+// compileParameters compiles the schemas of the path and query parameters of an operation.
+func compileParameters(c *jsonschema.Compiler, raw *rawDocument, base string, params []rawParameter) (
+	[]parameter, error,
+) {
+	var compiled []parameter
+	for i, p := range params {
+		loc := fmt.Sprintf("%s/parameters/%d/schema", base, i)
+		if p.Ref != "" {
+			loc = p.Ref + "/schema"
+			p = raw.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+		}
+		if p.In != "path" && p.In != "query" {
+			continue
+		}
+
+		schema, err := c.Compile(documentURL + loc)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, parameter{
+			name: p.Name, in: p.In, required: p.Required || p.In == "path", typ: p.Schema.Type, schema: schema,
+		})
+	}
+	return compiled, nil
+}
+
+// compileContent compiles the schemas of the JSON media types of a response.
+func compileContent(c *jsonschema.Compiler, loc string, content map[string]json.RawMessage) (
+	map[string]*jsonschema.Schema, error,
+) {
+	schemas := make(map[string]*jsonschema.Schema, len(content))
+	for mediaType := range content {
+		if !isJSON(mediaType) {
+			schemas[mediaType] = nil
+			continue
+		}
+
+		schema, err := c.Compile(documentURL + loc + "/content/" + pointerEscape(mediaType) + "/schema")
+		if err != nil {
+			return nil, err
+		}
+		schemas[mediaType] = schema
+	}
+	return schemas, nil
+}
+
+// pathPattern matches request paths against a path template, capturing its parameters.
+func pathPattern(template string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for template != "" {
+		start := strings.IndexByte(template, '{')
+		end := strings.IndexByte(template, '}')
+		if start < 0 || end < start {
+			sb.WriteString(regexp.QuoteMeta(template))
+			break
+		}
+
+		sb.WriteString(regexp.QuoteMeta(template[:start]))
+		sb.WriteString("(?P<" + template[start+1:end] + ">[^/]+)")
+		template = template[end+1:]
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+// pointerEscape escapes s as a JSON pointer token within a URL fragment.
+func pointerEscape(s string) string {
+	s = strings.ReplaceAll(s, "~", "~0")
+	s = strings.ReplaceAll(s, "/", "~1")
+	return url.PathEscape(s)
+}
@@ -0,0 +1,180 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/http/openapi"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+func TestCompanySchemas(t *testing.T) {
+	var doc struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(openapi.Document(), &doc))
+
+	var fields, writable []string
+	typ := reflect.TypeOf(models.Company{})
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		fields = append(fields, name)
+		if name != "id" && name != "version" {
+			writable = append(writable, name)
+		}
+	}
+
+	properties := func(schema string) []string {
+		var names []string
+		for name := range doc.Components.Schemas[schema].Properties {
+			names = append(names, name)
+		}
+		return names
+	}
+	require.ElementsMatch(t, fields, properties("Company"))
+	require.ElementsMatch(t, writable, properties("CompanyCreate"))
+	require.ElementsMatch(t, writable, properties("CompanyPatch"))
+}
+
+func TestOperation_ValidateRequest(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		expectedFields []string
+	}{
+		{
+			name:   "valid create",
+			method: http.MethodPost,
+			target: "/companies",
+			body:   `{"name":"Acme","amount_of_employees":10,"registered":true,"type":"Corporation"}`,
+		},
+		{
+			name:           "invalid create",
+			method:         http.MethodPost,
+			target:         "/companies",
+			body:           `{"name":"Acme Incorporated Ltd","amount_of_employees":-1,"type":"Partnership"}`,
+			expectedFields: []string{"amount_of_employees", "name", "registered", "type"},
+		},
+		{
+			name:           "missing body",
+			method:         http.MethodPost,
+			target:         "/companies",
+			expectedFields: []string{"body"},
+		},
+		{
+			name:           "empty patch",
+			method:         http.MethodPatch,
+			target:         "/companies/3f2c8d4e-6b1a-4f5e-9c7d-2a1b0e9f8d7c",
+			body:           `{}`,
+			expectedFields: []string{"body"},
+		},
+		{
+			name:           "invalid path parameter",
+			method:         http.MethodGet,
+			target:         "/companies/not-a-uuid",
+			expectedFields: []string{"id"},
+		},
+		{
+			name:   "valid query",
+			method: http.MethodGet,
+			target: "/companies?type=NonProfit&registered=true&min_employees=5&sort=-name&limit=10",
+		},
+		{
+			name:           "invalid query",
+			method:         http.MethodGet,
+			target:         "/companies?registered=maybe&min_employees=-1&sort=size&limit=500",
+			expectedFields: []string{"limit", "min_employees", "registered", "sort"},
+		},
+		{
+			name:           "missing required query parameter",
+			method:         http.MethodGet,
+			target:         "/companies/3f2c8d4e-6b1a-4f5e-9c7d-2a1b0e9f8d7c/diff",
+			expectedFields: []string{"from"},
+		},
+		{
+			name:           "invalid batch operation",
+			method:         http.MethodPost,
+			target:         "/companies:batch",
+			body:           `{"operations":[{"op":"create","data":{}},{"op":"rename"}]}`,
+			expectedFields: []string{"operations.1.op"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			op, params := spec.Find(req.Method, req.URL.Path)
+			require.NotNil(t, op)
+
+			err := op.ValidateRequest(req, params)
+			if len(tt.expectedFields) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			appErr := app.AsError(err)
+			require.NotNil(t, appErr)
+			require.Equal(t, app.KindValidation, appErr.Kind)
+
+			var fields []string
+			for _, f := range appErr.Fields {
+				fields = append(fields, f.Field)
+			}
+			sort.Strings(fields)
+			require.Equal(t, tt.expectedFields, fields)
+		})
+	}
+}
+
+func TestOperation_ValidateResponse(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	op, _ := spec.Find(http.MethodGet, "/companies/3f2c8d4e-6b1a-4f5e-9c7d-2a1b0e9f8d7c")
+	require.NotNil(t, op)
+
+	company := `{"id":"3f2c8d4e-6b1a-4f5e-9c7d-2a1b0e9f8d7c","name":"Acme","amount_of_employees":10,` +
+		`"registered":true,"type":"Corporation","version":1}`
+	require.NoError(t, op.ValidateResponse(http.StatusOK, "application/json; charset=utf-8", []byte(company)))
+	require.Error(t, op.ValidateResponse(http.StatusOK, "application/json", []byte(`{"name":"Acme"}`)))
+	require.Error(t, op.ValidateResponse(http.StatusOK, "text/plain", []byte(company)))
+
+	problem := `{"type":"/problems/not-found","title":"Not Found","status":404}`
+	require.NoError(t, op.ValidateResponse(http.StatusNotFound, "application/problem+json", []byte(problem)))
+	require.NoError(t, op.ValidateResponse(http.StatusInternalServerError, "application/problem+json", []byte(problem)))
+}
+
+func TestSpec_FindPrefersLiteralPaths(t *testing.T) {
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	op, params := spec.Find(http.MethodGet, "/companies/export")
+	require.Equal(t, "/companies/export", op.Path)
+	require.Empty(t, params)
+
+	op, params = spec.Find(http.MethodGet, "/companies/42")
+	require.Equal(t, "/companies/{id}", op.Path)
+	require.Equal(t, map[string]string{"id": "42"}, params)
+
+	op, _ = spec.Find(http.MethodPut, "/companies/42")
+	require.Nil(t, op)
+}
@@ -0,0 +1,200 @@
+package openapi
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+const jsonMediaType = "application/json"
+
+// invalidRequest is the message of the errors returned by ValidateRequest.
+const invalidRequest = "request does not match the API specification"
+
+// ValidateRequest checks the path and query parameters and the JSON body of r.
+// The returned error is a KindValidation *app.Error listing the invalid fields.
+// The body of r is left readable.
+func (o *Operation) ValidateRequest(r *http.Request, pathValues map[string]string) error {
+	var fields []models.FieldError
+	for _, p := range o.params {
+		fields = append(fields, p.validate(r, pathValues)...)
+	}
+
+	if o.body != nil {
+		bodyFields, err := o.validateBody(r)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, bodyFields...)
+	}
+
+	if len(fields) > 0 {
+		return &app.Error{Kind: app.KindValidation, Message: invalidRequest, Fields: fields}
+	}
+	return nil
+}
+
+// validate checks the value of p in r. Array parameters are repeated query
+// parameters, whose items are validated as strings.
+func (p *parameter) validate(r *http.Request, pathValues map[string]string) []models.FieldError {
+	var (
+		raw     string
+		present bool
+	)
+	if p.in == "path" {
+		raw, present = pathValues[p.name]
+	} else {
+		present = r.URL.Query().Has(p.name)
+		raw = r.URL.Query().Get(p.name)
+	}
+	if !present {
+		if p.required {
+			return []models.FieldError{{Field: p.name, Message: "is required"}}
+		}
+		return nil
+	}
+
+	var v any = raw
+	switch p.typ {
+	case "array":
+		items := make([]any, 0)
+		for _, item := range r.URL.Query()[p.name] {
+			items = append(items, item)
+		}
+		v = items
+	case "integer":
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return []models.FieldError{{Field: p.name, Message: "must be an integer"}}
+		}
+		v = n
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return []models.FieldError{{Field: p.name, Message: "must be true or false"}}
+		}
+		v = b
+	}
+
+	return schemaErrors(p.name, p.schema.Validate(v))
+}
+
+// validateBody checks a JSON request body, restoring it for the handlers.
+func (o *Operation) validateBody(r *http.Request) ([]models.FieldError, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "" && mediaType != jsonMediaType {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if o.bodyRequired {
+			return []models.FieldError{{Field: "body", Message: "is required"}}, nil
+		}
+		return nil, nil
+	}
+
+	v, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return []models.FieldError{{Field: "body", Message: "must be valid JSON"}}, nil
+	}
+	return schemaErrors("", o.body.Validate(v)), nil
+}
+
+// ValidateResponse checks that status is documented for the operation and that
+// a JSON body matches its schema. Bodies of other media types are not checked.
+func (o *Operation) ValidateResponse(status int, contentType string, body []byte) error {
+	content, ok := o.responses[strconv.Itoa(status)]
+	if !ok {
+		if content, ok = o.responses["default"]; !ok {
+			return errors.New("undocumented status " + strconv.Itoa(status))
+		}
+	}
+
+	if len(body) == 0 {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	schema, ok := content[mediaType]
+	if !ok {
+		return errors.New("undocumented content type " + contentType + " for status " + strconv.Itoa(status))
+	}
+	if schema == nil {
+		return nil
+	}
+
+	v, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	return schema.Validate(v)
+}
+
+// schemaErrors converts a schema validation error into field errors, naming
+// fields by their dotted path within the value, below prefix.
+func schemaErrors(prefix string, err error) []models.FieldError {
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []models.FieldError{{Field: prefix, Message: err.Error()}}
+	}
+
+	p := message.NewPrinter(language.English)
+	var fields []models.FieldError
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		for _, cause := range e.Causes {
+			walk(cause)
+		}
+		if len(e.Causes) > 0 {
+			return
+		}
+
+		location := e.InstanceLocation
+		if prefix != "" {
+			location = append([]string{prefix}, location...)
+		}
+		if required, ok := e.ErrorKind.(*kind.Required); ok {
+			for _, name := range required.Missing {
+				fields = append(fields, models.FieldError{Field: fieldName(location, name), Message: "is required"})
+			}
+			return
+		}
+		fields = append(fields, models.FieldError{Field: fieldName(location), Message: e.ErrorKind.LocalizedString(p)})
+	}
+	walk(validationErr)
+
+	return fields
+}
+
+func fieldName(location []string, names ...string) string {
+	name := strings.Join(append(append([]string(nil), location...), names...), ".")
+	if name == "" {
+		return "body"
+	}
+	return name
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == jsonMediaType || strings.HasSuffix(mediaType, "+json")
+}
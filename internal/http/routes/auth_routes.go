@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"database/sql"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dagherghinescu/companies/internal/http/handlers"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+func RegisterAuthRoutes(r *gin.Engine, jwtCfg *middleware.JWTConfig, db *sql.DB) {
+	tokens := repository.NewTokenRepo(db)
+
+	r.POST("/login", handlers.LoginHandler(db, jwtCfg, tokens))
+	r.POST("/token/refresh", handlers.RefreshToken(jwtCfg, tokens))
+	r.POST("/logout", middleware.JWTMiddleware(jwtCfg), handlers.Logout(tokens))
+	r.GET("/.well-known/jwks.json", handlers.JWKS(jwtCfg))
+}
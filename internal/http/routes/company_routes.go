@@ -8,17 +8,39 @@ import (
 	"github.com/dagherghinescu/companies/internal/app"
 	"github.com/dagherghinescu/companies/internal/http/handlers"
 	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
 )
 
 func RegisterCompanyRoutes(r *gin.Engine, app *app.App, jwtCfg *middleware.JWTConfig, db *sql.DB) {
 	auth := r.Group("/", middleware.JWTMiddleware(jwtCfg))
 	{
-		auth.POST("/companies", handlers.CreateCompany(app))
-		auth.PATCH("/companies/:id", handlers.UpdateCompany(app))
-		auth.DELETE("/companies/:id", handlers.DeleteCompany(app))
-	}
+		viewer := middleware.RequireRole(models.RoleViewer)
+		editor := middleware.RequireRole(models.RoleEditor)
+		admin := middleware.RequireRole(models.RoleAdmin)
+		idempotent := middleware.Idempotency(repository.NewIdempotencyRepo(db), app.Config.IdempotencyKeyTTL)
 
-	r.GET("/companies/:id", handlers.GetCompany(app))
+		auth.POST("/companies", editor, idempotent, handlers.CreateCompany(app))
+		auth.POST("/companies:action", editor, idempotent, handlers.CompanyAction(app))
+		// Not idempotent: uploads are streamed rather than buffered to be fingerprinted, and
+		// retrying is safe anyway since the rows already imported are rejected as taken names.
+		auth.POST("/companies/import", editor, handlers.ImportCompanies(app))
+		auth.PATCH("/companies/:id", editor, idempotent, handlers.UpdateCompany(app))
+		auth.DELETE("/companies/:id", admin, idempotent, handlers.DeleteCompany(app))
+		auth.POST("/companies/:id/restore", editor, idempotent, handlers.RestoreCompany(app))
+		auth.POST("/companies/purge", admin, idempotent, handlers.PurgeCompanies(app))
+		auth.GET("/companies/:id/history", viewer, handlers.CompanyHistory(app))
+		auth.GET("/companies/:id/diff", viewer, handlers.DiffCompany(app))
+		// Listing, searching and exporting return companies in bulk, so they all require viewer;
+		// only single companies can be read anonymously.
+		auth.GET("/companies", viewer, handlers.ListCompanies(app))
+		auth.GET("/companies/search", viewer, handlers.SearchCompanies(app))
+		auth.GET("/companies/export", viewer, handlers.ExportCompanies(app))
+		// The change feed carries every change as it happens, like gRPC Watch, which requires viewer too.
+		auth.GET("/companies/stream", viewer, handlers.StreamChanges(app))
+		auth.GET("/companies/stream/ws", viewer, handlers.StreamChangesWebSocket(app))
+	}
 
-	r.POST("/login", handlers.LoginHandler(db, jwtCfg.Secret))
+	// Reading a company as it was in the past exposes its audit trail, like /history.
+	r.GET("/companies/:id", middleware.QueryRequiresRole(jwtCfg, "as_of", models.RoleViewer), handlers.GetCompany(app))
 }
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"expvar"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+func RegisterDebugRoutes(r *gin.Engine, jwtCfg *middleware.JWTConfig) {
+	admin := r.Group("/debug", middleware.JWTMiddleware(jwtCfg), middleware.RequireRole(models.RoleAdmin))
+	{
+		admin.GET("/vars", gin.WrapH(expvar.Handler()))
+	}
+}
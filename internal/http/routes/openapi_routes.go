@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/dagherghinescu/companies/internal/http/handlers"
+)
+
+func RegisterOpenAPIRoutes(r *gin.Engine) {
+	r.GET("/openapi.json", handlers.OpenAPIDocument())
+}
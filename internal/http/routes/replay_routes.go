@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/http/handlers"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+func RegisterReplayRoutes(r *gin.Engine, app *app.App, jwtCfg *middleware.JWTConfig) {
+	admin := r.Group("/replays", middleware.JWTMiddleware(jwtCfg), middleware.RequireRole(models.RoleAdmin))
+	{
+		admin.POST("", handlers.StartReplay(app))
+		admin.GET("", handlers.ListReplays(app))
+		admin.GET("/:id", handlers.GetReplay(app))
+		admin.POST("/:id/pause", handlers.PauseReplay(app))
+		admin.POST("/:id/resume", handlers.ResumeReplay(app))
+	}
+}
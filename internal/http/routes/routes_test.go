@@ -0,0 +1,128 @@
+package routes_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/auth"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/http/openapi"
+	"github.com/dagherghinescu/companies/internal/http/routes"
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+// TestRoutesDocumented fails when a route is added without documenting it in
+// the OpenAPI document, or when the document describes a route that is gone.
+func TestRoutesDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	appl := app.New(zap.NewNop(), nil, &app.Config{})
+	routes.RegisterAuthRoutes(router, &middleware.JWTConfig{Secret: "secret"}, nil)
+	routes.RegisterCompanyRoutes(router, appl, &middleware.JWTConfig{Secret: "secret"}, nil)
+	routes.RegisterWebhookRoutes(router, appl, &middleware.JWTConfig{Secret: "secret"})
+	routes.RegisterReplayRoutes(router, appl, &middleware.JWTConfig{Secret: "secret"})
+	routes.RegisterOpenAPIRoutes(router)
+	routes.RegisterEventRoutes(router)
+	routes.RegisterDebugRoutes(router, &middleware.JWTConfig{Secret: "secret"})
+
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	// Path parameters are replaced by values the document accepts.
+	samples := strings.NewReplacer(
+		":id", "3f2c8d4e-6b1a-4f5e-9c7d-2a1b0e9f8d7c", ":action", ":batch", ":name", "company.created.v1.json",
+	)
+
+	documented := make(map[*openapi.Operation]bool)
+	for _, route := range router.Routes() {
+		op, _ := spec.Find(route.Method, samples.Replace(route.Path))
+		require.NotNil(t, op, "%s %s is not documented", route.Method, route.Path)
+		documented[op] = true
+	}
+
+	for _, op := range spec.Operations() {
+		require.True(t, documented[op], "%s %s is documented but not routed", op.Method, op.Path)
+	}
+}
+
+// readRepo answers reads with no companies. Other methods are left to the nil embedded interface.
+type readRepo struct {
+	repository.Company
+}
+
+func (readRepo) GetByID(_ context.Context, id uuid.UUID) (*models.Company, error) {
+	return &models.Company{ID: id}, nil
+}
+
+func (readRepo) List(_ context.Context, _ repository.ListFilter) (*repository.CompanyPage, error) {
+	return &repository.CompanyPage{}, nil
+}
+
+func (readRepo) Search(_ context.Context, _ string, _ uint64) ([]repository.SearchHit, error) {
+	return nil, nil
+}
+
+func (readRepo) Export(_ context.Context, _ repository.ListFilter, _ func(c *models.Company) error) error {
+	return nil
+}
+
+// TestCompanyRoutes_ReadRoles checks that companies are only returned in bulk to viewers,
+// while a single company can be read anonymously.
+func TestCompanyRoutes_ReadRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Problems(zap.NewNop()))
+	jwtCfg := &middleware.JWTConfig{Secret: "secret"}
+	routes.RegisterCompanyRoutes(router, app.New(zap.NewNop(), readRepo{}, &app.Config{}), jwtCfg, nil)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		Roles: []models.Role{models.RoleViewer},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	viewer, err := token.SignedString([]byte(jwtCfg.Secret))
+	require.NoError(t, err)
+
+	tests := []struct {
+		target         string
+		expectedPublic bool
+	}{
+		{target: "/companies"},
+		{target: "/companies/search?q=acme"},
+		{target: "/companies/export"},
+		{target: "/companies/" + uuid.NewString(), expectedPublic: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			for _, bearer := range []string{"", viewer} {
+				req, _ := http.NewRequest(http.MethodGet, tt.target, nil)
+				if bearer != "" {
+					req.Header.Set("Authorization", "Bearer "+bearer)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				if bearer == "" && !tt.expectedPublic {
+					require.Equal(t, http.StatusUnauthorized, w.Code)
+				} else {
+					require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/http/handlers"
+	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+func RegisterWebhookRoutes(r *gin.Engine, app *app.App, jwtCfg *middleware.JWTConfig) {
+	admin := r.Group("/webhooks", middleware.JWTMiddleware(jwtCfg), middleware.RequireRole(models.RoleAdmin))
+	{
+		admin.POST("", handlers.CreateWebhook(app))
+		admin.GET("", handlers.ListWebhooks(app))
+		admin.GET("/:id", handlers.GetWebhook(app))
+		admin.DELETE("/:id", handlers.DeleteWebhook(app))
+		admin.POST("/:id/enable", handlers.EnableWebhook(app))
+		admin.GET("/:id/deliveries", handlers.WebhookDeliveries(app))
+	}
+}
@@ -14,9 +14,12 @@ type Server interface {
 	Shutdown(ctx context.Context) error
 }
 
-// StartServer starts the HTTP server with graceful shutdown
+// StartServer starts the HTTP server and shuts it down gracefully once ctx is done.
+// It returns when the shutdown is over, so that the requests in flight are finished.
 func StartServer(ctx context.Context, l *zap.Logger, srv Server) error {
+	shutdown := make(chan struct{})
 	go func() {
+		defer close(shutdown)
 		<-ctx.Done()
 		l.Info("Shutting down server...")
 		ctxShutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -30,5 +33,7 @@ func StartServer(ctx context.Context, l *zap.Logger, srv Server) error {
 	if err != nil && err != http.ErrServerClosed {
 		return err
 	}
+	// ListenAndServe returns as soon as Shutdown is called, before the requests are done.
+	<-shutdown
 	return nil
 }
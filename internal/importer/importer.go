@@ -0,0 +1,194 @@
+// Package importer decodes company uploads one row at a time, so that
+// arbitrarily large files can be imported without loading them into memory.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// Format is the encoding of an upload.
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// maxLineSize bounds a single NDJSON line.
+const maxLineSize = 1 << 20
+
+// ErrUnknownFormat is returned by New for formats other than FormatCSV and FormatNDJSON.
+var ErrUnknownFormat = errors.New("unknown import format")
+
+// Row is a single decoded row of an upload.
+// Err is set instead of Company when the row could not be decoded.
+type Row struct {
+	Line    int
+	Company *models.Company
+	Err     error
+}
+
+// Reader returns the rows of an upload in order.
+// Next returns io.EOF after the last row, and any other error when the upload
+// as a whole cannot be read any further.
+type Reader interface {
+	Next() (Row, error)
+}
+
+// New returns a Reader decoding r in the given format.
+func New(format Format, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVReader(r)
+	case FormatNDJSON:
+		return NewNDJSONReader(r), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+// NewCSVReader returns a Reader for CSV with a header row naming the columns.
+// The name, amount_of_employees, registered and type columns are required,
+// description is optional and columns may appear in any order.
+func NewCSVReader(r io.Reader) (Reader, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !isCSVColumn(name) {
+			return nil, fmt.Errorf("unknown csv column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"name", "amount_of_employees", "registered", "type"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing csv column %q", name)
+		}
+	}
+
+	return &csvReader{r: cr, columns: columns}, nil
+}
+
+func isCSVColumn(name string) bool {
+	switch name {
+	case "name", "description", "amount_of_employees", "registered", "type":
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *csvReader) Next() (Row, error) {
+	record, err := r.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Row{Line: parseErr.StartLine, Err: parseErr.Err}, nil
+		}
+		return Row{}, err
+	}
+
+	line, _ := r.r.FieldPos(0)
+	company, err := r.company(record)
+	return Row{Line: line, Company: company, Err: err}, nil
+}
+
+// company converts a record, leaving empty cells unset.
+func (r *csvReader) company(record []string) (*models.Company, error) {
+	cell := func(name string) (string, bool) {
+		i, ok := r.columns[name]
+		if !ok {
+			return "", false
+		}
+		v := strings.TrimSpace(record[i])
+		return v, v != ""
+	}
+
+	var c models.Company
+	if v, ok := cell("name"); ok {
+		c.Name = &v
+	}
+	if v, ok := cell("description"); ok {
+		c.Description = &v
+	}
+	if v, ok := cell("amount_of_employees"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("amount_of_employees must be an integer, got %q", v)
+		}
+		c.AmountEmployees = &n
+	}
+	if v, ok := cell("registered"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("registered must be true or false, got %q", v)
+		}
+		c.Registered = &b
+	}
+	if v, ok := cell("type"); ok {
+		t := models.CompanyType(v)
+		c.Type = &t
+	}
+
+	return &c, nil
+}
+
+type ndjsonReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+// NewNDJSONReader returns a Reader for newline delimited JSON, one company object per line.
+// Blank lines are skipped.
+func NewNDJSONReader(r io.Reader) Reader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &ndjsonReader{s: s}
+}
+
+func (r *ndjsonReader) Next() (Row, error) {
+	for r.s.Scan() {
+		r.line++
+
+		data := bytes.TrimSpace(r.s.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+
+		var c models.Company
+		if err := dec.Decode(&c); err != nil {
+			return Row{Line: r.line, Err: err}, nil
+		}
+		return Row{Line: r.line, Company: &c}, nil
+	}
+
+	if err := r.s.Err(); err != nil {
+		return Row{}, err
+	}
+	return Row{}, io.EOF
+}
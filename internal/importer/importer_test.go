@@ -0,0 +1,106 @@
+package importer_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/importer"
+)
+
+// rowResult is the part of a row the tests compare.
+type rowResult struct {
+	Line int
+	Name string
+	Err  bool
+}
+
+func readAll(t *testing.T, r importer.Reader) []rowResult {
+	t.Helper()
+
+	var rows []rowResult
+	for {
+		row, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return rows
+		}
+		require.NoError(t, err)
+
+		res := rowResult{Line: row.Line, Err: row.Err != nil}
+		if row.Company != nil && row.Company.Name != nil {
+			res.Name = *row.Company.Name
+		}
+		rows = append(rows, res)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expectErr bool
+		expected  []rowResult
+	}{
+		{
+			name: "rows",
+			input: "type,name,amount_of_employees,registered\n" +
+				"Corporation,Acme,10,true\n" +
+				"NonProfit,Globex,many,false\n" +
+				"Cooperative,Initech,5\n" +
+				"Corporation,,3,true\n",
+			expected: []rowResult{
+				{Line: 2, Name: "Acme"},
+				{Line: 3, Err: true},
+				{Line: 4, Err: true},
+				{Line: 5},
+			},
+		},
+		{
+			name:      "unknown column",
+			input:     "name,amount_of_employees,registered,type,country\n",
+			expectErr: true,
+		},
+		{
+			name:      "missing column",
+			input:     "name,registered,type\n",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := importer.New(importer.FormatCSV, strings.NewReader(tt.input))
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, readAll(t, r))
+		})
+	}
+}
+
+func TestNDJSONReader(t *testing.T) {
+	input := `{"name":"Acme","amount_of_employees":10,"registered":true,"type":"Corporation"}` + "\n" +
+		"\n" +
+		`{"name":"Globex",` + "\n" +
+		`{"name":"Initech","country":"US"}` + "\n" +
+		`{"name":"Umbrella"}`
+
+	r, err := importer.New(importer.FormatNDJSON, strings.NewReader(input))
+	require.NoError(t, err)
+	require.Equal(t, []rowResult{
+		{Line: 1, Name: "Acme"},
+		{Line: 3, Err: true},
+		{Line: 4, Err: true},
+		{Line: 5, Name: "Umbrella"},
+	}, readAll(t, r))
+}
+
+func TestNew_UnknownFormat(t *testing.T) {
+	_, err := importer.New("xlsx", strings.NewReader(""))
+	require.ErrorIs(t, err, importer.ErrUnknownFormat)
+}
@@ -0,0 +1,54 @@
+package kafka
+
+import (
+	"encoding/json"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+
+	"github.com/dagherghinescu/companies/internal/events"
+)
+
+// CloudEvents content modes of the Kafka protocol binding.
+const (
+	// ModeBinary puts the event data in the message value and its attributes in ce_ headers.
+	ModeBinary = "binary"
+	// ModeStructured puts the whole event, as JSON, in the message value.
+	ModeStructured = "structured"
+)
+
+// CloudEventMessage lays out a payload, a CloudEvent in the JSON event format, as a
+// message in the given content mode. Outbox payloads that are not CloudEvents, written
+// before events were, are sent unchanged.
+func CloudEventMessage(key string, payload []byte, mode string) Message {
+	var e events.Event[json.RawMessage]
+	if err := json.Unmarshal(payload, &e); err != nil || e.SpecVersion == "" {
+		return Message{Key: key, Value: json.RawMessage(payload)}
+	}
+
+	if mode == ModeStructured {
+		return Message{
+			Key:     key,
+			Value:   json.RawMessage(payload),
+			Headers: []kafka.Header{{Key: "content-type", Value: []byte(events.StructuredContentType)}},
+		}
+	}
+
+	headers := []kafka.Header{
+		{Key: "ce_specversion", Value: []byte(e.SpecVersion)},
+		{Key: "ce_id", Value: []byte(e.ID)},
+		{Key: "ce_source", Value: []byte(e.Source)},
+		{Key: "ce_type", Value: []byte(e.Type)},
+		{Key: "ce_time", Value: []byte(e.Time.Format(time.RFC3339Nano))},
+	}
+	if e.Subject != "" {
+		headers = append(headers, kafka.Header{Key: "ce_subject", Value: []byte(e.Subject)})
+	}
+	if e.DataSchema != "" {
+		headers = append(headers, kafka.Header{Key: "ce_dataschema", Value: []byte(e.DataSchema)})
+	}
+	if e.DataContentType != "" {
+		headers = append(headers, kafka.Header{Key: "content-type", Value: []byte(e.DataContentType)})
+	}
+	return Message{Key: key, Value: e.Data, Headers: headers}
+}
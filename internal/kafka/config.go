@@ -1,11 +1,78 @@
 package kafka
 
-import "github.com/kelseyhightower/envconfig"
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
 
 // Config holds the kafka variables
 type Config struct {
 	Broker string `envconfig:"BROKER" required:"true"`
 	Topic  string `envconfig:"TOPIC" required:"true"`
+
+	// Compression is the codec of the produced messages: none, gzip, snappy, lz4 or zstd.
+	Compression string `envconfig:"COMPRESSION" default:"none"`
+	// BatchSize, BatchBytes and BatchTimeout bound the batches sent to a partition.
+	// A batch is sent once full or once the timeout expires.
+	BatchSize    int           `envconfig:"BATCH_SIZE" default:"100"`
+	BatchBytes   int64         `envconfig:"BATCH_BYTES" default:"1048576"`
+	BatchTimeout time.Duration `envconfig:"BATCH_TIMEOUT" default:"10ms"`
+	// Async makes publishes return before the messages are written. Write errors are
+	// only logged, so events may be lost.
+	Async bool `envconfig:"ASYNC" default:"false"`
+	// Balancer picks the partition of a message: hash, murmur2, crc32, least_bytes or round_robin.
+	Balancer string `envconfig:"BALANCER" default:"hash"`
+	// RequiredAcks is the number of replicas that must acknowledge a write: all, one or none.
+	RequiredAcks string `envconfig:"REQUIRED_ACKS" default:"all"`
+
+	TLSEnabled            bool   `envconfig:"TLS_ENABLED" default:"false"`
+	TLSCAFile             string `envconfig:"TLS_CA_FILE"`
+	TLSCertFile           string `envconfig:"TLS_CERT_FILE"`
+	TLSKeyFile            string `envconfig:"TLS_KEY_FILE"`
+	TLSServerName         string `envconfig:"TLS_SERVER_NAME"`
+	TLSInsecureSkipVerify bool   `envconfig:"TLS_INSECURE_SKIP_VERIFY" default:"false"`
+
+	// SASLMechanism is plain, scram-sha-256 or scram-sha-512; empty disables SASL.
+	SASLMechanism string `envconfig:"SASL_MECHANISM"`
+	SASLUsername  string `envconfig:"SASL_USERNAME"`
+	SASLPassword  string `envconfig:"SASL_PASSWORD"`
+
+	// OutboxPollInterval is how often the relay looks for unpublished outbox events.
+	OutboxPollInterval time.Duration `envconfig:"OUTBOX_POLL_INTERVAL" default:"1s"`
+	// OutboxBatchSize caps the number of events the relay publishes per poll.
+	OutboxBatchSize uint64 `envconfig:"OUTBOX_BATCH_SIZE" default:"100"`
+	// OutboxRetention is how long published events are kept in the outbox; zero keeps them.
+	OutboxRetention time.Duration `envconfig:"OUTBOX_RETENTION" default:"168h"`
+	// OutboxSweepInterval is how often the relay deletes the events older than OutboxRetention.
+	OutboxSweepInterval time.Duration `envconfig:"OUTBOX_SWEEP_INTERVAL" default:"1h"`
+	// CloudEventsMode is how events are laid out in messages: ModeBinary or ModeStructured.
+	CloudEventsMode string `envconfig:"CLOUDEVENTS_MODE" default:"binary"`
+
+	// PublishRetries is the number of times a failed publish is retried before giving up.
+	PublishRetries int `envconfig:"PUBLISH_RETRIES" default:"3"`
+	// PublishRetryBackoff is the delay before the first retry; it doubles with each retry,
+	// up to PublishRetryMaxBackoff, and is randomized to spread out the retries of instances.
+	PublishRetryBackoff    time.Duration `envconfig:"PUBLISH_RETRY_BACKOFF" default:"100ms"`
+	PublishRetryMaxBackoff time.Duration `envconfig:"PUBLISH_RETRY_MAX_BACKOFF" default:"2s"`
+	// BreakerThreshold is the number of failed publishes in a row that opens the circuit breaker.
+	BreakerThreshold int `envconfig:"BREAKER_THRESHOLD" default:"5"`
+	// BreakerCooldown is how long the open breaker fails publishes before letting one through.
+	BreakerCooldown time.Duration `envconfig:"BREAKER_COOLDOWN" default:"30s"`
+
+	// CommandTopic is the topic company commands are consumed from; empty disables the consumer.
+	CommandTopic string `envconfig:"COMMAND_TOPIC"`
+	// ConsumerGroup is the consumer group the command consumers join.
+	ConsumerGroup string `envconfig:"CONSUMER_GROUP" default:"companies"`
+	// DeadLetterTopic receives the commands that could not be applied. Defaults to CommandTopic + ".dlq".
+	DeadLetterTopic string `envconfig:"DEAD_LETTER_TOPIC"`
+	// ConsumerMaxAttempts is the number of attempts after which a failing command is dead-lettered.
+	ConsumerMaxAttempts int `envconfig:"CONSUMER_MAX_ATTEMPTS" default:"10"`
+	// ConsumerRetryBackoff is the delay before the first retry of a failing command; it doubles
+	// with each attempt, up to a minute.
+	ConsumerRetryBackoff time.Duration `envconfig:"CONSUMER_RETRY_BACKOFF" default:"1s"`
+	// CommandActor is recorded in the audit trail as the author of the changes made by commands.
+	CommandActor string `envconfig:"COMMAND_ACTOR" default:"kafka"`
 }
 
 // EnvConfig loads the Kafka configuration from environment variables
@@ -15,5 +82,8 @@ func EnvConfig() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if cfg.CommandTopic != "" && cfg.DeadLetterTopic == "" {
+		cfg.DeadLetterTopic = cfg.CommandTopic + ".dlq"
+	}
 	return &cfg, nil
 }
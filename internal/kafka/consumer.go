@@ -0,0 +1,248 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/backoff"
+)
+
+// consumerMaxBackoff caps the delay between the attempts of a failing message.
+const consumerMaxBackoff = time.Minute
+
+// Headers added to the messages sent to the dead-letter topic, next to the original ones.
+const (
+	HeaderDeadLetterError     = "dlq-error"
+	HeaderDeadLetterAttempts  = "dlq-attempts"
+	HeaderDeadLetterTopic     = "dlq-original-topic"
+	HeaderDeadLetterPartition = "dlq-original-partition"
+	HeaderDeadLetterOffset    = "dlq-original-offset"
+	HeaderDeadLetterFailedAt  = "dlq-failed-at"
+)
+
+// Handler processes a consumed message. Errors wrapped with Permanent dead-letter the
+// message right away; other errors are retried.
+type Handler func(ctx context.Context, msg kafka.Message) error
+
+// MessageReader is the consumer group member the consumer fetches messages from.
+type MessageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// MessageWriter is where the consumer sends the messages it gives up on.
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as one that retrying cannot fix, such as a malformed message.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Consumer hands the messages of a consumer group to a Handler, one at a time so that
+// each partition is processed in order. A message is committed only once it was handled,
+// or sent to the dead-letter topic after a permanent error or too many failed attempts.
+type Consumer struct {
+	reader      MessageReader
+	deadLetters MessageWriter
+	handler     Handler
+	log         *zap.Logger
+	maxAttempts int
+	baseBackoff time.Duration
+	now         func() time.Time
+}
+
+// NewConsumer creates a consumer handing the messages of reader to handler.
+func NewConsumer(
+	cfg *Config, reader MessageReader, deadLetters MessageWriter, handler Handler, log *zap.Logger,
+) *Consumer {
+	return &Consumer{
+		reader:      reader,
+		deadLetters: deadLetters,
+		handler:     handler,
+		log:         log,
+		maxAttempts: max(cfg.ConsumerMaxAttempts, 1),
+		baseBackoff: cfg.ConsumerRetryBackoff,
+		now:         time.Now,
+	}
+}
+
+// NewCommandReader joins the consumer group reading the command topic.
+// Offsets are committed synchronously by CommitMessages.
+func NewCommandReader(cfg *Config) (*kafka.Reader, error) {
+	dialer, err := cfg.dialer()
+	if err != nil {
+		return nil, err
+	}
+	return kafka.NewReader(kafka.ReaderConfig{
+		Brokers: cfg.brokers(),
+		GroupID: cfg.ConsumerGroup,
+		Topic:   cfg.CommandTopic,
+		Dialer:  dialer,
+	}), nil
+}
+
+// NewDeadLetterWriter creates the writer of the dead-letter topic. It uses the producer
+// settings, except that writes are always synchronous: a command is only committed
+// once it reached the dead-letter topic.
+func NewDeadLetterWriter(cfg *Config) (*kafka.Writer, error) {
+	writer, err := cfg.writer(cfg.DeadLetterTopic)
+	if err != nil {
+		return nil, err
+	}
+	writer.Async = false
+	return writer, nil
+}
+
+// Run consumes messages until ctx is canceled, then leaves the group and closes the
+// dead-letter writer. A message being handled at that point is not committed, so it
+// is consumed again after the restart.
+func (c *Consumer) Run(ctx context.Context) {
+	defer c.close()
+
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			c.log.Error("failed to fetch message", zap.Error(err))
+			if !sleep(ctx, c.baseBackoff) {
+				return
+			}
+			continue
+		}
+
+		if !c.process(ctx, msg) {
+			return
+		}
+	}
+}
+
+// process handles msg, dead-letters it if that fails, and commits it.
+// It returns false when ctx was canceled first.
+func (c *Consumer) process(ctx context.Context, msg kafka.Message) bool {
+	attempts, err := c.handle(ctx, msg)
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		c.log.Warn("dead-lettering message",
+			zap.String("topic", msg.Topic),
+			zap.Int("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.Int("attempts", attempts),
+			zap.Error(err),
+		)
+		dead := c.deadLetter(msg, attempts, err)
+		if !c.retry(ctx, func() error { return c.deadLetters.WriteMessages(ctx, dead) }) {
+			return false
+		}
+	}
+
+	return c.retry(ctx, func() error { return c.reader.CommitMessages(ctx, msg) })
+}
+
+// handle runs the handler until it succeeds, fails permanently or runs out of attempts,
+// and returns the number of attempts with the last error.
+func (c *Consumer) handle(ctx context.Context, msg kafka.Message) (int, error) {
+	for attempt := 1; ; attempt++ {
+		err := c.handler(ctx, msg)
+		if err == nil || IsPermanent(err) || attempt >= c.maxAttempts {
+			return attempt, err
+		}
+
+		delay := c.backoff(attempt)
+		c.log.Warn("message handling failed, retrying",
+			zap.String("key", string(msg.Key)),
+			zap.Int("attempts", attempt),
+			zap.Duration("retry_in", delay),
+			zap.Error(err),
+		)
+		if !sleep(ctx, delay) {
+			return attempt, ctx.Err()
+		}
+	}
+}
+
+// retry runs fn until it succeeds, backing off between attempts.
+// It returns false when ctx was canceled first.
+func (c *Consumer) retry(ctx context.Context, fn func() error) bool {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+
+		c.log.Error("kafka operation failed, retrying", zap.Int("attempts", attempt), zap.Error(err))
+		if !sleep(ctx, c.backoff(attempt)) {
+			return false
+		}
+	}
+}
+
+// deadLetter copies msg for the dead-letter topic, recording why it failed.
+func (c *Consumer) deadLetter(msg kafka.Message, attempts int, err error) kafka.Message {
+	headers := append(slices.Clone(msg.Headers),
+		kafka.Header{Key: HeaderDeadLetterError, Value: []byte(err.Error())},
+		kafka.Header{Key: HeaderDeadLetterAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: HeaderDeadLetterTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: HeaderDeadLetterPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: HeaderDeadLetterOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: HeaderDeadLetterFailedAt, Value: []byte(c.now().UTC().Format(time.RFC3339Nano))},
+	)
+	return kafka.Message{Key: msg.Key, Value: msg.Value, Headers: headers}
+}
+
+// backoff doubles the delay with each attempt, up to consumerMaxBackoff.
+func (c *Consumer) backoff(attempts int) time.Duration {
+	return backoff.Exponential(c.baseBackoff, consumerMaxBackoff, attempts-1)
+}
+
+func (c *Consumer) close() {
+	if err := c.reader.Close(); err != nil {
+		c.log.Error("failed to close consumer", zap.Error(err))
+	}
+	if err := c.deadLetters.Close(); err != nil {
+		c.log.Error("failed to close dead-letter writer", zap.Error(err))
+	}
+}
+
+// sleep waits for d, and returns false if ctx is canceled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
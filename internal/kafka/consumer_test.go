@@ -0,0 +1,174 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	segkafka "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/kafka"
+)
+
+// mockReader hands out its messages, then cancels the consumer.
+type mockReader struct {
+	msgs      []segkafka.Message
+	cancel    context.CancelFunc
+	committed []int64
+	closed    bool
+}
+
+func (m *mockReader) FetchMessage(ctx context.Context) (segkafka.Message, error) {
+	if len(m.msgs) == 0 {
+		m.cancel()
+		return segkafka.Message{}, ctx.Err()
+	}
+	msg := m.msgs[0]
+	m.msgs = m.msgs[1:]
+	return msg, nil
+}
+
+func (m *mockReader) CommitMessages(_ context.Context, msgs ...segkafka.Message) error {
+	for _, msg := range msgs {
+		m.committed = append(m.committed, msg.Offset)
+	}
+	return nil
+}
+
+func (m *mockReader) Close() error {
+	m.closed = true
+	return nil
+}
+
+type mockWriter struct {
+	failures int
+	written  []segkafka.Message
+	closed   bool
+}
+
+func (m *mockWriter) WriteMessages(_ context.Context, msgs ...segkafka.Message) error {
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("broker unavailable")
+	}
+	m.written = append(m.written, msgs...)
+	return nil
+}
+
+func (m *mockWriter) Close() error {
+	m.closed = true
+	return nil
+}
+
+func TestConsumer_Run(t *testing.T) {
+	errTransient := errors.New("database unavailable")
+
+	tests := []struct {
+		name              string
+		results           []error
+		dlqFailures       int
+		expectedAttempts  int
+		expectedDeadError string
+	}{
+		{
+			name:             "handled",
+			results:          []error{nil},
+			expectedAttempts: 1,
+		},
+		{
+			name:             "retried until handled",
+			results:          []error{errTransient, errTransient, nil},
+			expectedAttempts: 3,
+		},
+		{
+			name:              "permanent error",
+			results:           []error{kafka.Permanent(errors.New("invalid command"))},
+			expectedAttempts:  1,
+			expectedDeadError: "invalid command",
+		},
+		{
+			name:              "out of attempts",
+			results:           []error{errTransient, errTransient, errTransient, errTransient},
+			expectedAttempts:  3,
+			expectedDeadError: "database unavailable",
+		},
+		{
+			name:              "dead-letter write retried",
+			results:           []error{kafka.Permanent(errors.New("invalid command"))},
+			dlqFailures:       2,
+			expectedAttempts:  1,
+			expectedDeadError: "invalid command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			msg := segkafka.Message{
+				Topic: "commands", Partition: 2, Offset: 42, Key: []byte("k"), Value: []byte(`{}`),
+				Headers: []segkafka.Header{{Key: "trace", Value: []byte("t-1")}},
+			}
+			reader := &mockReader{msgs: []segkafka.Message{msg}, cancel: cancel}
+			writer := &mockWriter{failures: tt.dlqFailures}
+
+			attempts := 0
+			handler := func(_ context.Context, m segkafka.Message) error {
+				require.Equal(t, msg.Value, m.Value)
+				attempts++
+				return tt.results[attempts-1]
+			}
+
+			cfg := &kafka.Config{ConsumerMaxAttempts: 3}
+			kafka.NewConsumer(cfg, reader, writer, handler, zap.NewNop()).Run(ctx)
+
+			require.Equal(t, tt.expectedAttempts, attempts)
+			require.Equal(t, []int64{42}, reader.committed)
+			require.True(t, reader.closed)
+			require.True(t, writer.closed)
+
+			if tt.expectedDeadError == "" {
+				require.Empty(t, writer.written)
+				return
+			}
+			require.Len(t, writer.written, 1)
+			dead := writer.written[0]
+			require.Equal(t, msg.Key, dead.Key)
+			require.Equal(t, msg.Value, dead.Value)
+			require.Empty(t, dead.Topic)
+
+			headers := make(map[string]string)
+			for _, h := range dead.Headers {
+				headers[h.Key] = string(h.Value)
+			}
+			require.Equal(t, "t-1", headers["trace"])
+			require.Equal(t, tt.expectedDeadError, headers[kafka.HeaderDeadLetterError])
+			require.Equal(t, "commands", headers[kafka.HeaderDeadLetterTopic])
+			require.Equal(t, "2", headers[kafka.HeaderDeadLetterPartition])
+			require.Equal(t, "42", headers[kafka.HeaderDeadLetterOffset])
+			require.NotEmpty(t, headers[kafka.HeaderDeadLetterFailedAt])
+		})
+	}
+}
+
+func TestConsumer_Run_ShutdownWhileRetrying(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reader := &mockReader{msgs: []segkafka.Message{{Offset: 7}}, cancel: cancel}
+	writer := &mockWriter{}
+	handler := func(context.Context, segkafka.Message) error {
+		cancel()
+		return errors.New("database unavailable")
+	}
+
+	cfg := &kafka.Config{ConsumerMaxAttempts: 3}
+	kafka.NewConsumer(cfg, reader, writer, handler, zap.NewNop()).Run(ctx)
+
+	require.Empty(t, reader.committed)
+	require.Empty(t, writer.written)
+	require.True(t, reader.closed)
+}
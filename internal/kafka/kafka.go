@@ -3,33 +3,46 @@ package kafka
 import (
 	"context"
 	"encoding/json"
-	"strings"
 	"time"
 
 	kafka "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
 )
 
 // ProducerInterface defines the methods your app needs
 type ProducerInterface interface {
 	Publish(ctx context.Context, key string, value any) error
+	PublishBatch(ctx context.Context, msgs ...Message) error
 	Close() error
 }
 
+// Message is a single message of a PublishBatch call.
+type Message struct {
+	Key     string
+	Value   any
+	Headers []kafka.Header
+}
+
 // Producer wraps a Kafka writer
 type Producer struct {
 	writer *kafka.Writer
 }
 
-// NewProducer creates a new Kafka producer using KafkaConfig
-func NewProducer(cfg *Config) *Producer {
-	brokers := strings.Split(cfg.Broker, ",")
-	writer := &kafka.Writer{
-		Addr:         kafka.TCP(brokers...),
-		Topic:        cfg.Topic,
-		Balancer:     &kafka.LeastBytes{},
-		RequiredAcks: kafka.RequireAll,
+// NewProducer creates a new Kafka producer using KafkaConfig.
+// In async mode the errors of the writes are logged to log.
+func NewProducer(cfg *Config, log *zap.Logger) (*Producer, error) {
+	writer, err := cfg.writer(cfg.Topic)
+	if err != nil {
+		return nil, err
 	}
-	return &Producer{writer: writer}
+	if cfg.Async {
+		writer.Completion = func(msgs []kafka.Message, err error) {
+			if err != nil {
+				log.Error("async kafka write failed", zap.Int("messages", len(msgs)), zap.Error(err))
+			}
+		}
+	}
+	return &Producer{writer: writer}, nil
 }
 
 // Close shuts down the Kafka producer, releasing all resources.
@@ -54,3 +67,29 @@ func (p *Producer) Publish(ctx context.Context, key string, value any) error {
 	}
 	return p.writer.WriteMessages(ctx, msg)
 }
+
+// PublishBatch sends all messages to the Kafka topic in a single WriteMessages call.
+// Each value is marshaled to JSON. When only some messages could not be written
+// the returned error is a kafka.WriteErrors holding the error of each message by index.
+func (p *Producer) PublishBatch(ctx context.Context, msgs ...Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	batch := make([]kafka.Message, len(msgs))
+	for i, m := range msgs {
+		data, err := json.Marshal(m.Value)
+		if err != nil {
+			return err
+		}
+
+		batch[i] = kafka.Message{
+			Key:     []byte(m.Key),
+			Value:   data,
+			Headers: m.Headers,
+			Time:    now,
+		}
+	}
+	return p.writer.WriteMessages(ctx, batch...)
+}
@@ -0,0 +1,257 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// Validate checks the configuration, loading the TLS certificates, so that
+// mistakes are reported at startup rather than on the first publish.
+func (c *Config) Validate() error {
+	if c.CloudEventsMode != ModeBinary && c.CloudEventsMode != ModeStructured {
+		return fmt.Errorf("KAFKA_CLOUDEVENTS_MODE must be %s or %s", ModeBinary, ModeStructured)
+	}
+	if err := c.validateOutbox(); err != nil {
+		return err
+	}
+	if c.CommandTopic != "" && c.DeadLetterTopic == c.CommandTopic {
+		return errors.New("KAFKA_DEAD_LETTER_TOPIC must differ from KAFKA_COMMAND_TOPIC")
+	}
+	if err := c.validateWriter(); err != nil {
+		return err
+	}
+	if _, err := c.tlsConfig(); err != nil {
+		return err
+	}
+	_, err := c.saslMechanism()
+	return err
+}
+
+// validateOutbox checks the settings of the outbox sweep.
+func (c *Config) validateOutbox() error {
+	if c.OutboxRetention < 0 {
+		return errors.New("KAFKA_OUTBOX_RETENTION must not be negative")
+	}
+	if c.OutboxRetention > 0 && c.OutboxSweepInterval <= 0 {
+		return errors.New("KAFKA_OUTBOX_SWEEP_INTERVAL must be positive when KAFKA_OUTBOX_RETENTION is set")
+	}
+	return nil
+}
+
+// validateWriter checks the settings of the producer.
+func (c *Config) validateWriter() error {
+	if _, err := c.compression(); err != nil {
+		return err
+	}
+	if _, err := c.balancer(); err != nil {
+		return err
+	}
+	if _, err := c.requiredAcks(); err != nil {
+		return err
+	}
+	if c.BatchSize < 1 || c.BatchBytes < 1 || c.BatchTimeout <= 0 {
+		return errors.New("KAFKA_BATCH_SIZE, KAFKA_BATCH_BYTES and KAFKA_BATCH_TIMEOUT must be positive")
+	}
+	return nil
+}
+
+// writer creates a writer to topic with the producer settings.
+func (c *Config) writer(topic string) (*kafka.Writer, error) {
+	compression, err := c.compression()
+	if err != nil {
+		return nil, err
+	}
+	balancer, err := c.balancer()
+	if err != nil {
+		return nil, err
+	}
+	acks, err := c.requiredAcks()
+	if err != nil {
+		return nil, err
+	}
+	transport, err := c.transport()
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafka.Writer{
+		Addr:         kafka.TCP(c.brokers()...),
+		Topic:        topic,
+		Balancer:     balancer,
+		RequiredAcks: acks,
+		Compression:  compression,
+		BatchSize:    c.BatchSize,
+		BatchBytes:   c.BatchBytes,
+		BatchTimeout: c.BatchTimeout,
+		Async:        c.Async,
+		Transport:    transport,
+	}, nil
+}
+
+// transport returns the transport of the writers, the default one when neither
+// TLS nor SASL is configured.
+func (c *Config) transport() (kafka.RoundTripper, error) {
+	tlsCfg, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	mechanism, err := c.saslMechanism()
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg == nil && mechanism == nil {
+		return kafka.DefaultTransport, nil
+	}
+	return &kafka.Transport{TLS: tlsCfg, SASL: mechanism}, nil
+}
+
+// dialer returns the dialer of the readers.
+func (c *Config) dialer() (*kafka.Dialer, error) {
+	tlsCfg, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	mechanism, err := c.saslMechanism()
+	if err != nil {
+		return nil, err
+	}
+	return &kafka.Dialer{
+		Timeout:       10 * time.Second,
+		DualStack:     true,
+		TLS:           tlsCfg,
+		SASLMechanism: mechanism,
+	}, nil
+}
+
+func (c *Config) brokers() []string {
+	return strings.Split(c.Broker, ",")
+}
+
+func (c *Config) compression() (kafka.Compression, error) {
+	switch c.Compression {
+	case "none":
+		return 0, nil
+	case "gzip":
+		return kafka.Gzip, nil
+	case "snappy":
+		return kafka.Snappy, nil
+	case "lz4":
+		return kafka.Lz4, nil
+	case "zstd":
+		return kafka.Zstd, nil
+	default:
+		return 0, fmt.Errorf("KAFKA_COMPRESSION %q must be none, gzip, snappy, lz4 or zstd", c.Compression)
+	}
+}
+
+// balancer returns the partitioner of the messages. The hashing ones send all the
+// events of a company to the same partition, which keeps them in order.
+func (c *Config) balancer() (kafka.Balancer, error) {
+	switch c.Balancer {
+	case "hash":
+		return &kafka.Hash{}, nil
+	case "murmur2":
+		return kafka.Murmur2Balancer{}, nil
+	case "crc32":
+		return kafka.CRC32Balancer{}, nil
+	case "least_bytes":
+		return &kafka.LeastBytes{}, nil
+	case "round_robin":
+		return &kafka.RoundRobin{}, nil
+	default:
+		return nil, fmt.Errorf(
+			"KAFKA_BALANCER %q must be hash, murmur2, crc32, least_bytes or round_robin", c.Balancer,
+		)
+	}
+}
+
+func (c *Config) requiredAcks() (kafka.RequiredAcks, error) {
+	switch c.RequiredAcks {
+	case "all":
+		return kafka.RequireAll, nil
+	case "one":
+		return kafka.RequireOne, nil
+	case "none":
+		return kafka.RequireNone, nil
+	default:
+		return 0, fmt.Errorf("KAFKA_REQUIRED_ACKS %q must be all, one or none", c.RequiredAcks)
+	}
+}
+
+// tlsConfig returns the TLS configuration of the connections, nil when TLS is disabled.
+func (c *Config) tlsConfig() (*tls.Config, error) {
+	if !c.TLSEnabled {
+		if c.TLSCAFile != "" || c.TLSCertFile != "" || c.TLSKeyFile != "" {
+			return nil, errors.New("KAFKA_TLS_*_FILE require KAFKA_TLS_ENABLED=true")
+		}
+		return nil, nil
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return nil, errors.New("KAFKA_TLS_CERT_FILE and KAFKA_TLS_KEY_FILE must be set together")
+	}
+
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.TLSServerName,
+		InsecureSkipVerify: c.TLSInsecureSkipVerify,
+	}
+	if err := c.loadTLSFiles(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadTLSFiles adds the CA and the client certificate files to cfg.
+func (c *Config) loadTLSFiles(cfg *tls.Config) error {
+	if c.TLSCAFile != "" {
+		pem, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return fmt.Errorf("KAFKA_TLS_CA_FILE: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return errors.New("KAFKA_TLS_CA_FILE holds no PEM certificate")
+		}
+	}
+	if c.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("KAFKA_TLS_CERT_FILE: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return nil
+}
+
+// saslMechanism returns the SASL mechanism of the connections, nil when SASL is disabled.
+func (c *Config) saslMechanism() (sasl.Mechanism, error) {
+	if c.SASLMechanism == "" {
+		return nil, nil
+	}
+	if c.SASLUsername == "" || c.SASLPassword == "" {
+		return nil, errors.New("KAFKA_SASL_USERNAME and KAFKA_SASL_PASSWORD are required with KAFKA_SASL_MECHANISM")
+	}
+
+	switch c.SASLMechanism {
+	case "plain":
+		return plain.Mechanism{Username: c.SASLUsername, Password: c.SASLPassword}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, c.SASLUsername, c.SASLPassword)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, c.SASLUsername, c.SASLPassword)
+	default:
+		return nil, fmt.Errorf(
+			"KAFKA_SASL_MECHANISM %q must be plain, scram-sha-256 or scram-sha-512", c.SASLMechanism,
+		)
+	}
+}
@@ -0,0 +1,151 @@
+package kafka_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/kafka"
+)
+
+func validConfig() kafka.Config {
+	return kafka.Config{
+		Broker:          "localhost:9092",
+		Topic:           "companies",
+		CloudEventsMode: kafka.ModeBinary,
+		Compression:     "none",
+		BatchSize:       100,
+		BatchBytes:      1 << 20,
+		BatchTimeout:    10 * time.Millisecond,
+		Balancer:        "hash",
+		RequiredAcks:    "all",
+	}
+}
+
+// writeCertificate writes a self-signed certificate and its key as PEM files in dir.
+func writeCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kafka"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+	return certFile, keyFile
+}
+
+func TestConfig_Validate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir)
+	garbage := filepath.Join(dir, "garbage.pem")
+	require.NoError(t, os.WriteFile(garbage, []byte("not a certificate"), 0o600))
+
+	tests := []struct {
+		name        string
+		modify      func(c *kafka.Config)
+		expectedErr bool
+	}{
+		{name: "defaults", modify: func(*kafka.Config) {}},
+		{
+			name: "tuned",
+			modify: func(c *kafka.Config) {
+				c.Compression, c.Balancer, c.RequiredAcks, c.Async = "zstd", "murmur2", "one", true
+			},
+		},
+		{
+			name: "tls and scram",
+			modify: func(c *kafka.Config) {
+				c.TLSEnabled, c.TLSCAFile, c.TLSCertFile, c.TLSKeyFile = true, certFile, certFile, keyFile
+				c.SASLMechanism, c.SASLUsername, c.SASLPassword = "scram-sha-512", "user", "secret"
+			},
+		},
+		{name: "unknown compression", modify: func(c *kafka.Config) { c.Compression = "brotli" }, expectedErr: true},
+		{name: "unknown balancer", modify: func(c *kafka.Config) { c.Balancer = "random" }, expectedErr: true},
+		{name: "unknown acks", modify: func(c *kafka.Config) { c.RequiredAcks = "two" }, expectedErr: true},
+		{name: "empty batches", modify: func(c *kafka.Config) { c.BatchSize = 0 }, expectedErr: true},
+		{name: "unknown content mode", modify: func(c *kafka.Config) { c.CloudEventsMode = "x" }, expectedErr: true},
+		{
+			name:        "retention without sweeps",
+			modify:      func(c *kafka.Config) { c.OutboxRetention = time.Hour },
+			expectedErr: true,
+		},
+		{
+			name:        "tls files without tls",
+			modify:      func(c *kafka.Config) { c.TLSCAFile = certFile },
+			expectedErr: true,
+		},
+		{
+			name:        "certificate without key",
+			modify:      func(c *kafka.Config) { c.TLSEnabled, c.TLSCertFile = true, certFile },
+			expectedErr: true,
+		},
+		{
+			name:        "invalid ca",
+			modify:      func(c *kafka.Config) { c.TLSEnabled, c.TLSCAFile = true, garbage },
+			expectedErr: true,
+		},
+		{
+			name:        "missing ca",
+			modify:      func(c *kafka.Config) { c.TLSEnabled, c.TLSCAFile = true, filepath.Join(dir, "missing.pem") },
+			expectedErr: true,
+		},
+		{
+			name:        "sasl without password",
+			modify:      func(c *kafka.Config) { c.SASLMechanism, c.SASLUsername = "plain", "user" },
+			expectedErr: true,
+		},
+		{
+			name: "unknown sasl mechanism",
+			modify: func(c *kafka.Config) {
+				c.SASLMechanism, c.SASLUsername, c.SASLPassword = "gssapi", "user", "secret"
+			},
+			expectedErr: true,
+		},
+		{
+			name: "dead-letter topic is the command topic",
+			modify: func(c *kafka.Config) {
+				c.CommandTopic, c.DeadLetterTopic = "commands", "commands"
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(&cfg)
+
+			err := cfg.Validate()
+			if tt.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
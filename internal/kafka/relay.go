@@ -0,0 +1,193 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/backoff"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+const (
+	relayBaseBackoff = time.Second
+	relayMaxBackoff  = 5 * time.Minute
+	// relaySweepBatch is the number of published events deleted per statement by Sweep.
+	relaySweepBatch = 1000
+)
+
+// OutboxStore is the storage the relay drains events from.
+type OutboxStore interface {
+	Lock(ctx context.Context) (release func(), acquired bool, err error)
+	Pending(ctx context.Context, limit uint64) ([]repository.OutboxEvent, error)
+	MarkPublished(ctx context.Context, ids ...int64) error
+	MarkFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error
+	DeletePublished(ctx context.Context, before time.Time, limit uint64) (int64, error)
+}
+
+// Relay publishes the events committed to the outbox table to Kafka.
+// Events sharing a key are published in the order they were written; when one
+// fails, the later events for that key wait until it has been retried successfully.
+// Each batch of pending events is sent with a single PublishBatch call, laid out
+// in the configured CloudEvents content mode. Published events are deleted once
+// they are older than the retention.
+type Relay struct {
+	store         OutboxStore
+	producer      ProducerInterface
+	log           *zap.Logger
+	pollInterval  time.Duration
+	batchSize     uint64
+	mode          string
+	retention     time.Duration
+	sweepInterval time.Duration
+	now           func() time.Time
+}
+
+// NewRelay creates an outbox relay publishing through producer.
+func NewRelay(cfg *Config, store OutboxStore, producer ProducerInterface, log *zap.Logger) *Relay {
+	return &Relay{
+		store:         store,
+		producer:      producer,
+		log:           log,
+		pollInterval:  cfg.OutboxPollInterval,
+		batchSize:     cfg.OutboxBatchSize,
+		mode:          cfg.CloudEventsMode,
+		retention:     cfg.OutboxRetention,
+		sweepInterval: cfg.OutboxSweepInterval,
+		now:           time.Now,
+	}
+}
+
+// Run polls the outbox, and sweeps it every sweep interval when a retention is set,
+// until ctx is canceled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	var sweep <-chan time.Time
+	if r.retention > 0 {
+		sweepTicker := time.NewTicker(r.sweepInterval)
+		defer sweepTicker.Stop()
+		sweep = sweepTicker.C
+	}
+
+	for {
+		if _, err := r.Drain(ctx); err != nil && ctx.Err() == nil {
+			r.log.Error("outbox relay failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-sweep:
+			r.logSweep(ctx)
+		}
+	}
+}
+
+// Sweep deletes the events published and handed to webhooks longer ago than the
+// retention, relaySweepBatch at a time, and returns how many it deleted.
+func (r *Relay) Sweep(ctx context.Context) (int64, error) {
+	before := r.now().Add(-r.retention)
+
+	var deleted int64
+	for {
+		n, err := r.store.DeletePublished(ctx, before, relaySweepBatch)
+		deleted += n
+		if err != nil || n < relaySweepBatch {
+			return deleted, err
+		}
+	}
+}
+
+// logSweep runs Sweep and logs its outcome.
+func (r *Relay) logSweep(ctx context.Context) {
+	deleted, err := r.Sweep(ctx)
+	if err != nil && ctx.Err() == nil {
+		r.log.Error("outbox sweep failed", zap.Int64("deleted", deleted), zap.Error(err))
+		return
+	}
+	if deleted > 0 {
+		r.log.Info("swept published outbox events",
+			zap.Int64("deleted", deleted),
+			zap.Duration("retention", r.retention),
+		)
+	}
+}
+
+// Drain publishes one batch of pending events and returns how many were published.
+// It does nothing when another relay instance holds the outbox lock.
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	release, acquired, err := r.store.Lock(ctx)
+	if err != nil || !acquired {
+		return 0, err
+	}
+	defer release()
+
+	events, err := r.store.Pending(ctx, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	msgs := make([]Message, len(events))
+	for i, e := range events {
+		msgs[i] = CloudEventMessage(e.Key, e.Payload, r.mode)
+	}
+	failed := failedMessages(r.producer.PublishBatch(ctx, msgs...), len(msgs))
+
+	blocked := make(map[string]bool)
+	published := make([]int64, 0, len(events))
+	for i, e := range events {
+		if blocked[e.Key] {
+			// Left pending behind the failed event of its key, even if it was delivered,
+			// so the key is never published out of order. Kafka writes the messages of a
+			// partition together, so in practice they fail together too.
+			continue
+		}
+
+		err := failed[i]
+		if err == nil {
+			published = append(published, e.ID)
+			continue
+		}
+
+		blocked[e.Key] = true
+		retryAt := r.now().Add(backoff.Exponential(relayBaseBackoff, relayMaxBackoff, e.Attempts))
+		r.log.Warn("outbox publish failed",
+			zap.Int64("event_id", e.ID),
+			zap.String("key", e.Key),
+			zap.Int("attempts", e.Attempts+1),
+			zap.Time("retry_at", retryAt),
+			zap.Error(err),
+		)
+		if err := r.store.MarkFailed(ctx, e.ID, err.Error(), retryAt); err != nil {
+			return len(published), err
+		}
+	}
+
+	return len(published), r.store.MarkPublished(ctx, published...)
+}
+
+// failedMessages returns the error of each of the n messages of a PublishBatch call.
+// Any error other than kafka.WriteErrors applies to all of them.
+func failedMessages(err error, n int) []error {
+	failed := make([]error, n)
+	if err == nil {
+		return failed
+	}
+
+	var writeErrs kafka.WriteErrors
+	if errors.As(err, &writeErrs) && len(writeErrs) == n {
+		copy(failed, writeErrs)
+		return failed
+	}
+
+	for i := range failed {
+		failed[i] = err
+	}
+	return failed
+}
@@ -0,0 +1,213 @@
+package kafka_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	segkafka "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/kafka"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+type mockOutbox struct {
+	locked    bool
+	pending   []repository.OutboxEvent
+	published []int64
+	failed    []int64
+	// sweepable is the number of events DeletePublished deletes, sweptBefore its last cutoff.
+	sweepable   int64
+	sweeps      int
+	sweptBefore time.Time
+}
+
+func (m *mockOutbox) Lock(_ context.Context) (func(), bool, error) {
+	if m.locked {
+		return nil, false, nil
+	}
+	return func() {}, true, nil
+}
+
+func (m *mockOutbox) Pending(_ context.Context, _ uint64) ([]repository.OutboxEvent, error) {
+	return m.pending, nil
+}
+
+func (m *mockOutbox) MarkPublished(_ context.Context, ids ...int64) error {
+	m.published = append(m.published, ids...)
+	return nil
+}
+
+func (m *mockOutbox) MarkFailed(_ context.Context, id int64, _ string, _ time.Time) error {
+	m.failed = append(m.failed, id)
+	return nil
+}
+
+func (m *mockOutbox) DeletePublished(_ context.Context, before time.Time, limit uint64) (int64, error) {
+	m.sweeps++
+	m.sweptBefore = before
+	n := min(m.sweepable, int64(limit))
+	m.sweepable -= n
+	return n, nil
+}
+
+type recordingProducer struct {
+	failKey  string
+	sent     []string
+	messages []kafka.Message
+	batches  int
+}
+
+func (p *recordingProducer) Publish(_ context.Context, key string, value any) error {
+	if key == p.failKey {
+		return errors.New("broker unavailable")
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	p.sent = append(p.sent, key+":"+string(data))
+	return nil
+}
+
+func (p *recordingProducer) PublishBatch(ctx context.Context, msgs ...kafka.Message) error {
+	p.batches++
+	p.messages = append(p.messages, msgs...)
+
+	var errs segkafka.WriteErrors
+	for i, m := range msgs {
+		if err := p.Publish(ctx, m.Key, m.Value); err != nil {
+			if errs == nil {
+				errs = make(segkafka.WriteErrors, len(msgs))
+			}
+			errs[i] = err
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+func (p *recordingProducer) Close() error { return nil }
+
+func TestRelay_Drain(t *testing.T) {
+	store := &mockOutbox{pending: []repository.OutboxEvent{
+		{ID: 1, Key: "a", Payload: []byte(`{"n":1}`)},
+		{ID: 2, Key: "b", Payload: []byte(`{"n":2}`)},
+		{ID: 3, Key: "a", Payload: []byte(`{"n":3}`)},
+		{ID: 4, Key: "b", Payload: []byte(`{"n":4}`)},
+	}}
+	producer := &recordingProducer{failKey: "b"}
+
+	relay := kafka.NewRelay(&kafka.Config{OutboxBatchSize: 10}, store, producer, zap.NewNop())
+
+	n, err := relay.Drain(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, 1, producer.batches)
+	require.Equal(t, []string{`a:{"n":1}`, `a:{"n":3}`}, producer.sent)
+	require.Equal(t, []int64{1, 3}, store.published)
+	// Event 4 must wait for event 2, which shares its key, to be retried first.
+	require.Equal(t, []int64{2}, store.failed)
+}
+
+func TestRelay_Drain_LockHeldElsewhere(t *testing.T) {
+	store := &mockOutbox{
+		locked:  true,
+		pending: []repository.OutboxEvent{{ID: 1, Key: "a", Payload: []byte(`{}`)}},
+	}
+	producer := &recordingProducer{}
+
+	relay := kafka.NewRelay(&kafka.Config{OutboxBatchSize: 10}, store, producer, zap.NewNop())
+
+	n, err := relay.Drain(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, n)
+	require.Empty(t, producer.sent)
+}
+
+func TestRelay_Drain_CloudEvents(t *testing.T) {
+	event := `{"specversion":"1.0","id":"e-1","source":"/companies","type":"com.example.company.created.v1",` +
+		`"subject":"c-1","time":"2026-01-02T03:04:05Z","datacontenttype":"application/json",` +
+		`"dataschema":"http://localhost/s.json","data":{"action":"created"}}`
+
+	tests := []struct {
+		name            string
+		mode            string
+		payload         string
+		expectedValue   string
+		expectedHeaders map[string]string
+	}{
+		{
+			name:          "binary",
+			mode:          kafka.ModeBinary,
+			payload:       event,
+			expectedValue: `{"action":"created"}`,
+			expectedHeaders: map[string]string{
+				"ce_specversion": "1.0",
+				"ce_id":          "e-1",
+				"ce_source":      "/companies",
+				"ce_type":        "com.example.company.created.v1",
+				"ce_subject":     "c-1",
+				"ce_time":        "2026-01-02T03:04:05Z",
+				"ce_dataschema":  "http://localhost/s.json",
+				"content-type":   "application/json",
+			},
+		},
+		{
+			name:            "structured",
+			mode:            kafka.ModeStructured,
+			payload:         event,
+			expectedValue:   event,
+			expectedHeaders: map[string]string{"content-type": "application/cloudevents+json"},
+		},
+		{
+			name:            "not a cloudevent",
+			mode:            kafka.ModeBinary,
+			payload:         `{"action":"created"}`,
+			expectedValue:   `{"action":"created"}`,
+			expectedHeaders: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &mockOutbox{pending: []repository.OutboxEvent{{ID: 1, Key: "c-1", Payload: []byte(tt.payload)}}}
+			producer := &recordingProducer{}
+
+			cfg := &kafka.Config{OutboxBatchSize: 10, CloudEventsMode: tt.mode}
+			_, err := kafka.NewRelay(cfg, store, producer, zap.NewNop()).Drain(context.Background())
+			require.NoError(t, err)
+			require.Len(t, producer.messages, 1)
+
+			msg := producer.messages[0]
+			value, err := json.Marshal(msg.Value)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expectedValue, string(value))
+
+			headers := make(map[string]string, len(msg.Headers))
+			for _, h := range msg.Headers {
+				headers[h.Key] = string(h.Value)
+			}
+			require.Equal(t, tt.expectedHeaders, headers)
+		})
+	}
+}
+
+func TestRelay_Sweep(t *testing.T) {
+	store := &mockOutbox{sweepable: 2500}
+	cfg := &kafka.Config{OutboxRetention: 24 * time.Hour, OutboxSweepInterval: time.Hour}
+
+	deleted, err := kafka.NewRelay(cfg, store, &recordingProducer{}, zap.NewNop()).Sweep(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 2500, deleted)
+	// Deleted in batches of 1000, until one comes back short.
+	require.Equal(t, 3, store.sweeps)
+	require.WithinDuration(t, time.Now().Add(-24*time.Hour), store.sweptBefore, time.Minute)
+}
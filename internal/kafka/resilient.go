@@ -0,0 +1,156 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+
+	"github.com/dagherghinescu/companies/internal/backoff"
+)
+
+// ErrKeyBlocked is the error of a message sent after an earlier message of its key
+// failed. It is reported as failed too, even if it was delivered, so that the caller
+// sends it again behind that message and its key reaches Kafka in order.
+var ErrKeyBlocked = errors.New("an earlier message of the key was not sent")
+
+// ProducerStats are the counters of a ResilientProducer, published as metrics.
+type ProducerStats struct {
+	Published    int64  `json:"published"`
+	Retries      int64  `json:"retries"`
+	Failures     int64  `json:"failures"`
+	BreakerState string `json:"breaker_state"`
+}
+
+// ResilientProducer wraps a producer with retries and a circuit breaker. Failed
+// messages are retried with jittered exponential backoff; once the breaker opens,
+// publishes fail right away instead of waiting on Kafka. Messages that still could
+// not be sent are reported as failed, and a message that fails takes the later
+// messages of its key with it, so that its publisher can send them again in order.
+type ResilientProducer struct {
+	inner       ProducerInterface
+	breaker     *Breaker
+	retries     int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+
+	published atomic.Int64
+	retried   atomic.Int64
+	failures  atomic.Int64
+}
+
+// NewResilientProducer wraps inner.
+func NewResilientProducer(cfg *Config, inner ProducerInterface) *ResilientProducer {
+	return &ResilientProducer{
+		inner:       inner,
+		breaker:     NewBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+		retries:     max(cfg.PublishRetries, 0),
+		baseBackoff: cfg.PublishRetryBackoff,
+		maxBackoff:  cfg.PublishRetryMaxBackoff,
+	}
+}
+
+// Publish sends a single message, like PublishBatch.
+func (p *ResilientProducer) Publish(ctx context.Context, key string, value any) error {
+	return p.PublishBatch(ctx, Message{Key: key, Value: value})
+}
+
+// PublishBatch sends msgs. The error of each message that could not be sent is
+// returned as a kafka.WriteErrors.
+func (p *ResilientProducer) PublishBatch(ctx context.Context, msgs ...Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	if errs := p.send(ctx, msgs); errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// Stats returns the counters of the producer.
+func (p *ResilientProducer) Stats() ProducerStats {
+	return ProducerStats{
+		Published:    p.published.Load(),
+		Retries:      p.retried.Load(),
+		Failures:     p.failures.Load(),
+		BreakerState: p.breaker.State(),
+	}
+}
+
+// Close closes the wrapped producer.
+func (p *ResilientProducer) Close() error {
+	return p.inner.Close()
+}
+
+// send publishes msgs, retrying the ones that failed, and returns the error of each
+// message as a kafka.WriteErrors, or nil if all were sent.
+func (p *ResilientProducer) send(ctx context.Context, msgs []Message) kafka.WriteErrors {
+	errs := make(kafka.WriteErrors, len(msgs))
+	pending := make([]int, len(msgs))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	for attempt := 0; ; attempt++ {
+		pending = p.attempt(ctx, msgs, pending, errs)
+		if len(pending) == 0 {
+			return nil
+		}
+		if attempt >= p.retries || errors.Is(errs[pending[0]], ErrCircuitOpen) || !sleep(ctx, p.backoff(attempt)) {
+			p.failures.Add(int64(len(pending)))
+			return errs
+		}
+		p.retried.Add(int64(len(pending)))
+	}
+}
+
+// attempt publishes the messages of msgs at the indexes in pending, records their errors
+// in errs and returns the indexes of the ones that failed.
+func (p *ResilientProducer) attempt(ctx context.Context, msgs []Message, pending []int, errs []error) []int {
+	if err := p.breaker.Allow(); err != nil {
+		for _, i := range pending {
+			errs[i] = err
+		}
+		return pending
+	}
+
+	batch := make([]Message, len(pending))
+	for j, i := range pending {
+		batch[j] = msgs[i]
+	}
+	err := p.inner.PublishBatch(ctx, batch...)
+	p.breaker.Record(err)
+
+	var failed []int
+	blocked := make(map[string]bool)
+	for j, batchErr := range failedMessages(err, len(batch)) {
+		i := pending[j]
+		switch {
+		case batchErr != nil:
+			blocked[msgs[i].Key] = true
+		case blocked[msgs[i].Key]:
+			// Delivered ahead of a failed message of its key: it is sent again behind it.
+			batchErr = ErrKeyBlocked
+		}
+
+		errs[i] = batchErr
+		if batchErr != nil {
+			failed = append(failed, i)
+		}
+	}
+	p.published.Add(int64(len(batch) - len(failed)))
+	return failed
+}
+
+// backoff returns a random delay up to the base backoff doubled attempt times,
+// capped at the max backoff.
+func (p *ResilientProducer) backoff(attempt int) time.Duration {
+	d := backoff.Exponential(p.baseBackoff, p.maxBackoff, attempt)
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d) + 1
+}
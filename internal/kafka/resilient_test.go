@@ -0,0 +1,150 @@
+package kafka_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	segkafka "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/kafka"
+)
+
+// flakyProducer fails while down, and the first failures calls after that. Messages
+// of failKey, or with the value failValue, always fail.
+type flakyProducer struct {
+	down      bool
+	failures  int
+	failKey   string
+	failValue any
+	calls     int
+	sent      []string
+}
+
+func (p *flakyProducer) Publish(ctx context.Context, key string, value any) error {
+	return p.PublishBatch(ctx, kafka.Message{Key: key, Value: value})
+}
+
+func (p *flakyProducer) PublishBatch(_ context.Context, msgs ...kafka.Message) error {
+	p.calls++
+	if p.down || p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+
+	var errs segkafka.WriteErrors
+	for i, m := range msgs {
+		if m.Key == p.failKey || (p.failValue != nil && m.Value == p.failValue) {
+			if errs == nil {
+				errs = make(segkafka.WriteErrors, len(msgs))
+			}
+			errs[i] = errors.New("partition unavailable")
+			continue
+		}
+		data, err := json.Marshal(m.Value)
+		if err != nil {
+			return err
+		}
+		p.sent = append(p.sent, m.Key+":"+string(data))
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+func (p *flakyProducer) Close() error { return nil }
+
+func resilientConfig() *kafka.Config {
+	return &kafka.Config{
+		PublishRetries:         2,
+		PublishRetryBackoff:    time.Millisecond,
+		PublishRetryMaxBackoff: 2 * time.Millisecond,
+		BreakerThreshold:       2,
+		BreakerCooldown:        time.Hour,
+	}
+}
+
+func TestResilientProducer_Retries(t *testing.T) {
+	tests := []struct {
+		name          string
+		inner         *flakyProducer
+		expectedErr   bool
+		expectedSent  []string
+		expectedCalls int
+	}{
+		{
+			name:          "recovers within the retries",
+			inner:         &flakyProducer{failures: 1},
+			expectedSent:  []string{`a:1`, `b:2`},
+			expectedCalls: 2,
+		},
+		{
+			name:          "retries only the failed messages",
+			inner:         &flakyProducer{failKey: "b"},
+			expectedErr:   true,
+			expectedSent:  []string{`a:1`},
+			expectedCalls: 2, // Partial failures count too, so the breaker opens after two.
+		},
+		{
+			name:          "gives up after the retries",
+			inner:         &flakyProducer{down: true},
+			expectedErr:   true,
+			expectedCalls: 2, // The breaker opens after two failures.
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := kafka.NewResilientProducer(resilientConfig(), tt.inner)
+
+			msgs := []kafka.Message{{Key: "a", Value: 1}, {Key: "b", Value: 2}}
+			err := p.PublishBatch(context.Background(), msgs...)
+			require.Equal(t, tt.expectedErr, err != nil, err)
+			require.Equal(t, tt.expectedSent, tt.inner.sent)
+			require.Equal(t, tt.expectedCalls, tt.inner.calls)
+
+			if tt.expectedErr {
+				var writeErrs segkafka.WriteErrors
+				require.ErrorAs(t, err, &writeErrs)
+				require.Len(t, writeErrs, 2)
+				require.Error(t, writeErrs[1])
+			}
+		})
+	}
+}
+
+func TestResilientProducer_BreakerOpens(t *testing.T) {
+	inner := &flakyProducer{down: true}
+	p := kafka.NewResilientProducer(resilientConfig(), inner)
+
+	require.Error(t, p.Publish(context.Background(), "a", 1))
+	require.Equal(t, kafka.BreakerOpen, p.Stats().BreakerState)
+
+	// Kafka is no longer called until the cooldown is over.
+	calls := inner.calls
+	err := p.Publish(context.Background(), "a", 1)
+	var writeErrs segkafka.WriteErrors
+	require.ErrorAs(t, err, &writeErrs)
+	require.ErrorIs(t, writeErrs[0], kafka.ErrCircuitOpen)
+	require.Equal(t, calls, inner.calls)
+}
+
+func TestResilientProducer_KeyOrder(t *testing.T) {
+	cfg := resilientConfig()
+	cfg.PublishRetries = 0
+	inner := &flakyProducer{failValue: 1}
+	p := kafka.NewResilientProducer(cfg, inner)
+
+	// a:3 is delivered but, since a:1 failed, it is reported as failed to be sent again behind a:1.
+	msgs := []kafka.Message{{Key: "a", Value: 1}, {Key: "b", Value: 2}, {Key: "a", Value: 3}}
+	var writeErrs segkafka.WriteErrors
+	require.ErrorAs(t, p.PublishBatch(context.Background(), msgs...), &writeErrs)
+	require.Error(t, writeErrs[0])
+	require.NoError(t, writeErrs[1])
+	require.ErrorIs(t, writeErrs[2], kafka.ErrKeyBlocked)
+	require.EqualValues(t, 2, p.Stats().Failures)
+}
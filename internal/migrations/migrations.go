@@ -0,0 +1,266 @@
+// Package migrations applies the versioned SQL schema embedded in the binary.
+//
+// Each migration is a pair of files in the sql directory named
+// NNNN_description.up.sql and NNNN_description.down.sql. Applied versions are
+// recorded in the schema_migrations table and every migration runs in its own
+// transaction. A Postgres advisory lock serializes concurrent runners, so
+// several instances can start at once without applying a migration twice.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockID is the Postgres advisory lock key held while migrating.
+const lockID = 7_301_245_000
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// ErrUnknownVersion is returned when the database is at a version none of the embedded
+// migrations has, e.g. after rolling back to an older binary, so migrating it would be unsafe.
+var ErrUnknownVersion = errors.New("database is at a version unknown to this binary")
+
+// Migration is a single schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrator runs migrations against a database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New creates a Migrator for the migrations embedded in the binary.
+func New(db *sql.DB) (*Migrator, error) {
+	return NewFromFS(db, files)
+}
+
+// NewFromFS creates a Migrator for the migrations found in the sql directory of fsys.
+func NewFromFS(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// parseFileName splits a migration file name of the form NNNN_name.up.sql or NNNN_name.down.sql.
+func parseFileName(base string) (version int, name, direction string, err error) {
+	switch {
+	case strings.HasSuffix(base, ".up.sql"):
+		direction = "up"
+	case strings.HasSuffix(base, ".down.sql"):
+		direction = "down"
+	default:
+		return 0, "", "", fmt.Errorf("migration %s: expected .up.sql or .down.sql suffix", base)
+	}
+
+	prefix, name, ok := strings.Cut(strings.TrimSuffix(base, "."+direction+".sql"), "_")
+	if !ok {
+		return 0, "", "", fmt.Errorf("migration %s: expected NNNN_name prefix", base)
+	}
+	version, err = strconv.Atoi(prefix)
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("migration %s: invalid version %q", base, prefix)
+	}
+
+	return version, name, direction, nil
+}
+
+// Load reads the migrations from fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.Glob(fsys, "sql/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, p := range entries {
+		version, name, direction, err := parseFileName(path.Base(p))
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d: missing up script", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies all pending migrations and returns how many were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		current, err := version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.checkKnown(current); err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if mig.Version <= current {
+				continue
+			}
+			err := inTx(ctx, conn, mig.Up,
+				"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+				mig.Version, mig.Name, time.Now().UTC())
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s up: %w", mig.Version, mig.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations and returns how many were reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		current, err := version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.checkKnown(current); err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			mig := m.migrations[i]
+			if mig.Version > current {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %04d_%s is irreversible", mig.Version, mig.Name)
+			}
+			err := inTx(ctx, conn, mig.Down, "DELETE FROM schema_migrations WHERE version = $1", mig.Version)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s down: %w", mig.Version, mig.Name, err)
+			}
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Version returns the latest applied migration version, or 0 for an empty database.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	var v int
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		var err error
+		v, err = version(ctx, conn)
+		return err
+	})
+	return v, err
+}
+
+// locked runs fn on a dedicated connection holding the migration advisory lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+	}()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// checkKnown refuses to run when the database is ahead of the embedded migrations,
+// e.g. after rolling back to an older binary.
+func (m *Migrator) checkKnown(current int) error {
+	if current == 0 {
+		return nil
+	}
+	for _, mig := range m.migrations {
+		if mig.Version == current {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %d", ErrUnknownVersion, current)
+}
+
+func version(ctx context.Context, conn *sql.Conn) (int, error) {
+	var v int
+	err := conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&v)
+	return v, err
+}
+
+// inTx runs script followed by the bookkeeping statement in a single transaction.
+func inTx(ctx context.Context, conn *sql.Conn, script, bookkeeping string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,144 @@
+package migrations_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/migrations"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"sql/0002_add_index.up.sql":      {Data: []byte("CREATE INDEX i ON t (c);")},
+		"sql/0002_add_index.down.sql":    {Data: []byte("DROP INDEX i;")},
+		"sql/0001_create_table.up.sql":   {Data: []byte("CREATE TABLE t (c INT);")},
+		"sql/0001_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+	}
+}
+
+func TestLoad(t *testing.T) {
+	got, err := migrations.Load(testFS())
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, 1, got[0].Version)
+	require.Equal(t, "create_table", got[0].Name)
+	require.Equal(t, "DROP TABLE t;", got[0].Down)
+	require.Equal(t, 2, got[1].Version)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "missing up script",
+			fsys: fstest.MapFS{"sql/0001_create_table.down.sql": {Data: []byte("DROP TABLE t;")}},
+		},
+		{
+			name: "bad version",
+			fsys: fstest.MapFS{"sql/first_create_table.up.sql": {Data: []byte("CREATE TABLE t (c INT);")}},
+		},
+		{
+			name: "bad suffix",
+			fsys: fstest.MapFS{"sql/0001_create_table.sql": {Data: []byte("CREATE TABLE t (c INT);")}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := migrations.Load(tt.fsys)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = migrations.New(db)
+	require.NoError(t, err)
+}
+
+func TestMigrator_Up(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := migrations.NewFromFS(db, testFS())
+	require.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(MAX(version), 0) FROM schema_migrations")).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+
+	// Only migrations newer than the recorded version are applied.
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE INDEX i ON t (c);")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations")).
+		WithArgs(2, "add_index", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := m.Up(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, applied)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Down(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := migrations.NewFromFS(db, testFS())
+	require.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(MAX(version), 0) FROM schema_migrations")).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DROP INDEX i;")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version = $1")).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	reverted, err := m.Down(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, reverted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Up_UnknownVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := migrations.NewFromFS(db, testFS())
+	require.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(MAX(version), 0) FROM schema_migrations")).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err = m.Up(context.Background())
+	require.ErrorIs(t, err, migrations.ErrUnknownVersion)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS companies_description_trgm_idx;
+DROP INDEX IF EXISTS companies_name_trgm_idx;
+DROP INDEX IF EXISTS companies_search_vector_idx;
+
+ALTER TABLE companies DROP COLUMN IF EXISTS search_vector;
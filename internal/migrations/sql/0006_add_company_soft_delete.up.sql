@@ -0,0 +1,9 @@
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS deleted_by TEXT;
+
+-- Names only need to be unique among companies that are not deleted,
+-- otherwise a soft-deleted company would block its name forever.
+ALTER TABLE companies DROP CONSTRAINT IF EXISTS companies_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS companies_name_active_idx ON companies (name) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS companies_deleted_at_idx ON companies (deleted_at) WHERE deleted_at IS NOT NULL;
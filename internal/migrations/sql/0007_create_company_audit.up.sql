@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS company_audit (
+    id BIGSERIAL PRIMARY KEY,
+    company_id UUID NOT NULL,
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS company_audit_company_idx ON company_audit (company_id, id);
+
+CREATE OR REPLACE FUNCTION company_audit_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'company_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS company_audit_append_only ON company_audit;
+CREATE TRIGGER company_audit_append_only
+    BEFORE UPDATE OR DELETE ON company_audit
+    FOR EACH ROW EXECUTE FUNCTION company_audit_append_only();
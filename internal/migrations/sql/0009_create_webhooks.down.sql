@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS outbox_webhooks_pending_idx;
+ALTER TABLE outbox DROP COLUMN IF EXISTS webhooks_enqueued_at;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
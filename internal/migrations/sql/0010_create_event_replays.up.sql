@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS event_replays (
+    id UUID PRIMARY KEY,
+    company_type TEXT,
+    company_ids UUID[] NOT NULL DEFAULT '{}',
+    status TEXT NOT NULL DEFAULT 'running',
+    published BIGINT NOT NULL DEFAULT 0,
+    last_company_id UUID,
+    last_error TEXT,
+    requested_by TEXT NOT NULL,
+    -- The runner locked_by holds a replay until locked_until, and extends it with every
+    -- batch. Once it lapses, another runner takes the replay over from last_company_id.
+    locked_by UUID,
+    locked_until TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS event_replays_running_idx ON event_replays (created_at) WHERE status = 'running';
@@ -0,0 +1,5 @@
+CREATE OR REPLACE FUNCTION company_audit_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'company_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,12 @@
+-- The audit trail stays append-only, except that purging a company clears the
+-- snapshots of its entries: an update may only set before and after to NULL.
+CREATE OR REPLACE FUNCTION company_audit_append_only() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.before IS NULL AND NEW.after IS NULL
+        AND (NEW.id, NEW.company_id, NEW.actor, NEW.action, NEW.created_at)
+            IS NOT DISTINCT FROM (OLD.id, OLD.company_id, OLD.actor, OLD.action, OLD.created_at) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'company_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
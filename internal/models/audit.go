@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditAction is the kind of change recorded in the audit trail.
+type AuditAction string
+
+const (
+	ActionCreated  AuditAction = "created"
+	ActionUpdated  AuditAction = "updated"
+	ActionDeleted  AuditAction = "deleted"
+	ActionRestored AuditAction = "restored"
+	ActionPurged   AuditAction = "purged"
+)
+
+// Valid reports whether a is one of the known actions.
+func (a AuditAction) Valid() bool {
+	switch a {
+	case ActionCreated, ActionUpdated, ActionDeleted, ActionRestored, ActionPurged:
+		return true
+	default:
+		return false
+	}
+}
+
+// AuditEntry records a single change to a company.
+// Before and After are snapshots of the company around the change; Before is nil
+// for creations and After is nil when the company stopped being visible.
+type AuditEntry struct {
+	ID        int64       `json:"id"`
+	CompanyID uuid.UUID   `json:"company_id"`
+	Actor     string      `json:"actor"`
+	Action    AuditAction `json:"action"`
+	Before    *Company    `json:"before"`
+	After     *Company    `json:"after"`
+	CreatedAt time.Time   `json:"created_at"`
+}
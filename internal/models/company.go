@@ -1,9 +1,24 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
 	"github.com/google/uuid"
 )
 
+const (
+	// MaxNameLength is the maximum length of a company name, in characters.
+	MaxNameLength = 15
+	// MaxDescriptionLength is the maximum length of a company description, in characters.
+	MaxDescriptionLength = 3000
+)
+
+// ErrInvalidCompany is matched by the errors returned from Validate and ValidatePatch.
+var ErrInvalidCompany = errors.New("invalid company")
+
 // CompanyType defines allowed company types
 type CompanyType string
 
@@ -14,6 +29,16 @@ const (
 	SoleProprietorship CompanyType = "SoleProprietorship"
 )
 
+// Valid reports whether t is one of the known company types.
+func (t CompanyType) Valid() bool {
+	switch t {
+	case Corporation, NonProfit, Cooperative, SoleProprietorship:
+		return true
+	default:
+		return false
+	}
+}
+
 // Company represents a company entity
 type Company struct {
 	ID              uuid.UUID    `json:"id" db:"id"`
@@ -22,4 +47,122 @@ type Company struct {
 	AmountEmployees *int         `json:"amount_of_employees" db:"amount_of_employees"`
 	Registered      *bool        `json:"registered" db:"registered"`
 	Type            *CompanyType `json:"type" db:"type"`
+	// Version is incremented on every change and used as the ETag for optimistic concurrency.
+	Version int64 `json:"version" db:"version"`
+}
+
+// FieldError describes why a field is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a company.
+// It matches ErrInvalidCompany with errors.Is.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return ErrInvalidCompany.Error() + ": " + JoinFieldErrors(e.Fields)
+}
+
+// JoinFieldErrors describes fields in one line, e.g. "name is required; type is not valid".
+func JoinFieldErrors(fields []FieldError) string {
+	reasons := make([]string, len(fields))
+	for i, f := range fields {
+		reasons[i] = f.Field + " " + f.Message
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// Is reports whether target is ErrInvalidCompany.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidCompany
+}
+
+// Validate checks that c can be created: all required fields are set and every field is valid.
+func (c *Company) Validate() error {
+	var fields []FieldError
+	if c.Name == nil {
+		fields = append(fields, FieldError{Field: "name", Message: "is required"})
+	}
+	if c.AmountEmployees == nil {
+		fields = append(fields, FieldError{Field: "amount_of_employees", Message: "is required"})
+	}
+	if c.Registered == nil {
+		fields = append(fields, FieldError{Field: "registered", Message: "is required"})
+	}
+	if c.Type == nil {
+		fields = append(fields, FieldError{Field: "type", Message: "is required"})
+	}
+
+	return validationError(append(fields, c.patchErrors()...))
+}
+
+// PatchFields returns the fields set in c by column name, as used for a partial update.
+func (c *Company) PatchFields() map[string]interface{} {
+	updates := make(map[string]interface{})
+
+	if c.Name != nil {
+		updates["name"] = c.Name
+	}
+	if c.Description != nil {
+		updates["description"] = c.Description
+	}
+	if c.AmountEmployees != nil {
+		updates["amount_of_employees"] = c.AmountEmployees
+	}
+	if c.Registered != nil {
+		updates["registered"] = c.Registered
+	}
+	if c.Type != nil {
+		updates["type"] = c.Type
+	}
+
+	return updates
+}
+
+// ValidatePatch checks the fields set in c, as used for a partial update.
+func (c *Company) ValidatePatch() error {
+	return validationError(c.patchErrors())
+}
+
+func (c *Company) patchErrors() []FieldError {
+	var fields []FieldError
+	if c.Name != nil {
+		fields = append(fields, nameErrors(*c.Name)...)
+	}
+	if c.Description != nil && utf8.RuneCountInString(*c.Description) > MaxDescriptionLength {
+		fields = append(fields, FieldError{
+			Field: "description", Message: fmt.Sprintf("must be at most %d characters", MaxDescriptionLength),
+		})
+	}
+	if c.AmountEmployees != nil && *c.AmountEmployees < 0 {
+		fields = append(fields, FieldError{Field: "amount_of_employees", Message: "must not be negative"})
+	}
+	if c.Type != nil && !c.Type.Valid() {
+		fields = append(fields, FieldError{Field: "type", Message: fmt.Sprintf("must be one of %s, %s, %s, %s",
+			Corporation, NonProfit, Cooperative, SoleProprietorship)})
+	}
+
+	return fields
+}
+
+func nameErrors(name string) []FieldError {
+	switch {
+	case name == "":
+		return []FieldError{{Field: "name", Message: "must not be empty"}}
+	case utf8.RuneCountInString(name) > MaxNameLength:
+		return []FieldError{{Field: "name", Message: fmt.Sprintf("must be at most %d characters", MaxNameLength)}}
+	default:
+		return nil
+	}
+}
+
+func validationError(fields []FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: fields}
 }
@@ -0,0 +1,106 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+func TestCompany_Validate(t *testing.T) {
+	valid := func() models.Company {
+		name, employees, registered, ctype := "Acme", 10, true, models.Corporation
+		return models.Company{Name: &name, AmountEmployees: &employees, Registered: &registered, Type: &ctype}
+	}
+
+	tests := []struct {
+		name      string
+		modify    func(c *models.Company)
+		expectErr bool
+	}{
+		{
+			name:   "valid",
+			modify: func(_ *models.Company) {},
+		},
+		{
+			name:      "missing name",
+			modify:    func(c *models.Company) { c.Name = nil },
+			expectErr: true,
+		},
+		{
+			name: "name too long",
+			modify: func(c *models.Company) {
+				name := strings.Repeat("a", models.MaxNameLength+1)
+				c.Name = &name
+			},
+			expectErr: true,
+		},
+		{
+			name: "name of multi-byte characters",
+			modify: func(c *models.Company) {
+				name := strings.Repeat("ä", models.MaxNameLength)
+				c.Name = &name
+			},
+		},
+		{
+			name: "description too long",
+			modify: func(c *models.Company) {
+				description := strings.Repeat("a", models.MaxDescriptionLength+1)
+				c.Description = &description
+			},
+			expectErr: true,
+		},
+		{
+			name: "negative employees",
+			modify: func(c *models.Company) {
+				employees := -1
+				c.AmountEmployees = &employees
+			},
+			expectErr: true,
+		},
+		{
+			name: "unknown type",
+			modify: func(c *models.Company) {
+				ctype := models.CompanyType("Partnership")
+				c.Type = &ctype
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.modify(&c)
+
+			err := c.Validate()
+			if tt.expectErr {
+				require.ErrorIs(t, err, models.ErrInvalidCompany)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCompany_ValidatePatch(t *testing.T) {
+	employees := -1
+	require.NoError(t, (&models.Company{}).ValidatePatch())
+	require.ErrorIs(t, (&models.Company{AmountEmployees: &employees}).ValidatePatch(), models.ErrInvalidCompany)
+}
+
+func TestCompany_ValidateFields(t *testing.T) {
+	employees := -1
+	err := (&models.Company{AmountEmployees: &employees}).Validate()
+
+	var verr *models.ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, []models.FieldError{
+		{Field: "name", Message: "is required"},
+		{Field: "registered", Message: "is required"},
+		{Field: "type", Message: "is required"},
+		{Field: "amount_of_employees", Message: "must not be negative"},
+	}, verr.Fields)
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"reflect"
+	"time"
+)
+
+// CompanyDiff holds the changes made to a company between two points in time.
+type CompanyDiff struct {
+	From    time.Time     `json:"from"`
+	To      time.Time     `json:"to"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// FieldChange is the change of a single company field between two snapshots.
+// Field is the JSON name of the field; From and To are nil when the field was unset
+// or the company did not exist at that point.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// DiffCompanies lists the fields that differ between from and to, in declaration order.
+// A nil snapshot stands for a company that did not exist, so every set field of the
+// other snapshot is reported. The version is bookkeeping and is never reported.
+func DiffCompanies(from, to *Company) []FieldChange {
+	fromFields, toFields := companyFields(from), companyFields(to)
+
+	changes := make([]FieldChange, 0)
+	for i, name := range companyFieldNames() {
+		if reflect.DeepEqual(fromFields[i], toFields[i]) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: name, From: fromFields[i], To: toFields[i]})
+	}
+	return changes
+}
+
+func companyFieldNames() []string {
+	return []string{"id", "name", "description", "amount_of_employees", "registered", "type"}
+}
+
+// companyFields returns the dereferenced values of the fields named by companyFieldNames.
+func companyFields(c *Company) []any {
+	if c == nil {
+		return make([]any, len(companyFieldNames()))
+	}
+
+	return []any{c.ID.String(), deref(c.Name), deref(c.Description), deref(c.AmountEmployees), deref(c.Registered),
+		deref(c.Type)}
+}
+
+func deref[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
@@ -0,0 +1,60 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+func TestDiffCompanies(t *testing.T) {
+	id := uuid.New()
+	name := "Acme"
+	description := "Sample"
+	employees, moreEmployees := 10, 20
+	ctype := models.Corporation
+
+	before := &models.Company{ID: id, Name: &name, Description: &description, AmountEmployees: &employees,
+		Type: &ctype, Version: 1}
+	after := &models.Company{ID: id, Name: &name, AmountEmployees: &moreEmployees, Type: &ctype, Version: 2}
+
+	tests := []struct {
+		name     string
+		from, to *models.Company
+		expected []models.FieldChange
+	}{
+		{
+			name: "changed fields",
+			from: before,
+			to:   after,
+			expected: []models.FieldChange{
+				{Field: "description", From: "Sample", To: nil},
+				{Field: "amount_of_employees", From: 10, To: 20},
+			},
+		},
+		{
+			name:     "identical",
+			from:     before,
+			to:       before,
+			expected: []models.FieldChange{},
+		},
+		{
+			name: "created",
+			to:   after,
+			expected: []models.FieldChange{
+				{Field: "id", From: nil, To: id.String()},
+				{Field: "name", From: nil, To: "Acme"},
+				{Field: "amount_of_employees", From: nil, To: 20},
+				{Field: "type", From: nil, To: models.Corporation},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, models.DiffCompanies(tt.from, tt.to))
+		})
+	}
+}
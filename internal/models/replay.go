@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReplayStatus is where an event replay stands.
+type ReplayStatus string
+
+const (
+	ReplayRunning   ReplayStatus = "running"
+	ReplayPaused    ReplayStatus = "paused"
+	ReplayCompleted ReplayStatus = "completed"
+	// ReplayFailed replays stopped on an error; they can be resumed.
+	ReplayFailed ReplayStatus = "failed"
+)
+
+// Replay re-publishes a snapshot event of every company it selects, in the order of
+// their IDs, so that downstream consumers can rebuild their state.
+type Replay struct {
+	ID uuid.UUID `json:"id"`
+	// Type and CompanyIDs narrow the companies replayed; unset means every company.
+	Type       *CompanyType `json:"type,omitempty"`
+	CompanyIDs []uuid.UUID  `json:"company_ids"`
+	Status     ReplayStatus `json:"status"`
+	// Published counts the events published so far. LastCompanyID is the last company
+	// published, after which the replay resumes.
+	Published     int64      `json:"published"`
+	LastCompanyID *uuid.UUID `json:"last_company_id,omitempty"`
+	// LastError is why a failed replay stopped.
+	LastError   string     `json:"last_error,omitempty"`
+	RequestedBy string     `json:"requested_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
@@ -1,8 +1,37 @@
 package models
 
+// Role is a permission level granted to a user.
+// Roles are hierarchical: admin includes editor, which includes viewer.
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+// Includes reports whether r grants at least the permissions of other.
+func (r Role) Includes(other Role) bool {
+	return r.rank() > 0 && r.rank() >= other.rank()
+}
+
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleEditor:
+		return 2
+	case RoleAdmin:
+		return 3
+	default:
+		return 0
+	}
+}
+
 // User holds user data.
 type User struct {
 	ID       string
 	Username string
 	Password string // hashed password
+	Roles    []Role
 }
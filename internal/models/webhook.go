@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook is a partner endpoint receiving the company events over HTTP.
+type Webhook struct {
+	ID  uuid.UUID `json:"id"`
+	URL string    `json:"url"`
+	// Events limits the deliveries to events of these actions; empty means every event.
+	Events []AuditAction `json:"events"`
+	// Secret signs the deliveries. It is only returned when the webhook is created.
+	Secret string `json:"secret,omitempty"`
+	// ConsecutiveFailures counts the failed delivery attempts since the last successful one.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// DisabledAt is set once the endpoint failed too many times in a row; no events are sent to it then.
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// DeliveryState is where a webhook delivery stands.
+type DeliveryState string
+
+const (
+	DeliveryPending   DeliveryState = "pending"
+	DeliveryDelivered DeliveryState = "delivered"
+	// DeliveryFailed deliveries ran out of attempts and are not retried.
+	DeliveryFailed DeliveryState = "failed"
+)
+
+// WebhookDelivery is an event sent, or to be sent, to a webhook.
+type WebhookDelivery struct {
+	ID       int64           `json:"id"`
+	EventKey string          `json:"event_key"`
+	Payload  json.RawMessage `json:"payload"`
+	State    DeliveryState   `json:"state"`
+	Attempts int             `json:"attempts"`
+	// ResponseStatus and LastError describe the last attempt; ResponseStatus is nil when no response was received.
+	ResponseStatus *int       `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
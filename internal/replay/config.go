@@ -0,0 +1,29 @@
+package replay
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Config tunes the event replays.
+type Config struct {
+	// Rate caps the events a replay publishes per second; 0 publishes as fast as Kafka takes them.
+	Rate int `envconfig:"RATE" default:"500"`
+	// BatchSize is the number of companies published at a time. Progress is saved after each batch.
+	BatchSize uint64 `envconfig:"BATCH_SIZE" default:"100"`
+	// PollInterval is how often the runner looks for replays to publish.
+	PollInterval time.Duration `envconfig:"POLL_INTERVAL" default:"5s"`
+	// Lease is how long a replay stays with its runner without progress, after which
+	// another runner takes it over from the last company published.
+	Lease time.Duration `envconfig:"LEASE" default:"1m"`
+}
+
+// EnvConfig loads the replay configuration from environment variables
+func EnvConfig() (*Config, error) {
+	var cfg Config
+	if err := envconfig.Process("REPLAY", &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,195 @@
+// Package replay re-publishes the state of the companies to Kafka, so that downstream
+// consumers can rebuild theirs.
+package replay
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	segkafka "github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/events"
+	"github.com/dagherghinescu/companies/internal/kafka"
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+// HeaderReplay is set on every message published by a replay, to the ID of the replay,
+// so that consumers can tell snapshots apart from live events.
+const HeaderReplay = "replay"
+
+// ErrNotClaimed is returned by Publish when the replay is not running or is held by another runner.
+var ErrNotClaimed = errors.New("replay is not running or is published by another runner")
+
+// Runner publishes a company.snapshot.v1 event for every company selected by a replay,
+// in batches ordered by company ID and throttled to the configured rate. The last company
+// published is saved after every batch, so a replay interrupted by a restart or paused
+// by an admin resumes after it instead of starting over. Snapshots share the key of the
+// live events of their company, so they land on the same partition; consumers compare
+// versions to skip snapshots older than what they already applied.
+type Runner struct {
+	id        uuid.UUID
+	queue     repository.ReplayQueue
+	producer  kafka.ProducerInterface
+	cfg       *Config
+	mode      string
+	schemaURL string
+	log       *zap.Logger
+}
+
+// NewRunner creates a replay runner publishing through producer. mode is the CloudEvents
+// content mode of the messages and schemaURL the base URL of the event schemas.
+func NewRunner(
+	cfg *Config, queue repository.ReplayQueue, producer kafka.ProducerInterface, mode, schemaURL string,
+	log *zap.Logger,
+) *Runner {
+	return &Runner{
+		id:        uuid.New(),
+		queue:     queue,
+		producer:  producer,
+		cfg:       cfg,
+		mode:      mode,
+		schemaURL: schemaURL,
+		log:       log,
+	}
+}
+
+// Run publishes the running replays, one at a time and oldest first, until ctx is canceled.
+func (r *Runner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Drain(ctx); err != nil && ctx.Err() == nil {
+			r.log.Error("event replay failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain publishes running replays until none is left that another runner does not hold.
+func (r *Runner) Drain(ctx context.Context) error {
+	for {
+		rp, err := r.queue.Claim(ctx, r.id, nil, r.cfg.Lease)
+		if err != nil || rp == nil {
+			return err
+		}
+		if err := r.publish(ctx, rp); err != nil {
+			return err
+		}
+	}
+}
+
+// Publish claims the replay id and publishes it until it completes, fails, is paused
+// or ctx is canceled.
+func (r *Runner) Publish(ctx context.Context, id uuid.UUID) error {
+	rp, err := r.queue.Claim(ctx, r.id, &id, r.cfg.Lease)
+	if err != nil {
+		return err
+	}
+	if rp == nil {
+		return ErrNotClaimed
+	}
+	return r.publish(ctx, rp)
+}
+
+// publish sends the remaining batches of a claimed replay.
+func (r *Runner) publish(ctx context.Context, rp *models.Replay) error {
+	log := r.log.With(zap.Stringer("replay_id", rp.ID))
+	log.Info("publishing event replay", zap.Int64("published", rp.Published))
+
+	for {
+		companies, err := r.queue.Companies(ctx, rp, max(r.cfg.BatchSize, 1))
+		if err != nil {
+			return err
+		}
+		if len(companies) == 0 {
+			log.Info("event replay completed", zap.Int64("published", rp.Published))
+			return r.finish(ctx, rp, models.ReplayCompleted, "")
+		}
+
+		start := time.Now()
+		if err := r.publishBatch(ctx, rp, companies); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Error("event replay failed", zap.Int64("published", rp.Published), zap.Error(err))
+			return errors.Join(err, r.finish(ctx, rp, models.ReplayFailed, err.Error()))
+		}
+
+		last := companies[len(companies)-1].ID
+		err = r.queue.Advance(ctx, r.id, rp.ID, last, len(companies), r.cfg.Lease)
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Info("event replay paused or taken over", zap.Int64("published", rp.Published))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rp.LastCompanyID = &last
+		rp.Published += int64(len(companies))
+
+		if !sleep(ctx, r.pace(len(companies))-time.Since(start)) {
+			return ctx.Err()
+		}
+	}
+}
+
+// publishBatch sends a snapshot event of each company, marked as part of the replay.
+func (r *Runner) publishBatch(ctx context.Context, rp *models.Replay, companies []models.Company) error {
+	msgs := make([]kafka.Message, len(companies))
+	for i := range companies {
+		c := &companies[i]
+		payload, err := json.Marshal(events.NewSnapshotEvent(c, r.schemaURL))
+		if err != nil {
+			return err
+		}
+
+		msgs[i] = kafka.CloudEventMessage(c.ID.String(), payload, r.mode)
+		msgs[i].Headers = append(msgs[i].Headers, segkafka.Header{Key: HeaderReplay, Value: []byte(rp.ID.String())})
+	}
+	return r.producer.PublishBatch(ctx, msgs...)
+}
+
+// finish ends a replay. Losing it to a pause or another runner is not an error.
+func (r *Runner) finish(ctx context.Context, rp *models.Replay, status models.ReplayStatus, reason string) error {
+	err := r.queue.Finish(ctx, r.id, rp.ID, status, reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+// pace returns how long publishing n events should take at the configured rate.
+func (r *Runner) pace(n int) time.Duration {
+	if r.cfg.Rate <= 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second / time.Duration(r.cfg.Rate)
+}
+
+// sleep waits for d, and reports false if ctx was canceled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
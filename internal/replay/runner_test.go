@@ -0,0 +1,193 @@
+package replay_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	segkafka "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/kafka"
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/replay"
+)
+
+// memQueue holds a single replay over companies sorted by ID.
+type memQueue struct {
+	replay    models.Replay
+	companies []models.Company
+	holder    uuid.UUID
+	// pauseAfter pauses the replay, like an admin would, once that many events were recorded.
+	pauseAfter int64
+}
+
+func (q *memQueue) Claim(_ context.Context, runner uuid.UUID, id *uuid.UUID, _ time.Duration) (*models.Replay, error) {
+	if q.replay.Status != models.ReplayRunning || q.holder != uuid.Nil || (id != nil && *id != q.replay.ID) {
+		return nil, nil
+	}
+	q.holder = runner
+	rp := q.replay
+	return &rp, nil
+}
+
+func (q *memQueue) Companies(_ context.Context, rp *models.Replay, limit uint64) ([]models.Company, error) {
+	var companies []models.Company
+	for _, c := range q.companies {
+		if rp.LastCompanyID != nil && bytes.Compare(c.ID[:], rp.LastCompanyID[:]) <= 0 {
+			continue
+		}
+		if uint64(len(companies)) == limit {
+			break
+		}
+		companies = append(companies, c)
+	}
+	return companies, nil
+}
+
+func (q *memQueue) Advance(_ context.Context, runner, _, last uuid.UUID, n int, _ time.Duration) error {
+	if q.pauseAfter > 0 && q.replay.Published >= q.pauseAfter {
+		q.replay.Status, q.holder = models.ReplayPaused, uuid.Nil
+	}
+	if runner != q.holder || q.replay.Status != models.ReplayRunning {
+		return sql.ErrNoRows
+	}
+	q.replay.LastCompanyID = &last
+	q.replay.Published += int64(n)
+	return nil
+}
+
+func (q *memQueue) Finish(_ context.Context, runner, _ uuid.UUID, status models.ReplayStatus, reason string) error {
+	if runner != q.holder || q.replay.Status != models.ReplayRunning {
+		return sql.ErrNoRows
+	}
+	q.replay.Status, q.replay.LastError, q.holder = status, reason, uuid.Nil
+	return nil
+}
+
+// recordingProducer records the messages it publishes, and fails while down.
+type recordingProducer struct {
+	messages []kafka.Message
+	down     bool
+}
+
+func (p *recordingProducer) Publish(ctx context.Context, key string, value any) error {
+	return p.PublishBatch(ctx, kafka.Message{Key: key, Value: value})
+}
+
+func (p *recordingProducer) PublishBatch(_ context.Context, msgs ...kafka.Message) error {
+	if p.down {
+		return errors.New("broker unavailable")
+	}
+	p.messages = append(p.messages, msgs...)
+	return nil
+}
+
+func (p *recordingProducer) Close() error { return nil }
+
+func newQueue(n int) *memQueue {
+	q := &memQueue{replay: models.Replay{ID: uuid.New(), Status: models.ReplayRunning}}
+	for range n {
+		name := "Acme"
+		q.companies = append(q.companies, models.Company{ID: uuid.New(), Name: &name, Version: 1})
+	}
+	slices.SortFunc(q.companies, func(a, b models.Company) int { return bytes.Compare(a.ID[:], b.ID[:]) })
+	return q
+}
+
+func newRunner(q *memQueue, p kafka.ProducerInterface) *replay.Runner {
+	cfg := &replay.Config{BatchSize: 2, PollInterval: time.Second, Lease: time.Minute}
+	return replay.NewRunner(cfg, q, p, kafka.ModeBinary, "", zap.NewNop())
+}
+
+// header returns the value of the header key of m.
+func header(m kafka.Message, key string) string {
+	for _, h := range m.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func TestRunner_Publish(t *testing.T) {
+	q := newQueue(5)
+	p := &recordingProducer{}
+
+	require.NoError(t, newRunner(q, p).Publish(context.Background(), q.replay.ID))
+	require.Equal(t, models.ReplayCompleted, q.replay.Status)
+	require.EqualValues(t, 5, q.replay.Published)
+
+	require.Len(t, p.messages, 5)
+	for i, m := range p.messages {
+		require.Equal(t, q.companies[i].ID.String(), m.Key)
+		require.Equal(t, q.replay.ID.String(), header(m, replay.HeaderReplay))
+		require.Equal(t, "com.github.dagherghinescu.companies.company.snapshot.v1", header(m, "ce_type"))
+
+		var data struct {
+			CompanyID uuid.UUID      `json:"company_id"`
+			Company   models.Company `json:"company"`
+		}
+		require.NoError(t, json.Unmarshal(m.Value.(json.RawMessage), &data))
+		require.Equal(t, q.companies[i].ID, data.Company.ID)
+	}
+
+	// A completed replay is no longer claimed.
+	require.ErrorIs(t, newRunner(q, p).Publish(context.Background(), q.replay.ID), replay.ErrNotClaimed)
+}
+
+func TestRunner_Resume(t *testing.T) {
+	q := newQueue(5)
+	q.pauseAfter = 2
+	p := &recordingProducer{}
+
+	// The pause is noticed once the batch in flight was published.
+	require.NoError(t, newRunner(q, p).Drain(context.Background()))
+	require.Equal(t, models.ReplayPaused, q.replay.Status)
+	require.EqualValues(t, 2, q.replay.Published)
+	require.Len(t, p.messages, 4)
+
+	// Once resumed, the replay continues after the last company recorded, so the
+	// batch in flight is published again.
+	q.replay.Status, q.pauseAfter = models.ReplayRunning, 0
+	require.NoError(t, newRunner(q, p).Drain(context.Background()))
+	require.Equal(t, models.ReplayCompleted, q.replay.Status)
+	require.EqualValues(t, 5, q.replay.Published)
+	require.Len(t, p.messages, 7)
+	for i, m := range p.messages[4:] {
+		require.Equal(t, q.companies[i+2].ID.String(), m.Key)
+	}
+}
+
+func TestRunner_Fails(t *testing.T) {
+	q := newQueue(3)
+
+	err := newRunner(q, &recordingProducer{down: true}).Drain(context.Background())
+	require.Error(t, err)
+	require.Equal(t, models.ReplayFailed, q.replay.Status)
+	require.Equal(t, "broker unavailable", q.replay.LastError)
+	require.Zero(t, q.replay.Published)
+}
+
+func TestRunner_Throttles(t *testing.T) {
+	q := newQueue(4)
+	p := &recordingProducer{}
+	cfg := &replay.Config{Rate: 40, BatchSize: 2, Lease: time.Minute}
+
+	start := time.Now()
+	require.NoError(t, replay.NewRunner(cfg, q, p, kafka.ModeStructured, "", zap.NewNop()).Drain(context.Background()))
+	// Two batches of two events at 40 events per second take at least 100ms.
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	require.Len(t, p.messages, 4)
+	require.Equal(t, []segkafka.Header{
+		{Key: "content-type", Value: []byte("application/cloudevents+json")},
+		{Key: replay.HeaderReplay, Value: []byte(q.replay.ID.String())},
+	}, p.messages[0].Headers)
+}
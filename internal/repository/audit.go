@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// HistoryPage is a page of audit entries, newest first.
+// NextCursor is empty when there are no older entries.
+type HistoryPage struct {
+	Entries    []models.AuditEntry `json:"entries"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// AddAudit appends an entry to the audit trail.
+// Call it inside InTx so the entry is committed together with the change.
+func (r *postgresRepo) AddAudit(ctx context.Context, e *models.AuditEntry) error {
+	before, err := snapshot(e.Before)
+	if err != nil {
+		return err
+	}
+	after, err := snapshot(e.After)
+	if err != nil {
+		return err
+	}
+
+	query := r.sb.Insert("company_audit").
+		Columns("company_id", "actor", "action", "before", "after").
+		Values(e.CompanyID, e.Actor, e.Action, before, after).
+		Suffix("RETURNING id, created_at")
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return r.db.QueryRowContext(ctx, sqlStr, args...).Scan(&e.ID, &e.CreatedAt)
+}
+
+// History returns the audit entries of a company, newest first.
+// The cursor is the NextCursor of the previous page.
+func (r *postgresRepo) History(
+	ctx context.Context, companyID uuid.UUID, limit uint64, cursor string,
+) (*HistoryPage, error) {
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		limit = MaxListLimit
+	}
+
+	query := r.sb.Select("id", "company_id", "actor", "action", "before", "after", "created_at").
+		From("company_audit").
+		Where(sq.Eq{"company_id": companyID}).
+		OrderBy("id DESC").
+		Limit(limit + 1)
+
+	if cursor != "" {
+		before, err := decodeHistoryCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(sq.Lt{"id": before})
+	}
+
+	entries, err := r.queryAudit(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &HistoryPage{Entries: entries}
+	if uint64(len(entries)) > limit {
+		page.Entries = entries[:limit]
+		page.NextCursor = encodeHistoryCursor(page.Entries[limit-1].ID)
+	}
+
+	return page, nil
+}
+
+// AuditAt returns the latest audit entry of a company recorded at or before t,
+// or sql.ErrNoRows when the company had no recorded changes by then.
+func (r *postgresRepo) AuditAt(ctx context.Context, companyID uuid.UUID, t time.Time) (*models.AuditEntry, error) {
+	query := r.sb.Select("id", "company_id", "actor", "action", "before", "after", "created_at").
+		From("company_audit").
+		Where(sq.Eq{"company_id": companyID}).
+		Where(sq.LtOrEq{"created_at": t}).
+		OrderBy("id DESC").
+		Limit(1)
+
+	entries, err := r.queryAudit(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return &entries[0], nil
+}
+
+func (r *postgresRepo) queryAudit(ctx context.Context, query sq.SelectBuilder) ([]models.AuditEntry, error) {
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditEntry, 0)
+	for rows.Next() {
+		var e models.AuditEntry
+		var before, after []byte
+		err := rows.Scan(&e.ID, &e.CompanyID, &e.Actor, &e.Action, &before, &after, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if e.Before, err = unmarshalSnapshot(before); err != nil {
+			return nil, err
+		}
+		if e.After, err = unmarshalSnapshot(after); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// snapshot encodes a company for a JSONB column, keeping nil as SQL NULL.
+func snapshot(c *models.Company) (any, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+func unmarshalSnapshot(data []byte) (*models.Company, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	var c models.Company
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func encodeHistoryCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeHistoryCursor(s string) (int64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
@@ -0,0 +1,121 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+func TestPostgresRepo_AddAudit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewPostgresRepo(db)
+
+	id := uuid.New()
+	after := &models.Company{ID: id, Name: ptr("Acme"), Version: 1}
+	afterJSON, err := json.Marshal(after)
+	require.NoError(t, err)
+	createdAt := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO company_audit (company_id,actor,action,before,after) VALUES ($1,$2,$3,$4,$5) `+
+			`RETURNING id, created_at`)).
+		WithArgs(id, "user-1", models.ActionCreated, nil, afterJSON).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, createdAt))
+
+	entry := &models.AuditEntry{CompanyID: id, Actor: "user-1", Action: models.ActionCreated, After: after}
+	err = repo.AddAudit(context.Background(), entry)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), entry.ID)
+	require.Equal(t, createdAt, entry.CreatedAt)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_History(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewPostgresRepo(db)
+
+	id := uuid.New()
+	before := []byte(`{"id":"` + id.String() + `","name":"Acme","version":1}`)
+	after := []byte(`{"id":"` + id.String() + `","name":"Acme 2","version":2}`)
+	columns := []string{"id", "company_id", "actor", "action", "before", "after", "created_at"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT id, company_id, actor, action, before, after, created_at FROM company_audit ` +
+			`WHERE company_id = $1 ORDER BY id DESC LIMIT 2`)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(5, id, "user-1", "updated", before, after, time.Now()).
+			AddRow(3, id, "user-1", "created", nil, before, time.Now()))
+
+	page, err := repo.History(context.Background(), id, 1, "")
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+	require.Equal(t, models.ActionUpdated, page.Entries[0].Action)
+	require.Equal(t, "Acme", *page.Entries[0].Before.Name)
+	require.Equal(t, "Acme 2", *page.Entries[0].After.Name)
+	require.NotEmpty(t, page.NextCursor)
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT id, company_id, actor, action, before, after, created_at FROM company_audit `+
+			`WHERE company_id = $1 AND id < $2 ORDER BY id DESC LIMIT 2`)).
+		WithArgs(id, 5).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(3, id, "user-1", "created", nil, before, time.Now()))
+
+	page, err = repo.History(context.Background(), id, 1, page.NextCursor)
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+	require.Nil(t, page.Entries[0].Before)
+	require.Empty(t, page.NextCursor)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_AuditAt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewPostgresRepo(db)
+
+	id := uuid.New()
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	after := []byte(`{"id":"` + id.String() + `","name":"Acme","version":1}`)
+	columns := []string{"id", "company_id", "actor", "action", "before", "after", "created_at"}
+	query := regexp.QuoteMeta(
+		`SELECT id, company_id, actor, action, before, after, created_at FROM company_audit ` +
+			`WHERE company_id = $1 AND created_at <= $2 ORDER BY id DESC LIMIT 1`)
+
+	mock.ExpectQuery(query).
+		WithArgs(id, at).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(3, id, "user-1", "created", nil, after, at))
+
+	entry, err := repo.AuditAt(context.Background(), id, at)
+	require.NoError(t, err)
+	require.Equal(t, "Acme", *entry.After.Name)
+
+	mock.ExpectQuery(query).
+		WithArgs(id, at).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	_, err = repo.AuditAt(context.Background(), id, at)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func ptr[T any](v T) *T { return &v }
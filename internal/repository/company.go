@@ -2,17 +2,93 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/dagherghinescu/companies/internal/models"
 )
 
+// ErrVersionMismatch is returned by conditional writes when the company was changed since it was read.
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// SortField is a column companies can be ordered by when listing.
+type SortField string
+
+const (
+	SortByName      SortField = "name"
+	SortByEmployees SortField = "amount_of_employees"
+)
+
+// ListFilter narrows down and orders the companies returned by List.
+// Nil or zero values mean the filter is not applied.
+type ListFilter struct {
+	Type         *models.CompanyType
+	Registered   *bool
+	MinEmployees *int
+	MaxEmployees *int
+	NamePrefix   string
+	Sort         SortField
+	Descending   bool
+	Limit        uint64
+	Cursor       string
+}
+
+// CompanyPage is a single page of companies returned by List.
+// NextCursor is empty when there are no more results.
+type CompanyPage struct {
+	Companies  []models.Company `json:"companies"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// SearchHit is a company matched by Search, with its relevance and
+// the matching parts of its name and description highlighted.
+type SearchHit struct {
+	Company    models.Company   `json:"company"`
+	Rank       float64          `json:"rank"`
+	Highlights SearchHighlights `json:"highlights"`
+}
+
+// SearchHighlights holds HTML snippets where matched terms are wrapped in <mark></mark>.
+// The rest of the text is HTML-escaped, so snippets can be inserted into a page as is.
+type SearchHighlights struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
 // CompanyRepository defines the contract for interacting with company data.
 // Handlers and services should depend on this interface instead of a concrete implementation.
 type Company interface {
-	Create(ctx context.Context, c *models.Company) error
+	CompanyReader
+	CompanyWriter
+	ChangeLog
+	InTx(ctx context.Context, fn func(tx Company) error) error
+}
+
+// CompanyReader reads companies that are not deleted.
+type CompanyReader interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Company, error)
-	Patch(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
-	Delete(ctx context.Context, id uuid.UUID) error
+	GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Company, error)
+	List(ctx context.Context, f ListFilter) (*CompanyPage, error)
+	Export(ctx context.Context, f ListFilter, fn func(c *models.Company) error) error
+	Search(ctx context.Context, q string, limit uint64) ([]SearchHit, error)
+	TakenNames(ctx context.Context, names []string) ([]string, error)
+}
+
+// CompanyWriter changes companies.
+type CompanyWriter interface {
+	Create(ctx context.Context, c *models.Company) error
+	Patch(ctx context.Context, id uuid.UUID, updates map[string]interface{}, expectedVersion *int64) error
+	Delete(ctx context.Context, id uuid.UUID, deletedBy string, expectedVersion *int64) error
+	Restore(ctx context.Context, id uuid.UUID) error
+	Purge(ctx context.Context, deletedBefore time.Time) ([]uuid.UUID, error)
+}
+
+// ChangeLog records the changes made to companies in the outbox and the audit trail.
+type ChangeLog interface {
+	AddOutboxEvent(ctx context.Context, key string, payload []byte) error
+	AddAudit(ctx context.Context, e *models.AuditEntry) error
+	History(ctx context.Context, companyID uuid.UUID, limit uint64, cursor string) (*HistoryPage, error)
+	AuditAt(ctx context.Context, companyID uuid.UUID, t time.Time) (*models.AuditEntry, error)
 }
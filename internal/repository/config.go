@@ -14,6 +14,9 @@ type Config struct {
 	Port     string `envconfig:"PORT" default:"5432"`
 	Name     string `envconfig:"NAME" required:"true"`
 	SSLMode  string `envconfig:"SSLMODE" default:"disable"`
+
+	// AutoMigrate applies pending schema migrations when the service starts.
+	AutoMigrate bool `envconfig:"AUTO_MIGRATE" default:"true"`
 }
 
 // EnvConfig loads the Postgres configuration from environment variables
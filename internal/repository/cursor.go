@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor is the keyset position of the last row of a page.
+// It records the sort it was issued for so it can't be replayed against another ordering.
+type cursor struct {
+	Sort  SortField `json:"s"`
+	Desc  bool      `json:"d,omitempty"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+func newCursor(f ListFilter, c *models.Company) cursor {
+	cur := cursor{Sort: f.Sort, Desc: f.Descending, ID: c.ID}
+	switch f.Sort {
+	case SortByEmployees:
+		if c.AmountEmployees != nil {
+			cur.Value = strconv.Itoa(*c.AmountEmployees)
+		}
+	default:
+		if c.Name != nil {
+			cur.Value = *c.Name
+		}
+	}
+	return cur
+}
+
+func (c cursor) encode() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// sortValue converts the cursor value back to the type of the sort column.
+func (c cursor) sortValue() (any, error) {
+	if c.Sort != SortByEmployees {
+		return c.Value, nil
+	}
+
+	n, err := strconv.Atoi(c.Value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return n, nil
+}
+
+func decodeCursor(s string, f ListFilter) (cursor, error) {
+	var c cursor
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	if c.Sort != f.Sort || c.Desc != f.Descending {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// exportFetchSize is the number of rows fetched from the export cursor at a time.
+const exportFetchSize = 500
+
+// Export calls fn for each company matching the conditions of f, in the order of f.Sort
+// and then by id, stopping after f.Limit companies unless it is zero. The cursor of f
+// is ignored.
+//
+// Rows are read through a server-side cursor exportFetchSize at a time, so memory use
+// does not depend on the number of companies. The cursor lives in a transaction that
+// stays open until fn has been called for the last row or returns an error.
+func (r *postgresRepo) Export(ctx context.Context, f ListFilter, fn func(c *models.Company) error) error {
+	order, err := sortOrder(&f)
+	if err != nil {
+		return err
+	}
+
+	query := r.sb.Select("id", "name", "description", "amount_of_employees", "registered", "type", "version").
+		From("companies").
+		OrderBy(fmt.Sprintf("%s %s", f.Sort, order), "id "+order)
+	query = filterCompanies(query, f)
+	if f.Limit > 0 {
+		query = query.Limit(f.Limit)
+	}
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return r.InTx(ctx, func(tx Company) error {
+		db := tx.(*postgresRepo).db
+
+		// The cursor is closed by the end of the transaction.
+		if _, err := db.ExecContext(ctx, "DECLARE companies_export NO SCROLL CURSOR FOR "+sqlStr, args...); err != nil {
+			return err
+		}
+
+		fetch := fmt.Sprintf("FETCH FORWARD %d FROM companies_export", exportFetchSize)
+		for {
+			n, err := fetchCompanies(ctx, db, fetch, fn)
+			if err != nil {
+				return err
+			}
+			if n < exportFetchSize {
+				return nil
+			}
+		}
+	})
+}
+
+// fetchCompanies runs a FETCH and calls fn for each row, returning the number of rows.
+func fetchCompanies(ctx context.Context, db dbtx, fetch string, fn func(c *models.Company) error) (int, error) {
+	rows, err := db.QueryContext(ctx, fetch)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var c models.Company
+		err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.AmountEmployees, &c.Registered, &c.Type, &c.Version)
+		if err != nil {
+			return n, err
+		}
+		if err := fn(&c); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, rows.Err()
+}
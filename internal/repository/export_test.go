@@ -0,0 +1,51 @@
+package repository_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+func TestPostgresRepo_Export(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewPostgresRepo(db)
+
+	ctype := models.Corporation
+	first, second := uuid.New(), uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(
+		`DECLARE companies_export NO SCROLL CURSOR FOR ` + selectCompanies +
+			`WHERE deleted_at IS NULL AND type = $1 ORDER BY amount_of_employees DESC, id DESC LIMIT 2`)).
+		WithArgs(ctype).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`FETCH FORWARD 500 FROM companies_export`)).
+		WillReturnRows(companyRows().
+			AddRow(first, "Acme", nil, 50, true, ctype, 1).
+			AddRow(second, "Globex", "Sample", 42, true, ctype, 3))
+	mock.ExpectCommit()
+
+	var exported []uuid.UUID
+	err = repo.Export(context.Background(), repository.ListFilter{
+		Type:       &ctype,
+		Sort:       repository.SortByEmployees,
+		Descending: true,
+		Limit:      2,
+	}, func(c *models.Company) error {
+		exported = append(exported, c.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{first, second}, exported)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// StoredResponse is the response recorded for an idempotency key.
+type StoredResponse struct {
+	Status      int
+	ContentType string
+	ETag        string
+	Body        []byte
+}
+
+// IdempotencyRecord is the request that first used an idempotency key.
+// Response is nil while that request is still being processed.
+type IdempotencyRecord struct {
+	Fingerprint string
+	Response    *StoredResponse
+}
+
+// Idempotency stores the responses of requests sent with an Idempotency-Key,
+// so that retries of the same request can be answered without running it again.
+// Keys are unique within a scope, usually the user sending them.
+type Idempotency interface {
+	// Claim reserves key for a request with the given fingerprint. It returns nil
+	// when the key was free, or only used before expiredBefore, and the existing
+	// record otherwise.
+	Claim(ctx context.Context, scope, key, fingerprint string, expiredBefore time.Time) (*IdempotencyRecord, error)
+	// Complete stores the response of the request holding the key.
+	Complete(ctx context.Context, scope, key string, resp *StoredResponse) error
+	// Release frees a claimed key so that the request can be retried.
+	Release(ctx context.Context, scope, key string) error
+}
+
+type idempotencyRepo struct {
+	db *sql.DB
+	sb sq.StatementBuilderType
+}
+
+// NewIdempotencyRepo creates a new Postgres backed idempotency key store
+func NewIdempotencyRepo(db *sql.DB) Idempotency {
+	return &idempotencyRepo{
+		db: db,
+		sb: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+// Claim inserts the key, taking over expired records in place.
+func (r *idempotencyRepo) Claim(
+	ctx context.Context, scope, key, fingerprint string, expiredBefore time.Time,
+) (*IdempotencyRecord, error) {
+	query := r.sb.Insert("idempotency_keys").
+		Columns("scope", "key", "fingerprint").
+		Values(scope, key, fingerprint).
+		Suffix(`ON CONFLICT (scope, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint, status = NULL, content_type = NULL, etag = NULL, body = NULL,
+			created_at = NOW()
+			WHERE idempotency_keys.created_at < ?
+			RETURNING key`, expiredBefore)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var claimed string
+	err = r.db.QueryRowContext(ctx, sqlStr, args...).Scan(&claimed)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return r.get(ctx, scope, key)
+}
+
+// get returns the record of key. It returns sql.ErrNoRows when the key was
+// released since it was claimed.
+func (r *idempotencyRepo) get(ctx context.Context, scope, key string) (*IdempotencyRecord, error) {
+	query := r.sb.Select("fingerprint", "status", "content_type", "etag", "body").
+		From("idempotency_keys").
+		Where(sq.Eq{"scope": scope, "key": key})
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		rec         IdempotencyRecord
+		status      sql.NullInt64
+		contentType sql.NullString
+		etag        sql.NullString
+		body        []byte
+	)
+	err = r.db.QueryRowContext(ctx, sqlStr, args...).Scan(&rec.Fingerprint, &status, &contentType, &etag, &body)
+	if err != nil {
+		return nil, err
+	}
+
+	if status.Valid {
+		rec.Response = &StoredResponse{
+			Status:      int(status.Int64),
+			ContentType: contentType.String,
+			ETag:        etag.String,
+			Body:        body,
+		}
+	}
+	return &rec, nil
+}
+
+// Complete records the response of a claimed key.
+func (r *idempotencyRepo) Complete(ctx context.Context, scope, key string, resp *StoredResponse) error {
+	query := r.sb.Update("idempotency_keys").
+		Set("status", resp.Status).
+		Set("content_type", resp.ContentType).
+		Set("etag", resp.ETag).
+		Set("body", resp.Body).
+		Where(sq.Eq{"scope": scope, "key": key})
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+// Release deletes a claimed key that has no response yet.
+func (r *idempotencyRepo) Release(ctx context.Context, scope, key string) error {
+	query := r.sb.Delete("idempotency_keys").
+		Where(sq.Eq{"scope": scope, "key": key}).
+		Where("status IS NULL")
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, sqlStr, args...)
+	return err
+}
@@ -0,0 +1,94 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+func TestIdempotencyRepo_Claim(t *testing.T) {
+	expiredBefore := time.Now().Add(-time.Hour)
+	columns := []string{"fingerprint", "status", "content_type", "etag", "body"}
+
+	tests := []struct {
+		name           string
+		setupMock      func(mock sqlmock.Sqlmock)
+		expectedRecord *repository.IdempotencyRecord
+		expectedErr    error
+	}{
+		{
+			name: "free key",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO idempotency_keys \(scope,key,fingerprint\) VALUES \(\$1,\$2,\$3\) `+
+					`ON CONFLICT \(scope, key\) DO UPDATE SET .* `+
+					`WHERE idempotency_keys.created_at < \$4\s+RETURNING key`).
+					WithArgs("user-1", "k1", "fp", expiredBefore).
+					WillReturnRows(sqlmock.NewRows([]string{"key"}).AddRow("k1"))
+			},
+		},
+		{
+			name: "completed key",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO idempotency_keys`).
+					WithArgs("user-1", "k1", "fp", expiredBefore).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(`SELECT fingerprint, status, content_type, etag, body FROM idempotency_keys `+
+					`WHERE key = \$1 AND scope = \$2`).
+					WithArgs("k1", "user-1").
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow("fp", 201, "application/json", `"1"`, []byte(`{"id":"x"}`)))
+			},
+			expectedRecord: &repository.IdempotencyRecord{
+				Fingerprint: "fp",
+				Response: &repository.StoredResponse{
+					Status:      201,
+					ContentType: "application/json",
+					ETag:        `"1"`,
+					Body:        []byte(`{"id":"x"}`),
+				},
+			},
+		},
+		{
+			name: "key in progress",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO idempotency_keys`).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(`SELECT fingerprint, status, content_type, etag, body FROM idempotency_keys`).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow("other", nil, nil, nil, nil))
+			},
+			expectedRecord: &repository.IdempotencyRecord{Fingerprint: "other"},
+		},
+		{
+			name: "key released meanwhile",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`INSERT INTO idempotency_keys`).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(`SELECT fingerprint, status, content_type, etag, body FROM idempotency_keys`).
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+			expectedErr: sql.ErrNoRows,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			tt.setupMock(mock)
+
+			repo := repository.NewIdempotencyRepo(db)
+			rec, err := repo.Claim(context.Background(), "user-1", "k1", "fp", expiredBefore)
+			require.ErrorIs(t, err, tt.expectedErr)
+			require.Equal(t, tt.expectedRecord, rec)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+// outboxLockID is the Postgres advisory lock key held by the relay draining the outbox.
+const outboxLockID = 7_301_245_001
+
+// deletePublishedSQL deletes the oldest events published to Kafka and handed to webhooks
+// before $1, up to $2 of them.
+const deletePublishedSQL = `
+DELETE FROM outbox WHERE id IN (
+	SELECT id FROM outbox
+	WHERE published_at < $1 AND webhooks_enqueued_at IS NOT NULL
+	ORDER BY id
+	LIMIT $2
+)`
+
+// OutboxEvent is an event written in the same transaction as the change it describes,
+// waiting to be published to Kafka.
+type OutboxEvent struct {
+	ID       int64
+	Key      string
+	Payload  []byte
+	Attempts int
+}
+
+// Outbox gives the relay access to the events waiting to be published.
+type Outbox interface {
+	// Lock takes the relay lock so only one instance drains the outbox at a time.
+	// When acquired is true, release must be called once the batch is done.
+	Lock(ctx context.Context) (release func(), acquired bool, err error)
+	// Pending returns up to limit events that are due, ordered by creation.
+	// Events whose key has an earlier event still backing off are skipped to keep per-key ordering.
+	Pending(ctx context.Context, limit uint64) ([]OutboxEvent, error)
+	MarkPublished(ctx context.Context, ids ...int64) error
+	MarkFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error
+	// DeletePublished deletes up to limit events published and handed to webhooks before
+	// the given time, oldest first, and returns how many it deleted.
+	DeletePublished(ctx context.Context, before time.Time, limit uint64) (int64, error)
+}
+
+type outboxRepo struct {
+	db *sql.DB
+	sb sq.StatementBuilderType
+}
+
+// NewOutboxRepo creates a new Postgres backed outbox
+func NewOutboxRepo(db *sql.DB) Outbox {
+	return &outboxRepo{
+		db: db,
+		sb: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+// AddOutboxEvent stores an event to be published by the relay.
+// Call it inside InTx so the event is committed together with the change.
+func (r *postgresRepo) AddOutboxEvent(ctx context.Context, key string, payload []byte) error {
+	query := r.sb.Insert("outbox").
+		Columns("event_key", "payload").
+		Values(key, payload)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+// Lock takes a session level advisory lock on a dedicated connection.
+func (r *outboxRepo) Lock(ctx context.Context) (func(), bool, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var acquired bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", outboxLockID).Scan(&acquired)
+	if err != nil || !acquired {
+		_ = conn.Close()
+		return nil, false, err
+	}
+
+	release := func() {
+		_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", outboxLockID)
+		_ = conn.Close()
+	}
+	return release, true, nil
+}
+
+// Pending returns the events due for publishing.
+func (r *outboxRepo) Pending(ctx context.Context, limit uint64) ([]OutboxEvent, error) {
+	query := r.sb.Select("o.id", "o.event_key", "o.payload", "o.attempts").
+		From("outbox o").
+		Where("o.published_at IS NULL").
+		Where("o.next_attempt_at <= NOW()").
+		Where(`NOT EXISTS (
+			SELECT 1 FROM outbox p
+			WHERE p.event_key = o.event_key AND p.published_at IS NULL AND p.id < o.id AND p.next_attempt_at > NOW()
+		)`).
+		OrderBy("o.id").
+		Limit(limit)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []OutboxEvent
+	for rows.Next() {
+		var e OutboxEvent
+		if err := rows.Scan(&e.ID, &e.Key, &e.Payload, &e.Attempts); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// MarkPublished flags the events as delivered so they are not sent again.
+func (r *outboxRepo) MarkPublished(ctx context.Context, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := r.sb.Update("outbox").
+		Set("published_at", sq.Expr("NOW()")).
+		Where(sq.Expr("id = ANY(?)", pq.Array(ids)))
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+// MarkFailed records a failed publish attempt and when to try again.
+func (r *outboxRepo) MarkFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	query := r.sb.Update("outbox").
+		Set("attempts", sq.Expr("attempts + 1")).
+		Set("last_error", reason).
+		Set("next_attempt_at", retryAt).
+		Where(sq.Eq{"id": id})
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+// DeletePublished deletes old events that neither the relay nor the webhooks need anymore.
+func (r *outboxRepo) DeletePublished(ctx context.Context, before time.Time, limit uint64) (int64, error) {
+	res, err := r.db.ExecContext(ctx, deletePublishedSQL, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
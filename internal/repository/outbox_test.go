@@ -0,0 +1,32 @@
+package repository_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+func TestOutboxRepo_DeletePublished(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	before := time.Now().Add(-time.Hour)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM outbox WHERE id IN (`)+
+		`\s+SELECT id FROM outbox\s+`+
+		regexp.QuoteMeta(`WHERE published_at < $1 AND webhooks_enqueued_at IS NOT NULL`)+
+		`\s+ORDER BY id\s+LIMIT \$2\s+\)`).
+		WithArgs(before, uint64(1000)).
+		WillReturnResult(sqlmock.NewResult(0, 42))
+
+	deleted, err := repository.NewOutboxRepo(db).DeletePublished(context.Background(), before, 1000)
+	require.NoError(t, err)
+	require.EqualValues(t, 42, deleted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
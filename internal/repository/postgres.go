@@ -4,29 +4,64 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/dagherghinescu/companies/internal/models"
 )
 
+const (
+	DefaultListLimit uint64 = 20
+	MaxListLimit     uint64 = 100
+
+	// notDeleted hides soft deleted companies.
+	notDeleted = "deleted_at IS NULL"
+)
+
+// dbtx is the subset of *sql.DB and *sql.Tx used by the repository,
+// so the same queries can run with or without a transaction.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 // postgresRepo implements CompanyRepository using Postgres + Squirrel
 type postgresRepo struct {
-	db *sql.DB
-	sb sq.StatementBuilderType
+	db   dbtx
+	conn *sql.DB // nil when the repository is bound to a transaction
+	sb   sq.StatementBuilderType
 }
 
 // NewPostgresRepo creates a new Postgres repository instance
 func NewPostgresRepo(db *sql.DB) Company {
 	return &postgresRepo{
-		db: db,
-		sb: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		db:   db,
+		conn: db,
+		sb:   sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
 	}
 }
 
+// InTx runs fn with a repository bound to a single transaction, committing if fn succeeds
+// and rolling back otherwise. Nested calls reuse the outer transaction.
+func (r *postgresRepo) InTx(ctx context.Context, fn func(tx Company) error) error {
+	if r.conn == nil {
+		return fn(r)
+	}
+
+	return inTx(ctx, r.conn, func(tx *sql.Tx) error {
+		return fn(&postgresRepo{db: tx, sb: r.sb})
+	})
+}
+
 // Create inserts a new company record
 func (r *postgresRepo) Create(ctx context.Context, c *models.Company) error {
 	query := r.sb.Insert("companies").
@@ -44,9 +79,23 @@ func (r *postgresRepo) Create(ctx context.Context, c *models.Company) error {
 
 // GetByID retrieves a company by ID
 func (r *postgresRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Company, error) {
-	query := r.sb.Select("id", "name", "description", "amount_of_employees", "registered", "type").
+	return r.getByID(ctx, id, false)
+}
+
+// GetForUpdate retrieves a company by ID and locks its row until the transaction ends,
+// so the snapshot stays accurate while the company is being changed. Use it inside InTx.
+func (r *postgresRepo) GetForUpdate(ctx context.Context, id uuid.UUID) (*models.Company, error) {
+	return r.getByID(ctx, id, true)
+}
+
+func (r *postgresRepo) getByID(ctx context.Context, id uuid.UUID, forUpdate bool) (*models.Company, error) {
+	query := r.sb.Select("id", "name", "description", "amount_of_employees", "registered", "type", "version").
 		From("companies").
-		Where(sq.Eq{"id": id})
+		Where(sq.Eq{"id": id}).
+		Where(notDeleted)
+	if forUpdate {
+		query = query.Suffix("FOR UPDATE")
+	}
 
 	sqlStr, args, err := query.ToSql()
 	if err != nil {
@@ -55,7 +104,7 @@ func (r *postgresRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Compa
 
 	row := r.db.QueryRowContext(ctx, sqlStr, args...)
 	var c models.Company
-	err = row.Scan(&c.ID, &c.Name, &c.Description, &c.AmountEmployees, &c.Registered, &c.Type)
+	err = row.Scan(&c.ID, &c.Name, &c.Description, &c.AmountEmployees, &c.Registered, &c.Type, &c.Version)
 	if err != nil {
 		return nil, err
 	}
@@ -63,8 +112,208 @@ func (r *postgresRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Compa
 	return &c, nil
 }
 
-// Patch updates only the specified columns in updates for the company with id.
-func (r *postgresRepo) Patch(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
+// filterCompanies restricts query to the companies that are not deleted and match
+// the conditions of f. The sort order, limit and cursor of f are left to the caller.
+func filterCompanies(query sq.SelectBuilder, f ListFilter) sq.SelectBuilder {
+	query = query.Where(notDeleted)
+
+	if f.Type != nil {
+		query = query.Where(sq.Eq{"type": *f.Type})
+	}
+	if f.Registered != nil {
+		query = query.Where(sq.Eq{"registered": *f.Registered})
+	}
+	if f.MinEmployees != nil {
+		query = query.Where(sq.GtOrEq{"amount_of_employees": *f.MinEmployees})
+	}
+	if f.MaxEmployees != nil {
+		query = query.Where(sq.LtOrEq{"amount_of_employees": *f.MaxEmployees})
+	}
+	if f.NamePrefix != "" {
+		query = query.Where(sq.Like{"name": escapeLike(f.NamePrefix) + "%"})
+	}
+
+	return query
+}
+
+// List returns a page of companies matching the filter, ordered by f.Sort and then by id.
+// Pagination is keyset based: the returned NextCursor points after the last row of the page.
+func (r *postgresRepo) List(ctx context.Context, f ListFilter) (*CompanyPage, error) {
+	order, err := sortOrder(&f)
+	if err != nil {
+		return nil, err
+	}
+	if f.Limit == 0 {
+		f.Limit = DefaultListLimit
+	}
+	if f.Limit > MaxListLimit {
+		f.Limit = MaxListLimit
+	}
+
+	query := r.sb.Select("id", "name", "description", "amount_of_employees", "registered", "type", "version").
+		From("companies").
+		OrderBy(fmt.Sprintf("%s %s", f.Sort, order), "id "+order).
+		Limit(f.Limit + 1)
+	query, err = afterCursor(filterCompanies(query, f), f)
+	if err != nil {
+		return nil, err
+	}
+
+	companies, err := r.queryCompanies(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &CompanyPage{Companies: companies}
+	if uint64(len(companies)) > f.Limit {
+		page.Companies = companies[:f.Limit]
+		last := page.Companies[len(page.Companies)-1]
+		page.NextCursor, err = newCursor(f, &last).encode()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// sortOrder defaults the sort field of f and returns the SQL direction to sort in.
+func sortOrder(f *ListFilter) (string, error) {
+	if f.Sort == "" {
+		f.Sort = SortByName
+	}
+	if f.Sort != SortByName && f.Sort != SortByEmployees {
+		return "", fmt.Errorf("unsupported sort field %q", f.Sort)
+	}
+
+	if f.Descending {
+		return "DESC", nil
+	}
+	return "ASC", nil
+}
+
+// afterCursor restricts query to the rows that come after the cursor of f, if any.
+func afterCursor(query sq.SelectBuilder, f ListFilter) (sq.SelectBuilder, error) {
+	if f.Cursor == "" {
+		return query, nil
+	}
+
+	cur, err := decodeCursor(f.Cursor, f)
+	if err != nil {
+		return query, err
+	}
+	val, err := cur.sortValue()
+	if err != nil {
+		return query, err
+	}
+
+	cmp := ">"
+	if f.Descending {
+		cmp = "<"
+	}
+	return query.Where(sq.Expr(fmt.Sprintf("(%s, id) %s (?, ?)", f.Sort, cmp), val, cur.ID)), nil
+}
+
+// queryCompanies runs a query selecting the columns of models.Company.
+func (r *postgresRepo) queryCompanies(ctx context.Context, query sq.SelectBuilder) ([]models.Company, error) {
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	companies := make([]models.Company, 0)
+	for rows.Next() {
+		var c models.Company
+		err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.AmountEmployees, &c.Registered, &c.Type, &c.Version)
+		if err != nil {
+			return nil, err
+		}
+		companies = append(companies, c)
+	}
+
+	return companies, rows.Err()
+}
+
+// escapeHTML returns the SQL expression escaping the HTML special characters of expr,
+// so that the snippets of ts_headline only carry the <mark> tags it adds.
+func escapeHTML(expr string) string {
+	for _, r := range [][2]string{{"&", "&amp;"}, {"<", "&lt;"}, {">", "&gt;"}, {`"`, "&quot;"}, {"''", "&#39;"}} {
+		expr = "replace(" + expr + ", '" + r[0] + "', '" + r[1] + "')"
+	}
+	return expr
+}
+
+// Search ranks companies by how well their name and description match q.
+// Full-text matches are combined with trigram similarity so that misspelled
+// queries still find results.
+func (r *postgresRepo) Search(ctx context.Context, q string, limit uint64) ([]SearchHit, error) {
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		limit = MaxListLimit
+	}
+
+	const tsQuery = "websearch_to_tsquery('english', ?)"
+	const headline = "'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=5, MaxWords=20'"
+
+	query := r.sb.Select("id", "name", "description", "amount_of_employees", "registered", "type", "version").
+		Column(sq.Expr("ts_rank(search_vector, "+tsQuery+") + "+
+			"greatest(similarity(name, ?), word_similarity(?, coalesce(description, ''))) AS rank", q, q, q)).
+		Column(sq.Expr("ts_headline('english', "+escapeHTML("name")+", "+tsQuery+", "+headline+")", q)).
+		Column(sq.Expr("ts_headline('english', "+escapeHTML("coalesce(description, '')")+", "+
+			tsQuery+", "+headline+")", q)).
+		From("companies").
+		Where(notDeleted).
+		Where(sq.Or{
+			sq.Expr("search_vector @@ "+tsQuery, q),
+			sq.Expr("name % ?", q),
+			sq.Expr("? <% description", q),
+		}).
+		OrderBy("rank DESC", "id").
+		Limit(limit)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := make([]SearchHit, 0, limit)
+	for rows.Next() {
+		var h SearchHit
+		c := &h.Company
+		err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.AmountEmployees, &c.Registered, &c.Type, &c.Version,
+			&h.Rank, &h.Highlights.Name, &h.Highlights.Description)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
+
+// Patch updates only the specified columns in updates for the company with id
+// and bumps its version. When expectedVersion is set the update only applies if
+// the stored version still matches, otherwise ErrVersionMismatch is returned.
+func (r *postgresRepo) Patch(
+	ctx context.Context, id uuid.UUID, updates map[string]interface{}, expectedVersion *int64,
+) error {
 	if len(updates) == 0 {
 		return nil
 	}
@@ -73,37 +322,233 @@ func (r *postgresRepo) Patch(ctx context.Context, id uuid.UUID, updates map[stri
 	for col, val := range updates {
 		q = q.Set(col, val)
 	}
-	q = q.Where(sq.Eq{"id": id})
+	q = q.Set("version", sq.Expr("version + 1")).
+		Where(sq.Eq{"id": id}).
+		Where(notDeleted)
+	if expectedVersion != nil {
+		q = q.Where(sq.Eq{"version": *expectedVersion})
+	}
 
 	sqlStr, args, err := q.ToSql()
 	if err != nil {
 		return err
 	}
 
-	_, err = r.db.ExecContext(ctx, sqlStr, args...)
+	res, err := r.db.ExecContext(ctx, sqlStr, args...)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return r.checkWritten(ctx, res, id, expectedVersion)
 }
 
-// Delete removes a company by ID
-func (r *postgresRepo) Delete(ctx context.Context, id uuid.UUID) error {
-	query := r.sb.Delete("companies").
+// Delete soft deletes a company by ID, recording when and by whom. Deleted companies
+// are hidden from reads until restored or purged. When expectedVersion is set the
+// company is only deleted if the stored version still matches, otherwise
+// ErrVersionMismatch is returned.
+func (r *postgresRepo) Delete(ctx context.Context, id uuid.UUID, deletedBy string, expectedVersion *int64) error {
+	query := r.sb.Update("companies").
+		Set("deleted_at", sq.Expr("NOW()")).
+		Set("deleted_by", deletedBy).
+		Set("version", sq.Expr("version + 1")).
+		Where(sq.Eq{"id": id}).
+		Where(notDeleted)
+	if expectedVersion != nil {
+		query = query.Where(sq.Eq{"version": *expectedVersion})
+	}
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx, sqlStr, args...)
+	if err != nil {
+		return err
+	}
+
+	return r.checkWritten(ctx, res, id, expectedVersion)
+}
+
+// TakenNames returns those of names that are used by a company that is not deleted.
+func (r *postgresRepo) TakenNames(ctx context.Context, names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	query := r.sb.Select("name").
 		From("companies").
-		Where(sq.Eq{"id": id})
+		Where(sq.Eq{"name": names}).
+		Where(notDeleted)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	taken := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		taken = append(taken, name)
+	}
+
+	return taken, rows.Err()
+}
+
+// Restore brings back a soft deleted company.
+// It returns sql.ErrNoRows when there is no deleted company with that ID.
+func (r *postgresRepo) Restore(ctx context.Context, id uuid.UUID) error {
+	query := r.sb.Update("companies").
+		Set("deleted_at", nil).
+		Set("deleted_by", nil).
+		Set("version", sq.Expr("version + 1")).
+		Where(sq.Eq{"id": id}).
+		Where("deleted_at IS NOT NULL")
 
 	sqlStr, args, err := query.ToSql()
 	if err != nil {
 		return err
 	}
 
+	res, err := r.db.ExecContext(ctx, sqlStr, args...)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(res)
+}
+
+// Purge permanently removes the companies soft deleted before deletedBefore
+// and returns their IDs. The snapshots they left behind go with them: those of
+// their audit entries are cleared, their past webhook deliveries removed, and so
+// are the outbox events published and handed to webhooks before deletedBefore.
+// Call it inside InTx so nothing is left over when one of the steps fails.
+func (r *postgresRepo) Purge(ctx context.Context, deletedBefore time.Time) ([]uuid.UUID, error) {
+	ids, err := r.purgeCompanies(ctx, deletedBefore)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		if err := r.redact(ctx, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	err = r.exec(ctx, r.sb.Delete("outbox").
+		Where("published_at IS NOT NULL AND webhooks_enqueued_at IS NOT NULL").
+		Where(sq.Lt{"created_at": deletedBefore}))
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// redact clears the snapshots of the audit entries of the companies ids and removes
+// their webhook deliveries that are no longer pending.
+func (r *postgresRepo) redact(ctx context.Context, ids []uuid.UUID) error {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = id.String()
+	}
+
+	err := r.exec(ctx, r.sb.Update("company_audit").
+		Set("before", nil).
+		Set("after", nil).
+		Where(sq.Expr("company_id = ANY(?)", pq.Array(ids))).
+		Where("(before IS NOT NULL OR after IS NOT NULL)"))
+	if err != nil {
+		return err
+	}
+
+	return r.exec(ctx, r.sb.Delete("webhook_deliveries").
+		Where(sq.Expr("event_key = ANY(?)", pq.Array(keys))).
+		Where(sq.NotEq{"state": models.DeliveryPending}))
+}
+
+// purgeCompanies deletes the companies soft deleted before deletedBefore and returns their IDs.
+func (r *postgresRepo) purgeCompanies(ctx context.Context, deletedBefore time.Time) ([]uuid.UUID, error) {
+	query := r.sb.Delete("companies").
+		Where(sq.Lt{"deleted_at": deletedBefore}).
+		Suffix("RETURNING id")
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// exec runs a statement, whatever the number of rows it changes.
+func (r *postgresRepo) exec(ctx context.Context, query sq.Sqlizer) error {
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
 	_, err = r.db.ExecContext(ctx, sqlStr, args...)
 	return err
 }
 
-// EnsureAdminUser creates the admin user if it doesn't exist.
+// checkWritten tells apart why a conditional write touched no row:
+// sql.ErrNoRows when the company does not exist, ErrVersionMismatch when it changed meanwhile.
+func (r *postgresRepo) checkWritten(ctx context.Context, res sql.Result, id uuid.UUID, expectedVersion *int64) error {
+	err := expectAffected(res)
+	if !errors.Is(err, sql.ErrNoRows) || expectedVersion == nil {
+		return err
+	}
+
+	var exists bool
+	err = r.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM companies WHERE id = $1 AND deleted_at IS NULL)", id,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return sql.ErrNoRows
+}
+
+// expectAffected returns sql.ErrNoRows when a statement did not touch any row.
+func expectAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// EnsureAdminUser creates the admin user if it doesn't exist
+// and makes sure it holds the admin role.
 func EnsureAdminUser(ctx context.Context, db *sql.DB, username, password string) error {
 	var exists bool
 	err := db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE username=$1)", username).Scan(&exists)
@@ -113,7 +558,11 @@ func EnsureAdminUser(ctx context.Context, db *sql.DB, username, password string)
 
 	if exists {
 		log.Println("Admin user already exists")
-		return nil
+		_, err = db.ExecContext(ctx,
+			"UPDATE users SET roles = array_append(roles, $2) WHERE username = $1 AND NOT $2 = ANY(roles)",
+			username, string(models.RoleAdmin),
+		)
+		return err
 	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -123,8 +572,8 @@ func EnsureAdminUser(ctx context.Context, db *sql.DB, username, password string)
 
 	id := uuid.New()
 	_, err = db.ExecContext(ctx,
-		"INSERT INTO users (id, username, password_hash) VALUES ($1, $2, $3)",
-		id, username, string(hash),
+		"INSERT INTO users (id, username, password_hash, roles) VALUES ($1, $2, $3, $4)",
+		id, username, string(hash), pq.Array([]string{string(models.RoleAdmin)}),
 	)
 	if err != nil {
 		return err
@@ -133,3 +582,8 @@ func EnsureAdminUser(ctx context.Context, db *sql.DB, username, password string)
 	log.Println("Admin user created with username:", username)
 	return nil
 }
+
+// escapeLike escapes the LIKE wildcards in s so it is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
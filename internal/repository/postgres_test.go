@@ -2,17 +2,32 @@ package repository_test
 
 import (
 	"context"
+	"database/sql"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 
 	"github.com/dagherghinescu/companies/internal/models"
 	"github.com/dagherghinescu/companies/internal/repository"
 )
 
+const (
+	selectCompanies = `SELECT id, name, description, amount_of_employees, registered, type, version FROM companies `
+	softDelete      = `UPDATE companies SET deleted_at = NOW(), deleted_by = $1, version = version + 1 ` +
+		`WHERE id = $2 AND deleted_at IS NULL`
+)
+
+func companyRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"id", "name", "description", "amount_of_employees", "registered", "type", "version",
+	})
+}
+
 func TestPostgresRepo_Create(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -65,11 +80,11 @@ func TestPostgresRepo_GetByID(t *testing.T) {
 	registered := true
 	ctype := models.Corporation
 
-	rows := sqlmock.NewRows([]string{"id", "name", "description", "amount_of_employees", "registered", "type"}).
-		AddRow(id, name, description, employees, registered, ctype)
+	rows := companyRows().
+		AddRow(id, name, description, employees, registered, ctype, 3)
 
 	mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT id, name, description, amount_of_employees, registered, type FROM companies WHERE id = $1`)).
+		selectCompanies + `WHERE id = $1 AND deleted_at IS NULL`)).
 		WithArgs(id).
 		WillReturnRows(rows)
 
@@ -82,6 +97,7 @@ func TestPostgresRepo_GetByID(t *testing.T) {
 	require.Equal(t, employees, *got.AmountEmployees)
 	require.Equal(t, registered, *got.Registered)
 	require.Equal(t, ctype, *got.Type)
+	require.Equal(t, int64(3), got.Version)
 
 	require.NoError(t, mock.ExpectationsWereMet())
 }
@@ -99,16 +115,16 @@ func TestPostgresRepo_Patch(t *testing.T) {
 	}
 
 	mock.ExpectExec(regexp.QuoteMeta(
-		`UPDATE companies SET name = $1 WHERE id = $2`)).
+		`UPDATE companies SET name = $1, version = version + 1 WHERE id = $2 AND deleted_at IS NULL`)).
 		WithArgs(updates["name"], id).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	err = repo.Patch(context.Background(), id, updates)
+	err = repo.Patch(context.Background(), id, updates, nil)
 	require.NoError(t, err)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestPostgresRepo_Delete(t *testing.T) {
+func TestPostgresRepo_Patch_VersionMismatch(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	defer db.Close()
@@ -116,13 +132,298 @@ func TestPostgresRepo_Delete(t *testing.T) {
 	repo := repository.NewPostgresRepo(db)
 
 	id := uuid.New()
+	version := int64(2)
+	updates := map[string]interface{}{
+		"name": "New Name",
+	}
 
 	mock.ExpectExec(regexp.QuoteMeta(
-		`DELETE FROM companies WHERE id = $1`)).
+		`UPDATE companies SET name = $1, version = version + 1 WHERE id = $2 AND deleted_at IS NULL AND version = $3`)).
+		WithArgs(updates["name"], id, version).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM companies WHERE id = $1 AND deleted_at IS NULL)`)).
 		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	err = repo.Patch(context.Background(), id, updates, &version)
+	require.ErrorIs(t, err, repository.ErrVersionMismatch)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewPostgresRepo(db)
+
+	id := uuid.New()
+
+	mock.ExpectExec(regexp.QuoteMeta(
+		softDelete)).
+		WithArgs("user-1", id).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = repo.Delete(context.Background(), id, "user-1", nil)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_Restore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewPostgresRepo(db)
+
+	id := uuid.New()
+
+	mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE companies SET deleted_at = $1, deleted_by = $2, version = version + 1 `+
+			`WHERE id = $3 AND deleted_at IS NOT NULL`)).
+		WithArgs(nil, nil, id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.Restore(context.Background(), id)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_TakenNames(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewPostgresRepo(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT name FROM companies WHERE name IN ($1,$2) AND deleted_at IS NULL`)).
+		WithArgs("Acme", "Globex").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Acme"))
+
+	taken, err := repo.TakenNames(context.Background(), []string{"Acme", "Globex"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Acme"}, taken)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_Purge(t *testing.T) {
+	id := uuid.New()
+	deletedBefore := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name        string
+		setupMock   func(mock sqlmock.Sqlmock)
+		expectedIDs []uuid.UUID
+	}{
+		{
+			name: "redacts what the purged companies left behind",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM companies WHERE deleted_at < $1 RETURNING id`)).
+					WithArgs(deletedBefore).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE company_audit SET before = $1, after = $2 `+
+					`WHERE company_id = ANY($3) AND (before IS NOT NULL OR after IS NOT NULL)`)).
+					WithArgs(nil, nil, pq.Array([]uuid.UUID{id})).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM webhook_deliveries `+
+					`WHERE event_key = ANY($1) AND state <> $2`)).
+					WithArgs(pq.Array([]string{id.String()}), models.DeliveryPending).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedIDs: []uuid.UUID{id},
+		},
+		{
+			name: "nothing to purge",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM companies WHERE deleted_at < $1 RETURNING id`)).
+					WithArgs(deletedBefore).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			tt.setupMock(mock)
+			// The outbox events published and handed to webhooks are removed either way.
+			mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM outbox WHERE published_at IS NOT NULL ` +
+				`AND webhooks_enqueued_at IS NOT NULL AND created_at < $1`)).
+				WithArgs(deletedBefore).
+				WillReturnResult(sqlmock.NewResult(0, 5))
+
+			ids, err := repository.NewPostgresRepo(db).Purge(context.Background(), deletedBefore)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedIDs, ids)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresRepo_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewPostgresRepo(db)
+
+	ctype := models.Corporation
+	registered := true
+	minEmployees := 10
+	first, second := uuid.New(), uuid.New()
+
+	rows := companyRows().
+		AddRow(first, "Acme", "Sample", 42, true, ctype, 1).
+		AddRow(second, "Acme 2", "Sample", 50, true, ctype, 1)
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		selectCompanies+
+			`WHERE deleted_at IS NULL AND type = $1 AND registered = $2 AND amount_of_employees >= $3 `+
+			`AND name LIKE $4 `+
+			`ORDER BY name ASC, id ASC LIMIT 2`)).
+		WithArgs(ctype, registered, minEmployees, `Ac\_%`).
+		WillReturnRows(rows)
+
+	page, err := repo.List(context.Background(), repository.ListFilter{
+		Type:         &ctype,
+		Registered:   &registered,
+		MinEmployees: &minEmployees,
+		NamePrefix:   "Ac_",
+		Limit:        1,
+	})
+	require.NoError(t, err)
+	require.Len(t, page.Companies, 1)
+	require.Equal(t, first, page.Companies[0].ID)
+	require.NotEmpty(t, page.NextCursor)
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		selectCompanies+
+			`WHERE deleted_at IS NULL AND (name, id) > ($1, $2) ORDER BY name ASC, id ASC LIMIT 2`)).
+		WithArgs("Acme", first).
+		WillReturnRows(companyRows().
+			AddRow(second, "Acme 2", "Sample", 50, true, ctype, 1))
+
+	page, err = repo.List(context.Background(), repository.ListFilter{Limit: 1, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Companies, 1)
+	require.Equal(t, second, page.Companies[0].ID)
+	require.Empty(t, page.NextCursor)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_List_CursorForAnotherSort(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewPostgresRepo(db)
+
+	id := uuid.New()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT`)).
+		WillReturnRows(companyRows().
+			AddRow(id, "Acme", "Sample", 42, true, models.Corporation, 1).
+			AddRow(uuid.New(), "Beta", "Sample", 43, true, models.Corporation, 1))
+
+	page, err := repo.List(context.Background(), repository.ListFilter{Limit: 1})
+	require.NoError(t, err)
+
+	_, err = repo.List(context.Background(), repository.ListFilter{
+		Sort:   repository.SortByEmployees,
+		Cursor: page.NextCursor,
+	})
+	require.ErrorIs(t, err, repository.ErrInvalidCursor)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_Search(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewPostgresRepo(db)
+
+	id := uuid.New()
+	q := "acme"
+
+	rows := sqlmock.NewRows([]string{
+		"id", "name", "description", "amount_of_employees", "registered", "type", "version",
+		"rank", "name_highlight", "description_highlight",
+	}).AddRow(id, "Acme", "Sample", 42, true, models.Corporation, 1, 0.9, "<mark>Acme</mark>", "Sample")
+
+	mock.ExpectQuery(`SELECT id, name, description, amount_of_employees, registered, type, version, `+
+		`ts_rank\(.+ AS rank, `+
+		regexp.QuoteMeta(`ts_headline('english', replace(replace(replace(replace(replace(name, `+
+			`'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;'), `)+
+		`.+ FROM companies WHERE deleted_at IS NULL AND `+
+		`\(search_vector @@ .+ OR name % \$\d+ OR \$\d+ <% description\) `+
+		`ORDER BY rank DESC, id LIMIT 5`).
+		WithArgs(q, q, q, q, q, q, q, q).
+		WillReturnRows(rows)
+
+	hits, err := repo.Search(context.Background(), q, 5)
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	require.Equal(t, id, hits[0].Company.ID)
+	require.InDelta(t, 0.9, hits[0].Rank, 0.0001)
+	require.Equal(t, "<mark>Acme</mark>", hits[0].Highlights.Name)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepo_InTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewPostgresRepo(db)
+
+	id := uuid.New()
+	payload := []byte(`{"action":"deleted"}`)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(softDelete)).
+		WithArgs("", id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox (event_key,payload) VALUES ($1,$2)`)).
+		WithArgs(id.String(), payload).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = repo.InTx(context.Background(), func(tx repository.Company) error {
+		if err := tx.Delete(context.Background(), id, "", nil); err != nil {
+			return err
+		}
+		return tx.AddOutboxEvent(context.Background(), id.String(), payload)
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
 
-	err = repo.Delete(context.Background(), id)
+func TestPostgresRepo_InTx_RollbackWhenNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
+	defer db.Close()
+
+	repo := repository.NewPostgresRepo(db)
+
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(softDelete)).
+		WithArgs("", id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = repo.InTx(context.Background(), func(tx repository.Company) error {
+		if err := tx.Delete(context.Background(), id, "", nil); err != nil {
+			return err
+		}
+		return tx.AddOutboxEvent(context.Background(), id.String(), []byte(`{}`))
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
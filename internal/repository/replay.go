@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// replayColumns are the columns of event_replays scanned by scanReplays.
+const replayColumns = "id, company_type, company_ids, status, published, last_company_id, last_error, " +
+	"requested_by, created_at, updated_at, finished_at"
+
+// claimReplaySQL leases a running replay no runner holds, skipping the rows being claimed
+// by other runners. $1 is the runner, $2 the lease in seconds, $3 the replay or NULL for
+// the oldest one.
+const claimReplaySQL = `UPDATE event_replays
+SET locked_by = $1, locked_until = NOW() + make_interval(secs => $2), updated_at = NOW()
+WHERE id = (
+	SELECT id FROM event_replays
+	WHERE status = 'running' AND (locked_until IS NULL OR locked_until < NOW())
+		AND ($3::uuid IS NULL OR id = $3)
+	ORDER BY created_at
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING ` + replayColumns
+
+// Replays stores the event replays.
+// Get and SetStatus return sql.ErrNoRows when the replay does not exist.
+type Replays interface {
+	Create(ctx context.Context, r *models.Replay) error
+	// List returns up to limit replays, newest first.
+	List(ctx context.Context, limit uint64) ([]models.Replay, error)
+	Get(ctx context.Context, id uuid.UUID) (*models.Replay, error)
+	// SetStatus moves a replay in one of the statuses from to status, releasing its lease
+	// and clearing its error. It also returns sql.ErrNoRows when the replay is in none of them.
+	SetStatus(ctx context.Context, id uuid.UUID, status models.ReplayStatus, from ...models.ReplayStatus) error
+}
+
+// ReplayQueue gives the replay runners the replays to publish. A runner leases a replay
+// while it publishes it; the lease is extended as it progresses, and another runner can
+// take the replay over once it lapses.
+type ReplayQueue interface {
+	// Claim leases the running replay id, or the oldest one when id is nil, to runner.
+	// It returns nil when there is none that is not held by another runner.
+	Claim(ctx context.Context, runner uuid.UUID, id *uuid.UUID, lease time.Duration) (*models.Replay, error)
+	// Companies returns up to limit companies selected by r after its last company, ordered by ID.
+	Companies(ctx context.Context, r *models.Replay, limit uint64) ([]models.Company, error)
+	// Advance adds n to the published events of a replay held by runner, records last as
+	// its last company and extends its lease. It returns sql.ErrNoRows when the replay
+	// is no longer running or was taken over.
+	Advance(ctx context.Context, runner, id, last uuid.UUID, n int, lease time.Duration) error
+	// Finish ends a replay held by runner as completed, or as failed with reason.
+	Finish(ctx context.Context, runner, id uuid.UUID, status models.ReplayStatus, reason string) error
+}
+
+type replayRepo struct {
+	db        *sql.DB
+	sb        sq.StatementBuilderType
+	companies *postgresRepo
+}
+
+// NewReplayRepo creates a new Postgres backed replay store
+func NewReplayRepo(db *sql.DB) Replays {
+	return newReplayRepo(db)
+}
+
+// NewReplayQueue creates a new Postgres backed replay queue
+func NewReplayQueue(db *sql.DB) ReplayQueue {
+	return newReplayRepo(db)
+}
+
+func newReplayRepo(db *sql.DB) *replayRepo {
+	sb := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	return &replayRepo{
+		db:        db,
+		sb:        sb,
+		companies: &postgresRepo{db: db, conn: db, sb: sb},
+	}
+}
+
+// Create inserts a running replay and sets its timestamps.
+func (r *replayRepo) Create(ctx context.Context, rp *models.Replay) error {
+	if rp.CompanyIDs == nil {
+		rp.CompanyIDs = []uuid.UUID{}
+	}
+
+	query := r.sb.Insert("event_replays").
+		Columns("id", "company_type", "company_ids", "status", "requested_by").
+		Values(rp.ID, rp.Type, pq.Array(rp.CompanyIDs), rp.Status, rp.RequestedBy).
+		Suffix("RETURNING created_at, updated_at")
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return r.db.QueryRowContext(ctx, sqlStr, args...).Scan(&rp.CreatedAt, &rp.UpdatedAt)
+}
+
+// List returns the latest replays, newest first.
+func (r *replayRepo) List(ctx context.Context, limit uint64) ([]models.Replay, error) {
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		limit = MaxListLimit
+	}
+
+	query := r.sb.Select(replayColumns).From("event_replays").OrderBy("created_at DESC", "id DESC").Limit(limit)
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanReplays(rows)
+}
+
+// Get returns a replay.
+func (r *replayRepo) Get(ctx context.Context, id uuid.UUID) (*models.Replay, error) {
+	sqlStr, args, err := r.sb.Select(replayColumns).From("event_replays").Where(sq.Eq{"id": id}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	return firstReplay(rows)
+}
+
+// SetStatus changes the status of a replay that is in one of the from statuses.
+func (r *replayRepo) SetStatus(
+	ctx context.Context, id uuid.UUID, status models.ReplayStatus, from ...models.ReplayStatus,
+) error {
+	return r.exec(ctx, r.sb.Update("event_replays").
+		Set("status", status).
+		Set("last_error", nil).
+		Set("locked_by", nil).
+		Set("locked_until", nil).
+		Set("updated_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": id, "status": from}))
+}
+
+// Claim leases a running replay to runner.
+func (r *replayRepo) Claim(
+	ctx context.Context, runner uuid.UUID, id *uuid.UUID, lease time.Duration,
+) (*models.Replay, error) {
+	rows, err := r.db.QueryContext(ctx, claimReplaySQL, runner, lease.Seconds(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	rp, err := firstReplay(rows)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return rp, err
+}
+
+// Companies returns the next companies of a replay that are not deleted.
+func (r *replayRepo) Companies(ctx context.Context, rp *models.Replay, limit uint64) ([]models.Company, error) {
+	query := r.sb.Select("id", "name", "description", "amount_of_employees", "registered", "type", "version").
+		From("companies").
+		OrderBy("id").
+		Limit(limit)
+	query = filterCompanies(query, ListFilter{Type: rp.Type})
+	if len(rp.CompanyIDs) > 0 {
+		query = query.Where("id = ANY(?)", pq.Array(rp.CompanyIDs))
+	}
+	if rp.LastCompanyID != nil {
+		query = query.Where(sq.Gt{"id": *rp.LastCompanyID})
+	}
+
+	return r.companies.queryCompanies(ctx, query)
+}
+
+// Advance records the progress of a replay and extends its lease.
+func (r *replayRepo) Advance(
+	ctx context.Context, runner, id, last uuid.UUID, n int, lease time.Duration,
+) error {
+	return r.exec(ctx, r.sb.Update("event_replays").
+		Set("published", sq.Expr("published + ?", n)).
+		Set("last_company_id", last).
+		Set("locked_until", sq.Expr("NOW() + make_interval(secs => ?)", lease.Seconds())).
+		Set("updated_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": id, "locked_by": runner, "status": models.ReplayRunning}))
+}
+
+// Finish ends a replay and releases its lease.
+func (r *replayRepo) Finish(
+	ctx context.Context, runner, id uuid.UUID, status models.ReplayStatus, reason string,
+) error {
+	query := r.sb.Update("event_replays").
+		Set("status", status).
+		Set("last_error", sql.NullString{String: reason, Valid: reason != ""}).
+		Set("locked_by", nil).
+		Set("locked_until", nil).
+		Set("updated_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": id, "locked_by": runner, "status": models.ReplayRunning})
+	if status == models.ReplayCompleted {
+		query = query.Set("finished_at", sq.Expr("NOW()"))
+	}
+	return r.exec(ctx, query)
+}
+
+// exec runs a statement changing a single replay, returning sql.ErrNoRows when there was none.
+func (r *replayRepo) exec(ctx context.Context, query sq.UpdateBuilder) error {
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx, sqlStr, args...)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// firstReplay scans the replay of rows, returning sql.ErrNoRows when there is none.
+func firstReplay(rows *sql.Rows) (*models.Replay, error) {
+	replays, err := scanReplays(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(replays) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &replays[0], nil
+}
+
+// scanReplays reads the replayColumns of rows and closes them.
+func scanReplays(rows *sql.Rows) ([]models.Replay, error) {
+	defer rows.Close()
+
+	replays := make([]models.Replay, 0)
+	for rows.Next() {
+		var (
+			rp        models.Replay
+			ids       []string
+			lastError sql.NullString
+		)
+		err := rows.Scan(&rp.ID, &rp.Type, pq.Array(&ids), &rp.Status, &rp.Published, &rp.LastCompanyID,
+			&lastError, &rp.RequestedBy, &rp.CreatedAt, &rp.UpdatedAt, &rp.FinishedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		rp.LastError = lastError.String
+		rp.CompanyIDs = make([]uuid.UUID, len(ids))
+		for i, id := range ids {
+			if rp.CompanyIDs[i], err = uuid.Parse(id); err != nil {
+				return nil, err
+			}
+		}
+		replays = append(replays, rp)
+	}
+
+	return replays, rows.Err()
+}
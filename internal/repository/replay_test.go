@@ -0,0 +1,133 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+func replayRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"id", "company_type", "company_ids", "status", "published", "last_company_id", "last_error",
+		"requested_by", "created_at", "updated_at", "finished_at",
+	})
+}
+
+func TestReplayQueue_Claim(t *testing.T) {
+	runner, id, company := uuid.New(), uuid.New(), uuid.New()
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		id       *uuid.UUID
+		rows     *sqlmock.Rows
+		expected *models.Replay
+	}{
+		{
+			name: "oldest running replay",
+			rows: replayRows().AddRow(id, nil, "{"+company.String()+"}", "running", 100, company, nil,
+				"admin", now, now, nil),
+			expected: &models.Replay{
+				ID: id, CompanyIDs: []uuid.UUID{company}, Status: models.ReplayRunning, Published: 100,
+				LastCompanyID: &company, RequestedBy: "admin", CreatedAt: now, UpdatedAt: now,
+			},
+		},
+		{name: "held by another runner", id: &id, rows: replayRows()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectQuery(`UPDATE event_replays SET locked_by = \$1, locked_until = NOW\(\) \+ `+
+				`make_interval\(secs => \$2\).* FOR UPDATE SKIP LOCKED \) RETURNING id, company_type`).
+				WithArgs(runner, float64(60), tt.id).
+				WillReturnRows(tt.rows)
+
+			rp, err := repository.NewReplayQueue(db).Claim(context.Background(), runner, tt.id, time.Minute)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, rp)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReplayQueue_Companies(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctype := models.Cooperative
+	first, last := uuid.New(), uuid.New()
+	rp := &models.Replay{Type: &ctype, CompanyIDs: []uuid.UUID{first}, LastCompanyID: &last}
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		selectCompanies+`WHERE deleted_at IS NULL AND type = $1 AND id = ANY($2) AND id > $3 ORDER BY id LIMIT 50`)).
+		WithArgs(ctype, pq.Array([]uuid.UUID{first}), last).
+		WillReturnRows(companyRows().AddRow(first, "Acme", "Sample", 42, true, ctype, 1))
+
+	companies, err := repository.NewReplayQueue(db).Companies(context.Background(), rp, 50)
+	require.NoError(t, err)
+	require.Len(t, companies, 1)
+	require.Equal(t, first, companies[0].ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReplayQueue_Advance(t *testing.T) {
+	tests := []struct {
+		name        string
+		affected    int64
+		expectedErr error
+	}{
+		{name: "advanced", affected: 1},
+		{name: "paused or taken over", expectedErr: sql.ErrNoRows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			runner, id, last := uuid.New(), uuid.New(), uuid.New()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE event_replays SET published = published + $1, `+
+				`last_company_id = $2, locked_until = NOW() + make_interval(secs => $3), updated_at = NOW() `+
+				`WHERE id = $4 AND locked_by = $5 AND status = $6`)).
+				WithArgs(100, last, float64(60), id, runner, models.ReplayRunning).
+				WillReturnResult(sqlmock.NewResult(0, tt.affected))
+
+			err = repository.NewReplayQueue(db).Advance(context.Background(), runner, id, last, 100, time.Minute)
+			require.ErrorIs(t, err, tt.expectedErr)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReplayRepo_SetStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	id := uuid.New()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE event_replays SET status = $1, last_error = $2, locked_by = $3, `+
+		`locked_until = $4, updated_at = NOW() WHERE id = $5 AND status IN ($6,$7)`)).
+		WithArgs(models.ReplayRunning, nil, nil, nil, id, models.ReplayPaused, models.ReplayFailed).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repository.NewReplayRepo(db).SetStatus(context.Background(), id, models.ReplayRunning,
+		models.ReplayPaused, models.ReplayFailed)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// ErrTokenReused is returned when a refresh token that was already exchanged is presented again.
+var ErrTokenReused = errors.New("refresh token reused")
+
+// RefreshToken is a single use token exchanged for a new access token and its successor.
+// Tokens rotated from the one issued at login belong to its family.
+type RefreshToken struct {
+	ID       uuid.UUID
+	FamilyID uuid.UUID
+	UserID   uuid.UUID
+	// Hash is the SHA-256 of the token, which is never stored.
+	Hash      string
+	ExpiresAt time.Time
+	// Roles are the current roles of the user, returned by RotateRefreshToken.
+	Roles []models.Role
+}
+
+// Tokens stores the refresh tokens and the access tokens revoked before they expire.
+type Tokens interface {
+	// CreateRefreshToken stores the first token of a family, and drops the expired
+	// tokens of its user.
+	CreateRefreshToken(ctx context.Context, t *RefreshToken) error
+	// RotateRefreshToken marks the token with the given hash as used and stores next in
+	// its family, returning the used token. It returns sql.ErrNoRows when the token is
+	// unknown, expired or revoked, and ErrTokenReused, once the whole family is revoked,
+	// when it was already used.
+	RotateRefreshToken(ctx context.Context, hash string, next *RefreshToken) (*RefreshToken, error)
+	// RevokeFamily revokes the family of the token with the given hash, when userID owns it.
+	RevokeFamily(ctx context.Context, userID uuid.UUID, hash string) error
+	// RevokeAccessToken denies the access token jti until it expires, and forgets the
+	// revoked tokens that expired since.
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	// IsRevoked reports whether the access token jti was revoked.
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type tokenRepo struct {
+	db *sql.DB
+	sb sq.StatementBuilderType
+}
+
+// NewTokenRepo creates a new Postgres backed token store
+func NewTokenRepo(db *sql.DB) Tokens {
+	return &tokenRepo{
+		db: db,
+		sb: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *tokenRepo) CreateRefreshToken(ctx context.Context, t *RefreshToken) error {
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		expired := r.sb.Delete("refresh_tokens").
+			Where(sq.Eq{"user_id": t.UserID}).
+			Where("expires_at < NOW()")
+		if err := execTx(ctx, tx, expired); err != nil {
+			return err
+		}
+		return execTx(ctx, tx, r.insert(t))
+	})
+}
+
+// RotateRefreshToken locks the token, so that concurrent exchanges of the same token
+// are told apart: the first one rotates it, the others revoke its family.
+func (r *tokenRepo) RotateRefreshToken(ctx context.Context, hash string, next *RefreshToken) (*RefreshToken, error) {
+	var (
+		used    *RefreshToken
+		reused  bool
+		invalid bool
+	)
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		var (
+			wasUsed, revoked bool
+			err              error
+		)
+		used, wasUsed, revoked, err = r.lock(ctx, tx, hash)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case revoked || !used.ExpiresAt.After(time.Now()):
+			invalid = true
+			return nil
+		case wasUsed:
+			reused = true
+			return execTx(ctx, tx, r.revokeFamily(used.FamilyID))
+		}
+
+		next.FamilyID, next.UserID = used.FamilyID, used.UserID
+
+		rotated := r.sb.Update("refresh_tokens").
+			Set("used_at", sq.Expr("NOW()")).
+			Where(sq.Eq{"id": used.ID})
+		if err := execTx(ctx, tx, rotated); err != nil {
+			return err
+		}
+		return execTx(ctx, tx, r.insert(next))
+	})
+
+	switch {
+	case err != nil:
+		return nil, err
+	case invalid:
+		return nil, sql.ErrNoRows
+	case reused:
+		return nil, ErrTokenReused
+	}
+	return used, nil
+}
+
+func (r *tokenRepo) RevokeFamily(ctx context.Context, userID uuid.UUID, hash string) error {
+	family := r.sb.Select("family_id").
+		From("refresh_tokens").
+		Where(sq.Eq{"token_hash": hash, "user_id": userID})
+
+	query := r.sb.Update("refresh_tokens").
+		Set("revoked_at", sq.Expr("NOW()")).
+		Where(sq.Expr("family_id = (?)", family)).
+		Where("revoked_at IS NULL")
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+func (r *tokenRepo) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		expired := r.sb.Delete("revoked_tokens").Where("expires_at < NOW()")
+		if err := execTx(ctx, tx, expired); err != nil {
+			return err
+		}
+
+		revoked := r.sb.Insert("revoked_tokens").
+			Columns("jti", "expires_at").
+			Values(jti, expiresAt).
+			Suffix("ON CONFLICT (jti) DO NOTHING")
+		return execTx(ctx, tx, revoked)
+	})
+}
+
+func (r *tokenRepo) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)", jti,
+	).Scan(&revoked)
+	return revoked, err
+}
+
+// lock selects the token with the given hash for update, along with the roles of its user,
+// and whether it was used or revoked.
+func (r *tokenRepo) lock(ctx context.Context, tx *sql.Tx, hash string) (*RefreshToken, bool, bool, error) {
+	query := r.sb.Select("t.id", "t.family_id", "t.user_id", "t.expires_at", "t.used_at IS NOT NULL",
+		"t.revoked_at IS NOT NULL", "u.roles").
+		From("refresh_tokens t").
+		Join("users u ON u.id = t.user_id").
+		Where(sq.Eq{"t.token_hash": hash}).
+		Suffix("FOR UPDATE OF t")
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	t := &RefreshToken{Hash: hash}
+	var (
+		used, revoked bool
+		roles         []string
+	)
+	err = tx.QueryRowContext(ctx, sqlStr, args...).Scan(&t.ID, &t.FamilyID, &t.UserID, &t.ExpiresAt,
+		&used, &revoked, pq.Array(&roles))
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	t.Roles = make([]models.Role, len(roles))
+	for i, role := range roles {
+		t.Roles[i] = models.Role(role)
+	}
+	return t, used, revoked, nil
+}
+
+func (r *tokenRepo) insert(t *RefreshToken) sq.InsertBuilder {
+	return r.sb.Insert("refresh_tokens").
+		Columns("id", "family_id", "user_id", "token_hash", "expires_at").
+		Values(t.ID, t.FamilyID, t.UserID, t.Hash, t.ExpiresAt)
+}
+
+func (r *tokenRepo) revokeFamily(family uuid.UUID) sq.UpdateBuilder {
+	return r.sb.Update("refresh_tokens").
+		Set("revoked_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"family_id": family}).
+		Where("revoked_at IS NULL")
+}
@@ -0,0 +1,124 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+// lockTokenSQL matches the query locking a refresh token.
+const lockTokenSQL = `SELECT t.id, .* FROM refresh_tokens t JOIN users u ON u.id = t.user_id ` +
+	`WHERE t.token_hash = \$1 FOR UPDATE OF t`
+
+func TestTokenRepo_RotateRefreshToken(t *testing.T) {
+	id, family, user := uuid.New(), uuid.New(), uuid.New()
+	valid := time.Now().Add(time.Hour)
+
+	lockRows := func(expiresAt time.Time, used, revoked bool) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "family_id", "user_id", "expires_at", "used", "revoked", "roles"}).
+			AddRow(id, family, user, expiresAt, used, revoked, "{editor}")
+	}
+
+	tests := []struct {
+		name        string
+		setupMock   func(mock sqlmock.Sqlmock, next *repository.RefreshToken)
+		expectedErr error
+	}{
+		{
+			name: "rotated",
+			setupMock: func(mock sqlmock.Sqlmock, next *repository.RefreshToken) {
+				mock.ExpectQuery(lockTokenSQL).WithArgs("hash").WillReturnRows(lockRows(valid, false, false))
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1`)).
+					WithArgs(id).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO refresh_tokens `+
+					`(id,family_id,user_id,token_hash,expires_at) VALUES ($1,$2,$3,$4,$5)`)).
+					WithArgs(next.ID, family, user, "next-hash", next.ExpiresAt).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "reused",
+			setupMock: func(mock sqlmock.Sqlmock, _ *repository.RefreshToken) {
+				mock.ExpectQuery(lockTokenSQL).WithArgs("hash").WillReturnRows(lockRows(valid, true, false))
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE refresh_tokens SET revoked_at = NOW() ` +
+					`WHERE family_id = $1 AND revoked_at IS NULL`)).
+					WithArgs(family).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			expectedErr: repository.ErrTokenReused,
+		},
+		{
+			name: "revoked",
+			setupMock: func(mock sqlmock.Sqlmock, _ *repository.RefreshToken) {
+				mock.ExpectQuery(lockTokenSQL).WithArgs("hash").WillReturnRows(lockRows(valid, true, true))
+				mock.ExpectCommit()
+			},
+			expectedErr: sql.ErrNoRows,
+		},
+		{
+			name: "expired",
+			setupMock: func(mock sqlmock.Sqlmock, _ *repository.RefreshToken) {
+				mock.ExpectQuery(lockTokenSQL).WithArgs("hash").
+					WillReturnRows(lockRows(time.Now().Add(-time.Minute), false, false))
+				mock.ExpectCommit()
+			},
+			expectedErr: sql.ErrNoRows,
+		},
+		{
+			name: "unknown",
+			setupMock: func(mock sqlmock.Sqlmock, _ *repository.RefreshToken) {
+				mock.ExpectQuery(lockTokenSQL).WithArgs("hash").WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			expectedErr: sql.ErrNoRows,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			next := &repository.RefreshToken{ID: uuid.New(), Hash: "next-hash", ExpiresAt: valid}
+			mock.ExpectBegin()
+			tt.setupMock(mock, next)
+
+			used, err := repository.NewTokenRepo(db).RotateRefreshToken(context.Background(), "hash", next)
+			require.ErrorIs(t, err, tt.expectedErr)
+			if tt.expectedErr == nil {
+				require.Equal(t, user, used.UserID)
+				require.Equal(t, []models.Role{models.RoleEditor}, used.Roles)
+				require.Equal(t, family, next.FamilyID)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTokenRepo_RevokeFamily(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	user := uuid.New()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = `+
+		`(SELECT family_id FROM refresh_tokens WHERE token_hash = $1 AND user_id = $2) AND revoked_at IS NULL`)).
+		WithArgs("hash", user).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	require.NoError(t, repository.NewTokenRepo(db).RevokeFamily(context.Background(), user, "hash"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,425 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/dagherghinescu/companies/internal/models"
+)
+
+// webhookLockID is the Postgres advisory lock key held by the dispatcher delivering webhooks.
+const webhookLockID = 7_301_245_002
+
+// enqueueWebhooksSQL fans the outbox events not yet handed to webhooks out to a delivery
+// per enabled webhook subscribed to their action, and marks them as handed over.
+// The action is read from the data of the CloudEvent, or from the top level of the
+// events written before they were CloudEvents.
+const enqueueWebhooksSQL = `WITH events AS (
+	SELECT id, event_key, payload FROM outbox
+	WHERE webhooks_enqueued_at IS NULL
+	ORDER BY id
+	LIMIT $1
+	FOR UPDATE
+), deliveries AS (
+	INSERT INTO webhook_deliveries (webhook_id, event_id, event_key, payload)
+	SELECT w.id, e.id, e.event_key, e.payload
+	FROM events e
+	JOIN webhooks w ON w.disabled_at IS NULL
+		AND (cardinality(w.events) = 0 OR COALESCE(e.payload->'data'->>'action', e.payload->>'action') = ANY(w.events))
+	ORDER BY e.id, w.id
+)
+UPDATE outbox SET webhooks_enqueued_at = NOW() WHERE id IN (SELECT id FROM events)`
+
+// DeliveryPage is a page of webhook deliveries, newest first.
+// NextCursor is empty when there are no older deliveries.
+type DeliveryPage struct {
+	Deliveries []models.WebhookDelivery `json:"deliveries"`
+	NextCursor string                   `json:"next_cursor,omitempty"`
+}
+
+// Webhooks stores the webhook subscriptions and their delivery log.
+// Get, Delete and Enable return sql.ErrNoRows when the webhook does not exist.
+type Webhooks interface {
+	Create(ctx context.Context, w *models.Webhook) error
+	List(ctx context.Context) ([]models.Webhook, error)
+	Get(ctx context.Context, id uuid.UUID) (*models.Webhook, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	// Enable lets a disabled webhook receive events again and resets its failure count.
+	Enable(ctx context.Context, id uuid.UUID) error
+	Deliveries(ctx context.Context, webhookID uuid.UUID, limit uint64, cursor string) (*DeliveryPage, error)
+}
+
+// PendingDelivery is a delivery due to be sent, with the endpoint it goes to.
+type PendingDelivery struct {
+	ID        int64
+	WebhookID uuid.UUID
+	URL       string
+	Secret    string
+	EventKey  string
+	Payload   []byte
+	Attempts  int
+}
+
+// DeliveryFailure describes a failed delivery attempt.
+type DeliveryFailure struct {
+	// Status is the HTTP status of the response, zero when none was received.
+	Status int
+	Reason string
+	// RetryAt is when to try again; nil gives up on the delivery.
+	RetryAt *time.Time
+	// DisableAfter is the number of consecutive failures after which the webhook is disabled.
+	DisableAfter int
+}
+
+// WebhookQueue gives the dispatcher access to the deliveries waiting to be sent.
+type WebhookQueue interface {
+	// Lock takes the dispatcher lock so only one instance delivers at a time.
+	// When acquired is true, release must be called once the batch is done.
+	Lock(ctx context.Context) (release func(), acquired bool, err error)
+	// Enqueue creates the deliveries of up to limit outbox events and returns how many events it handled.
+	Enqueue(ctx context.Context, limit uint64) (int64, error)
+	// Pending returns up to limit deliveries that are due, ordered by creation.
+	// Deliveries whose webhook and key have an earlier delivery still backing off are skipped.
+	Pending(ctx context.Context, limit uint64) ([]PendingDelivery, error)
+	// MarkDelivered records a successful attempt and resets the failure count of the webhook.
+	MarkDelivered(ctx context.Context, d *PendingDelivery, status int) error
+	// MarkFailed records a failed attempt and reports whether it got the webhook disabled.
+	MarkFailed(ctx context.Context, d *PendingDelivery, f *DeliveryFailure) (disabled bool, err error)
+}
+
+type webhookRepo struct {
+	db *sql.DB
+	sb sq.StatementBuilderType
+}
+
+// NewWebhookRepo creates a new Postgres backed webhook store
+func NewWebhookRepo(db *sql.DB) Webhooks {
+	return newWebhookRepo(db)
+}
+
+// NewWebhookQueue creates a new Postgres backed webhook delivery queue
+func NewWebhookQueue(db *sql.DB) WebhookQueue {
+	return newWebhookRepo(db)
+}
+
+func newWebhookRepo(db *sql.DB) *webhookRepo {
+	return &webhookRepo{
+		db: db,
+		sb: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+// Create inserts a webhook and sets its creation time.
+func (r *webhookRepo) Create(ctx context.Context, w *models.Webhook) error {
+	query := r.sb.Insert("webhooks").
+		Columns("id", "url", "secret", "events").
+		Values(w.ID, w.URL, w.Secret, pq.Array(w.Events)).
+		Suffix("RETURNING created_at")
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return r.db.QueryRowContext(ctx, sqlStr, args...).Scan(&w.CreatedAt)
+}
+
+// List returns every webhook, oldest first, without their secrets.
+func (r *webhookRepo) List(ctx context.Context) ([]models.Webhook, error) {
+	return r.queryWebhooks(ctx, r.selectWebhooks().OrderBy("created_at", "id"))
+}
+
+// Get returns a webhook without its secret.
+func (r *webhookRepo) Get(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	webhooks, err := r.queryWebhooks(ctx, r.selectWebhooks().Where(sq.Eq{"id": id}))
+	if err != nil {
+		return nil, err
+	}
+	if len(webhooks) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &webhooks[0], nil
+}
+
+func (r *webhookRepo) selectWebhooks() sq.SelectBuilder {
+	return r.sb.Select("id", "url", "events", "consecutive_failures", "disabled_at", "created_at").
+		From("webhooks")
+}
+
+func (r *webhookRepo) queryWebhooks(ctx context.Context, query sq.SelectBuilder) ([]models.Webhook, error) {
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]models.Webhook, 0)
+	for rows.Next() {
+		var w models.Webhook
+		var events []string
+		err := rows.Scan(&w.ID, &w.URL, pq.Array(&events), &w.ConsecutiveFailures, &w.DisabledAt, &w.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		w.Events = make([]models.AuditAction, len(events))
+		for i, e := range events {
+			w.Events[i] = models.AuditAction(e)
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// Delete removes a webhook together with its deliveries.
+func (r *webhookRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.exec(ctx, r.sb.Delete("webhooks").Where(sq.Eq{"id": id}))
+}
+
+// Enable clears the disabled flag and the failure count of a webhook.
+func (r *webhookRepo) Enable(ctx context.Context, id uuid.UUID) error {
+	return r.exec(ctx, r.sb.Update("webhooks").
+		Set("disabled_at", nil).
+		Set("consecutive_failures", 0).
+		Where(sq.Eq{"id": id}))
+}
+
+// exec runs a statement changing a single webhook, returning sql.ErrNoRows when there was none.
+func (r *webhookRepo) exec(ctx context.Context, query sq.Sqlizer) error {
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx, sqlStr, args...)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Deliveries returns the delivery log of a webhook, newest first.
+// The cursor is the NextCursor of the previous page.
+func (r *webhookRepo) Deliveries(
+	ctx context.Context, webhookID uuid.UUID, limit uint64, cursor string,
+) (*DeliveryPage, error) {
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		limit = MaxListLimit
+	}
+
+	query := r.sb.Select("id", "event_key", "payload", "state", "attempts", "response_status", "last_error",
+		"next_attempt_at", "created_at", "delivered_at").
+		From("webhook_deliveries").
+		Where(sq.Eq{"webhook_id": webhookID}).
+		OrderBy("id DESC").
+		Limit(limit + 1)
+
+	if cursor != "" {
+		before, err := decodeHistoryCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(sq.Lt{"id": before})
+	}
+
+	deliveries, err := r.queryDeliveries(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &DeliveryPage{Deliveries: deliveries}
+	if uint64(len(deliveries)) > limit {
+		page.Deliveries = deliveries[:limit]
+		page.NextCursor = encodeHistoryCursor(page.Deliveries[limit-1].ID)
+	}
+
+	return page, nil
+}
+
+func (r *webhookRepo) queryDeliveries(ctx context.Context, query sq.SelectBuilder) ([]models.WebhookDelivery, error) {
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		var (
+			d         models.WebhookDelivery
+			status    sql.NullInt64
+			lastError sql.NullString
+			payload   []byte
+		)
+		err := rows.Scan(&d.ID, &d.EventKey, &payload, &d.State, &d.Attempts, &status, &lastError,
+			&d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = payload
+		d.LastError = lastError.String
+		if status.Valid {
+			s := int(status.Int64)
+			d.ResponseStatus = &s
+		}
+		if d.State != models.DeliveryPending {
+			d.NextAttemptAt = nil
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// Lock takes a session level advisory lock on a dedicated connection.
+func (r *webhookRepo) Lock(ctx context.Context) (func(), bool, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var acquired bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", webhookLockID).Scan(&acquired)
+	if err != nil || !acquired {
+		_ = conn.Close()
+		return nil, false, err
+	}
+
+	release := func() {
+		_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", webhookLockID)
+		_ = conn.Close()
+	}
+	return release, true, nil
+}
+
+// Enqueue creates the deliveries of the outbox events not handed to webhooks yet.
+func (r *webhookRepo) Enqueue(ctx context.Context, limit uint64) (int64, error) {
+	res, err := r.db.ExecContext(ctx, enqueueWebhooksSQL, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Pending returns the deliveries due for sending to enabled webhooks.
+func (r *webhookRepo) Pending(ctx context.Context, limit uint64) ([]PendingDelivery, error) {
+	query := r.sb.Select("d.id", "d.webhook_id", "w.url", "w.secret", "d.event_key", "d.payload", "d.attempts").
+		From("webhook_deliveries d").
+		Join("webhooks w ON w.id = d.webhook_id").
+		Where(sq.Eq{"d.state": models.DeliveryPending}).
+		Where("w.disabled_at IS NULL").
+		Where("d.next_attempt_at <= NOW()").
+		Where(`NOT EXISTS (
+			SELECT 1 FROM webhook_deliveries p
+			WHERE p.webhook_id = d.webhook_id AND p.event_key = d.event_key AND p.state = d.state
+				AND p.id < d.id AND p.next_attempt_at > NOW()
+		)`).
+		OrderBy("d.id").
+		Limit(limit)
+
+	sqlStr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []PendingDelivery
+	for rows.Next() {
+		var d PendingDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.URL, &d.Secret, &d.EventKey, &d.Payload, &d.Attempts); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// MarkDelivered flags the delivery as sent and resets the failure count of its webhook.
+func (r *webhookRepo) MarkDelivered(ctx context.Context, d *PendingDelivery, status int) error {
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		delivery := r.sb.Update("webhook_deliveries").
+			Set("state", models.DeliveryDelivered).
+			Set("attempts", sq.Expr("attempts + 1")).
+			Set("response_status", status).
+			Set("last_error", nil).
+			Set("delivered_at", sq.Expr("NOW()")).
+			Where(sq.Eq{"id": d.ID})
+		if err := execTx(ctx, tx, delivery); err != nil {
+			return err
+		}
+
+		webhook := r.sb.Update("webhooks").
+			Set("consecutive_failures", 0).
+			Where(sq.Eq{"id": d.WebhookID})
+		return execTx(ctx, tx, webhook)
+	})
+}
+
+// MarkFailed records a failed attempt, and disables the webhook once it failed
+// f.DisableAfter times in a row. It reports whether this attempt disabled it.
+func (r *webhookRepo) MarkFailed(ctx context.Context, d *PendingDelivery, f *DeliveryFailure) (bool, error) {
+	var disabled bool
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		delivery := r.sb.Update("webhook_deliveries").
+			Set("attempts", sq.Expr("attempts + 1")).
+			Set("response_status", sql.NullInt64{Int64: int64(f.Status), Valid: f.Status != 0}).
+			Set("last_error", f.Reason).
+			Where(sq.Eq{"id": d.ID})
+		if f.RetryAt != nil {
+			delivery = delivery.Set("next_attempt_at", *f.RetryAt)
+		} else {
+			delivery = delivery.Set("state", models.DeliveryFailed)
+		}
+		if err := execTx(ctx, tx, delivery); err != nil {
+			return err
+		}
+
+		webhook := r.sb.Update("webhooks").
+			Set("consecutive_failures", sq.Expr("consecutive_failures + 1")).
+			Set("disabled_at", sq.Expr(
+				"CASE WHEN consecutive_failures + 1 >= ? THEN COALESCE(disabled_at, NOW()) ELSE disabled_at END",
+				f.DisableAfter,
+			)).
+			Where(sq.Eq{"id": d.WebhookID}).
+			Suffix("RETURNING consecutive_failures = ?", f.DisableAfter)
+
+		sqlStr, args, err := webhook.ToSql()
+		if err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, sqlStr, args...).Scan(&disabled)
+	})
+	return disabled, err
+}
@@ -0,0 +1,102 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/models"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+func TestWebhookQueue_MarkFailed(t *testing.T) {
+	delivery := &repository.PendingDelivery{ID: 7, WebhookID: uuid.New()}
+	retryAt := time.Now().Add(time.Minute)
+
+	tests := []struct {
+		name             string
+		failure          *repository.DeliveryFailure
+		setupMock        func(mock sqlmock.Sqlmock)
+		expectedDisabled bool
+	}{
+		{
+			name:    "retried",
+			failure: &repository.DeliveryFailure{Status: 500, Reason: "boom", RetryAt: &retryAt, DisableAfter: 20},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE webhook_deliveries SET attempts = attempts \+ 1, response_status = \$1, `+
+					`last_error = \$2, next_attempt_at = \$3 WHERE id = \$4`).
+					WithArgs(sql.NullInt64{Int64: 500, Valid: true}, "boom", retryAt, int64(7)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(`UPDATE webhooks SET consecutive_failures = consecutive_failures \+ 1, `+
+					`disabled_at = CASE WHEN consecutive_failures \+ 1 >= \$1 .* WHERE id = \$2 `+
+					`RETURNING consecutive_failures = \$3`).
+					WithArgs(20, delivery.WebhookID, 20).
+					WillReturnRows(sqlmock.NewRows([]string{"disabled"}).AddRow(false))
+			},
+		},
+		{
+			name:    "given up and disabled",
+			failure: &repository.DeliveryFailure{Reason: "connection refused", DisableAfter: 20},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(`UPDATE webhook_deliveries SET attempts = attempts \+ 1, response_status = \$1, `+
+					`last_error = \$2, state = \$3 WHERE id = \$4`).
+					WithArgs(sql.NullInt64{}, "connection refused", models.DeliveryFailed, int64(7)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(`UPDATE webhooks`).
+					WithArgs(20, delivery.WebhookID, 20).
+					WillReturnRows(sqlmock.NewRows([]string{"disabled"}).AddRow(true))
+			},
+			expectedDisabled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tt.setupMock(mock)
+			mock.ExpectCommit()
+
+			disabled, err := repository.NewWebhookQueue(db).MarkFailed(context.Background(), delivery, tt.failure)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedDisabled, disabled)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestWebhookRepo_Delete(t *testing.T) {
+	tests := []struct {
+		name        string
+		affected    int64
+		expectedErr error
+	}{
+		{name: "deleted", affected: 1},
+		{name: "not found", expectedErr: sql.ErrNoRows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			id := uuid.New()
+			mock.ExpectExec(`DELETE FROM webhooks WHERE id = \$1`).
+				WithArgs(id).
+				WillReturnResult(sqlmock.NewResult(0, tt.affected))
+
+			err = repository.NewWebhookRepo(db).Delete(context.Background(), id)
+			require.ErrorIs(t, err, tt.expectedErr)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
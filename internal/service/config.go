@@ -3,17 +3,25 @@ package service
 import (
 	"fmt"
 
+	"github.com/dagherghinescu/companies/internal/app"
+	grpcapi "github.com/dagherghinescu/companies/internal/grpc"
 	api "github.com/dagherghinescu/companies/internal/http"
 	"github.com/dagherghinescu/companies/internal/http/middleware"
 	"github.com/dagherghinescu/companies/internal/kafka"
+	"github.com/dagherghinescu/companies/internal/replay"
 	"github.com/dagherghinescu/companies/internal/repository"
+	"github.com/dagherghinescu/companies/internal/webhook"
 )
 
 type config struct {
-	httpSrv  *api.Config
-	dbCfg    *repository.Config
-	jwtCfg   *middleware.JWTConfig
-	kafkaCfg *kafka.Config
+	appCfg    *app.Config
+	httpSrv   *api.Config
+	grpcSrv   *grpcapi.Config
+	dbCfg     *repository.Config
+	jwtCfg    *middleware.JWTConfig
+	kafkaCfg  *kafka.Config
+	hookCfg   *webhook.Config
+	replayCfg *replay.Config
 }
 
 func validateConfigs() (*config, error) {
@@ -22,6 +30,11 @@ func validateConfigs() (*config, error) {
 		return nil, fmt.Errorf("server configuration error: %w", err)
 	}
 
+	grpcConfig, err := grpcapi.EnvConfig()
+	if err != nil {
+		return nil, fmt.Errorf("grpc server configuration error: %w", err)
+	}
+
 	pgCfg, err := repository.EnvConfig()
 	if err != nil {
 		return nil, fmt.Errorf("database configuration error: %w", err)
@@ -36,11 +49,34 @@ func validateConfigs() (*config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("kafka config error: %w", err)
 	}
+	if err := kafkaCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("kafka config error: %w", err)
+	}
+
+	hookCfg, err := webhook.EnvConfig()
+	if err != nil {
+		return nil, fmt.Errorf("webhook config error: %w", err)
+	}
+
+	replayCfg, err := replay.EnvConfig()
+	if err != nil {
+		return nil, fmt.Errorf("replay config error: %w", err)
+	}
+
+	appCfg, err := app.EnvConfig()
+	if err != nil {
+		return nil, fmt.Errorf("app config error: %w", err)
+	}
+	appCfg.AllowPrivateWebhooks = hookCfg.AllowPrivateTargets
 
 	return &config{
-		httpSrv:  srvConfig,
-		dbCfg:    pgCfg,
-		jwtCfg:   jwtCfg,
-		kafkaCfg: kafkaCfg,
+		appCfg:    appCfg,
+		httpSrv:   srvConfig,
+		grpcSrv:   grpcConfig,
+		dbCfg:     pgCfg,
+		jwtCfg:    jwtCfg,
+		kafkaCfg:  kafkaCfg,
+		hookCfg:   hookCfg,
+		replayCfg: replayCfg,
 	}, nil
 }
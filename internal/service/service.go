@@ -4,30 +4,47 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"expvar"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
 	"github.com/dagherghinescu/companies/internal/app"
+	"github.com/dagherghinescu/companies/internal/commands"
+	grpcapi "github.com/dagherghinescu/companies/internal/grpc"
 	api "github.com/dagherghinescu/companies/internal/http"
 	"github.com/dagherghinescu/companies/internal/http/middleware"
+	"github.com/dagherghinescu/companies/internal/http/openapi"
 	"github.com/dagherghinescu/companies/internal/http/routes"
 	"github.com/dagherghinescu/companies/internal/kafka"
 	"github.com/dagherghinescu/companies/internal/logger"
+	"github.com/dagherghinescu/companies/internal/migrations"
+	"github.com/dagherghinescu/companies/internal/replay"
 	"github.com/dagherghinescu/companies/internal/repository"
+	"github.com/dagherghinescu/companies/internal/webhook"
 )
 
 // Service holds the application dependencies and configuration.
 type Service struct {
-	Log           *zap.Logger
-	APICfg        *api.Config
-	Repo          *repository.Company
-	JWTCfg        *middleware.JWTConfig
-	KafkaProducer *kafka.Producer
-	DB            *sql.DB
+	Log               *zap.Logger
+	AppCfg            *app.Config
+	APICfg            *api.Config
+	GRPCCfg           *grpcapi.Config
+	Repo              *repository.Company
+	Webhooks          repository.Webhooks
+	Replays           repository.Replays
+	JWTCfg            *middleware.JWTConfig
+	KafkaCfg          *kafka.Config
+	KafkaProducer     *kafka.ResilientProducer
+	OutboxRelay       *kafka.Relay
+	WebhookDispatcher *webhook.Dispatcher
+	ReplayRunner      *replay.Runner
+	DB                *sql.DB
 }
 
 // New creates a new Service instance, initializing logger and configuration.
@@ -52,6 +69,12 @@ func New(ctx context.Context) (*Service, error) {
 		return nil, fmt.Errorf("failed to DB clients: %w", err)
 	}
 
+	if configs.dbCfg.AutoMigrate {
+		if err := migrate(ctx, logger, db); err != nil {
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
 	username := os.Getenv("ADMIN_USERNAME")
 	password := os.Getenv("ADMIN_PASSWORD")
 	err = repository.EnsureAdminUser(ctx, db, username, password)
@@ -60,28 +83,71 @@ func New(ctx context.Context) (*Service, error) {
 	}
 
 	repo := repository.NewPostgresRepo(db)
+	configs.jwtCfg.Denylist = repository.NewTokenRepo(db)
 
-	kafkaProducer := kafka.NewProducer(configs.kafkaCfg)
+	writer, err := kafka.NewProducer(configs.kafkaCfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka producer: %w", err)
+	}
+	kafkaProducer := kafka.NewResilientProducer(configs.kafkaCfg, writer)
+	relay := kafka.NewRelay(configs.kafkaCfg, repository.NewOutboxRepo(db), kafkaProducer, logger)
+	dispatcher := webhook.NewDispatcher(configs.hookCfg, repository.NewWebhookQueue(db), logger)
+	replayRunner := replay.NewRunner(configs.replayCfg, repository.NewReplayQueue(db), kafkaProducer,
+		configs.kafkaCfg.CloudEventsMode, configs.appCfg.EventSchemaURL, logger)
 
 	return &Service{
-		Log:           logger,
-		APICfg:        configs.httpSrv,
-		Repo:          &repo,
-		JWTCfg:        configs.jwtCfg,
-		KafkaProducer: kafkaProducer,
-		DB:            db,
+		Log:               logger,
+		AppCfg:            configs.appCfg,
+		APICfg:            configs.httpSrv,
+		GRPCCfg:           configs.grpcSrv,
+		Repo:              &repo,
+		Webhooks:          repository.NewWebhookRepo(db),
+		Replays:           repository.NewReplayRepo(db),
+		JWTCfg:            configs.jwtCfg,
+		KafkaCfg:          configs.kafkaCfg,
+		KafkaProducer:     kafkaProducer,
+		OutboxRelay:       relay,
+		WebhookDispatcher: dispatcher,
+		ReplayRunner:      replayRunner,
+		DB:                db,
 	}, nil
 }
 
-func Run(ctx context.Context, svc *Service) error {
+// Run starts the HTTP and gRPC servers and the background workers, which stop once ctx
+// is done. The returned wait blocks until they all have, so that the requests, calls and
+// publishes in flight are finished before the service is closed.
+func Run(ctx context.Context, svc *Service) (wait func(), err error) {
+	var wg sync.WaitGroup
+	wait = wg.Wait
+
 	appl := app.New(
 		svc.Log,
 		*svc.Repo,
-		svc.KafkaProducer,
+		svc.AppCfg,
 	)
-
-	r := gin.Default()
+	appl.Webhooks = svc.Webhooks
+	appl.Replays = svc.Replays
+
+	r := gin.New()
+	// Recovery comes after Problems so that a panic is answered with a problem document too.
+	r.Use(gin.Logger(), middleware.RequestID(), middleware.Problems(svc.Log), middleware.Recovery())
+	r.NoRoute(func(c *gin.Context) {
+		_ = c.Error(app.NewError(app.KindNotFound, "no such route"))
+	})
+	if svc.APICfg.ValidateOpenAPI {
+		spec, err := openapi.Load()
+		if err != nil {
+			return wait, fmt.Errorf("failed to load openapi document: %w", err)
+		}
+		r.Use(middleware.OpenAPIValidation(spec, svc.Log))
+	}
+	routes.RegisterAuthRoutes(r, svc.JWTCfg, svc.DB)
 	routes.RegisterCompanyRoutes(r, appl, svc.JWTCfg, svc.DB)
+	routes.RegisterWebhookRoutes(r, appl, svc.JWTCfg)
+	routes.RegisterReplayRoutes(r, appl, svc.JWTCfg)
+	routes.RegisterOpenAPIRoutes(r)
+	routes.RegisterEventRoutes(r)
+	routes.RegisterDebugRoutes(r, svc.JWTCfg)
 
 	srv := &http.Server{
 		Addr:              svc.APICfg.Addr,
@@ -91,20 +157,94 @@ func Run(ctx context.Context, svc *Service) error {
 		WriteTimeout:      svc.APICfg.WriteTimeout,
 	}
 
-	go func() {
+	wg.Go(func() {
 		if err := api.StartServer(ctx, svc.Log, srv); err != nil && err != http.ErrServerClosed {
 			svc.Log.Error("HTTP server stopped with error", zap.Error(err))
 		} else {
 			svc.Log.Info("HTTP server stopped")
 		}
-	}()
+	})
 
-	svc.Log.Info("Application is running", zap.String("addr", svc.APICfg.Addr))
+	lis, err := net.Listen("tcp", svc.GRPCCfg.Addr)
+	if err != nil {
+		return wait, fmt.Errorf("failed to listen for gRPC: %w", err)
+	}
+	grpcSrv := grpcapi.NewServer(appl, svc.JWTCfg, svc.Log)
+
+	wg.Go(func() {
+		if err := grpcapi.StartServer(ctx, svc.Log, grpcSrv, lis); err != nil {
+			svc.Log.Error("gRPC server stopped with error", zap.Error(err))
+		} else {
+			svc.Log.Info("gRPC server stopped")
+		}
+	})
+
+	wg.Go(func() {
+		// Ends the change streams, which would otherwise hold up the shutdown of both servers.
+		<-ctx.Done()
+		appl.Changes.Close()
+	})
+
+	expvar.Publish("kafka_producer", expvar.Func(func() any { return svc.KafkaProducer.Stats() }))
+	wg.Go(func() { svc.OutboxRelay.Run(ctx) })
+	wg.Go(func() { svc.WebhookDispatcher.Run(ctx) })
+	wg.Go(func() { svc.ReplayRunner.Run(ctx) })
+	if svc.KafkaCfg.CommandTopic != "" {
+		consumer, err := commandConsumer(svc, appl)
+		if err != nil {
+			return wait, fmt.Errorf("failed to create command consumer: %w", err)
+		}
+		wg.Go(func() { consumer.Run(ctx) })
+	}
+
+	svc.Log.Info("Application is running",
+		zap.String("addr", svc.APICfg.Addr),
+		zap.String("grpc_addr", svc.GRPCCfg.Addr),
+	)
+	return wait, nil
+}
+
+// commandConsumer creates the consumer applying the company commands of the command topic.
+func commandConsumer(svc *Service, appl *app.App) (*kafka.Consumer, error) {
+	cfg := svc.KafkaCfg
+	reader, err := kafka.NewCommandReader(cfg)
+	if err != nil {
+		return nil, err
+	}
+	deadLetters, err := kafka.NewDeadLetterWriter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return kafka.NewConsumer(cfg, reader, deadLetters, commands.Handler(appl, cfg.CommandActor), svc.Log), nil
+}
+
+// migrate brings the database schema up to date with the embedded migrations.
+func migrate(ctx context.Context, l *zap.Logger, db *sql.DB) error {
+	m, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		return err
+	}
+
+	l.Info("Database schema is up to date", zap.Int("applied_migrations", applied))
 	return nil
 }
 
-// Close releases resources held by Service
+// Close releases resources held by Service. Call it once the wait returned by Run is over,
+// since the Kafka producer flushes the messages still buffered when it is closed.
 func (d *Service) Close() {
+	if d.KafkaProducer != nil {
+		if err := d.KafkaProducer.Close(); err != nil {
+			d.Log.Error("failed to close kafka producer", zap.Error(err))
+		}
+	}
+	if d.DB != nil {
+		_ = d.DB.Close()
+	}
 	if d.Log != nil {
 		// Ensure all buffered logs are written
 		_ = d.Log.Sync()
@@ -0,0 +1,35 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Config tunes the delivery of webhooks.
+type Config struct {
+	// PollInterval is how often the dispatcher looks for events to deliver.
+	PollInterval time.Duration `envconfig:"POLL_INTERVAL" default:"1s"`
+	// BatchSize caps the number of deliveries attempted per poll.
+	BatchSize uint64 `envconfig:"BATCH_SIZE" default:"100"`
+	// Concurrency caps the number of deliveries sent at the same time.
+	Concurrency int `envconfig:"CONCURRENCY" default:"8"`
+	// Timeout is how long an endpoint has to answer a delivery.
+	Timeout time.Duration `envconfig:"TIMEOUT" default:"10s"`
+	// MaxAttempts is the number of attempts after which a delivery is given up.
+	MaxAttempts int `envconfig:"MAX_ATTEMPTS" default:"10"`
+	// DisableAfter is the number of consecutive failed attempts after which a webhook is disabled.
+	DisableAfter int `envconfig:"DISABLE_AFTER" default:"20"`
+	// AllowPrivateTargets lets webhooks target loopback, private and link-local addresses,
+	// for local development. Otherwise they are refused when created and when delivered to.
+	AllowPrivateTargets bool `envconfig:"ALLOW_PRIVATE_TARGETS" default:"false"`
+}
+
+// EnvConfig loads the webhook configuration from environment variables
+func EnvConfig() (*Config, error) {
+	var cfg Config
+	if err := envconfig.Process("WEBHOOK", &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,262 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/backoff"
+	"github.com/dagherghinescu/companies/internal/events"
+	"github.com/dagherghinescu/companies/internal/repository"
+)
+
+// Headers sent with every delivery.
+const (
+	// HeaderID identifies the delivery; it is the same on every attempt, so receivers can drop duplicates.
+	HeaderID = "X-Webhook-Id"
+	// HeaderTimestamp is the Unix time the attempt was signed at.
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature holds the signature returned by Sign.
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	userAgent = "companies-webhooks/1"
+
+	deliveryBaseBackoff = 10 * time.Second
+	deliveryMaxBackoff  = time.Hour
+
+	// maxResponseBody is how much of a response is read before the connection is reused.
+	maxResponseBody = 64 << 10
+
+	// dialTimeout and dialKeepAlive are those of http.DefaultTransport.
+	dialTimeout   = 30 * time.Second
+	dialKeepAlive = 30 * time.Second
+)
+
+// Dispatcher delivers the events committed to the outbox to the webhooks subscribed to them.
+// Each event is POSTed with the same JSON payload as published to Kafka. The deliveries of a
+// webhook sharing a key are sent in the order they were written; when one fails, the later ones
+// wait until it has been retried successfully or given up.
+type Dispatcher struct {
+	queue  repository.WebhookQueue
+	client *http.Client
+	log    *zap.Logger
+	cfg    *Config
+	now    func() time.Time
+}
+
+// NewDispatcher creates a webhook dispatcher taking its deliveries from queue.
+func NewDispatcher(cfg *Config, queue repository.WebhookQueue, log *zap.Logger) *Dispatcher {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Deliveries go straight to the endpoints, so the dialer checks the address of the endpoint, not of a proxy.
+	transport.Proxy = nil
+	if !cfg.AllowPrivateTargets {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: dialKeepAlive,
+			Control:   publicOnly,
+		}).DialContext
+	}
+
+	return &Dispatcher{
+		queue: queue,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.Timeout,
+			// Redirects count as failures, so deliveries only ever go to the registered URL.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		log: log,
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+// Run polls for deliveries until ctx is canceled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.Drain(ctx); err != nil && ctx.Err() == nil {
+			d.log.Error("webhook dispatcher failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain creates the deliveries of new events, then attempts one batch of due deliveries
+// and returns how many succeeded. It does nothing when another instance holds the lock.
+func (d *Dispatcher) Drain(ctx context.Context) (int, error) {
+	release, acquired, err := d.queue.Lock(ctx)
+	if err != nil || !acquired {
+		return 0, err
+	}
+	defer release()
+
+	if _, err := d.queue.Enqueue(ctx, d.cfg.BatchSize); err != nil {
+		return 0, err
+	}
+
+	pending, err := d.queue.Pending(ctx, d.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	streams := orderedStreams(pending)
+	errs := make([]error, len(streams))
+	sem := make(chan struct{}, max(d.cfg.Concurrency, 1))
+
+	var (
+		wg        sync.WaitGroup
+		delivered atomic.Int64
+	)
+	for i, stream := range streams {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			n, err := d.deliverStream(ctx, stream)
+			delivered.Add(int64(n))
+			errs[i] = err
+		})
+	}
+	wg.Wait()
+
+	return int(delivered.Load()), errors.Join(errs...)
+}
+
+// orderedStreams groups the deliveries by webhook and key, keeping their order.
+func orderedStreams(pending []repository.PendingDelivery) [][]repository.PendingDelivery {
+	type streamKey struct {
+		webhookID uuid.UUID
+		key       string
+	}
+
+	index := make(map[streamKey]int)
+	var streams [][]repository.PendingDelivery
+	for _, p := range pending {
+		k := streamKey{webhookID: p.WebhookID, key: p.EventKey}
+		i, ok := index[k]
+		if !ok {
+			i = len(streams)
+			index[k] = i
+			streams = append(streams, nil)
+		}
+		streams[i] = append(streams[i], p)
+	}
+	return streams
+}
+
+// deliverStream sends deliveries one after the other, stopping at the first that fails.
+func (d *Dispatcher) deliverStream(ctx context.Context, stream []repository.PendingDelivery) (int, error) {
+	for i := range stream {
+		ok, err := d.attempt(ctx, &stream[i])
+		if err != nil || !ok {
+			return i, err
+		}
+	}
+	return len(stream), nil
+}
+
+// attempt sends a delivery once and records the outcome. It reports whether the delivery succeeded.
+func (d *Dispatcher) attempt(ctx context.Context, p *repository.PendingDelivery) (bool, error) {
+	status, sendErr := d.send(ctx, p)
+	if ctx.Err() != nil {
+		// Shutting down: the attempt is not the endpoint's fault and is retried on the next run.
+		return false, ctx.Err()
+	}
+	if sendErr == nil {
+		return true, d.queue.MarkDelivered(ctx, p, status)
+	}
+
+	failure := &repository.DeliveryFailure{
+		Status:       status,
+		Reason:       sendErr.Error(),
+		DisableAfter: d.cfg.DisableAfter,
+	}
+	if p.Attempts+1 < d.cfg.MaxAttempts {
+		retryAt := d.now().Add(backoff.Exponential(deliveryBaseBackoff, deliveryMaxBackoff, p.Attempts))
+		failure.RetryAt = &retryAt
+	}
+
+	d.log.Warn("webhook delivery failed",
+		zap.Int64("delivery_id", p.ID),
+		zap.String("webhook_id", p.WebhookID.String()),
+		zap.Int("attempts", p.Attempts+1),
+		zap.Bool("given_up", failure.RetryAt == nil),
+		zap.Error(sendErr),
+	)
+
+	disabled, err := d.queue.MarkFailed(ctx, p, failure)
+	if err != nil {
+		return false, err
+	}
+	if disabled {
+		d.log.Warn("webhook disabled after consecutive failures",
+			zap.String("webhook_id", p.WebhookID.String()),
+			zap.Int("failures", d.cfg.DisableAfter),
+		)
+	}
+	return false, nil
+}
+
+// send POSTs the payload of a delivery and returns the status of the response, zero if there was none.
+// Any status other than 2xx is an error.
+func (d *Dispatcher) send(ctx context.Context, p *repository.PendingDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(p.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", events.StructuredContentType)
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderID, strconv.FormatInt(p.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(p.Secret, timestamp, p.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature of a delivery: "sha256=" followed by the hex encoded
+// HMAC-SHA256, keyed with the webhook secret, of the timestamp, a dot and the body.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
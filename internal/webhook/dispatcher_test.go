@@ -0,0 +1,219 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/dagherghinescu/companies/internal/events"
+	"github.com/dagherghinescu/companies/internal/repository"
+	"github.com/dagherghinescu/companies/internal/webhook"
+)
+
+type mockQueue struct {
+	mu        sync.Mutex
+	pending   []repository.PendingDelivery
+	delivered []int64
+	failed    map[int64]*repository.DeliveryFailure
+	failures  map[uuid.UUID]int
+}
+
+func (m *mockQueue) Lock(_ context.Context) (func(), bool, error) {
+	return func() {}, true, nil
+}
+
+func (m *mockQueue) Enqueue(_ context.Context, _ uint64) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockQueue) Pending(_ context.Context, _ uint64) ([]repository.PendingDelivery, error) {
+	return m.pending, nil
+}
+
+func (m *mockQueue) MarkDelivered(_ context.Context, d *repository.PendingDelivery, _ int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.delivered = append(m.delivered, d.ID)
+	m.failures[d.WebhookID] = 0
+	return nil
+}
+
+func (m *mockQueue) MarkFailed(
+	_ context.Context, d *repository.PendingDelivery, f *repository.DeliveryFailure,
+) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failed[d.ID] = f
+	m.failures[d.WebhookID]++
+	return m.failures[d.WebhookID] == f.DisableAfter, nil
+}
+
+func newMockQueue(pending ...repository.PendingDelivery) *mockQueue {
+	return &mockQueue{
+		pending:  pending,
+		failed:   make(map[int64]*repository.DeliveryFailure),
+		failures: make(map[uuid.UUID]int),
+	}
+}
+
+// receiver is an endpoint answering deliveries with status. Deliveries with
+// a wrong signature are answered with 401 and not recorded.
+type receiver struct {
+	mu       sync.Mutex
+	received []string
+}
+
+func (rc *receiver) handler(secret string, status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		timestamp, tsErr := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+		if err != nil || tsErr != nil ||
+			r.Header.Get(webhook.HeaderSignature) != webhook.Sign(secret, timestamp, body) ||
+			r.Header.Get("Content-Type") != events.StructuredContentType {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		rc.mu.Lock()
+		rc.received = append(rc.received, r.Header.Get(webhook.HeaderID)+":"+string(body))
+		rc.mu.Unlock()
+		w.WriteHeader(status)
+	}
+}
+
+// testConfig allows private targets, since the test servers listen on the loopback interface.
+func testConfig() *webhook.Config {
+	return &webhook.Config{
+		BatchSize: 10, Concurrency: 2, Timeout: time.Second, MaxAttempts: 3, DisableAfter: 5,
+		AllowPrivateTargets: true,
+	}
+}
+
+func TestDispatcher_Drain(t *testing.T) {
+	ok := &receiver{}
+	okServer := httptest.NewServer(ok.handler("s1", http.StatusNoContent))
+	defer okServer.Close()
+
+	failing := &receiver{}
+	failingServer := httptest.NewServer(failing.handler("s2", http.StatusInternalServerError))
+	defer failingServer.Close()
+
+	okHook, failingHook := uuid.New(), uuid.New()
+	queue := newMockQueue(
+		repository.PendingDelivery{ID: 1, WebhookID: okHook, URL: okServer.URL, Secret: "s1",
+			EventKey: "a", Payload: []byte(`{"n":1}`)},
+		repository.PendingDelivery{ID: 2, WebhookID: failingHook, URL: failingServer.URL, Secret: "s2",
+			EventKey: "a", Payload: []byte(`{"n":1}`)},
+		repository.PendingDelivery{ID: 3, WebhookID: okHook, URL: okServer.URL, Secret: "s1",
+			EventKey: "a", Payload: []byte(`{"n":2}`)},
+		repository.PendingDelivery{ID: 4, WebhookID: failingHook, URL: failingServer.URL, Secret: "s2",
+			EventKey: "a", Payload: []byte(`{"n":2}`)},
+	)
+
+	dispatcher := webhook.NewDispatcher(testConfig(), queue, zap.NewNop())
+
+	n, err := dispatcher.Drain(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []string{`1:{"n":1}`, `3:{"n":2}`}, ok.received)
+	require.ElementsMatch(t, []int64{1, 3}, queue.delivered)
+
+	// Delivery 4 must wait for delivery 2, which shares its webhook and key, to be retried first.
+	require.Equal(t, []string{`2:{"n":1}`}, failing.received)
+	require.Len(t, queue.failed, 1)
+	failure := queue.failed[2]
+	require.Equal(t, http.StatusInternalServerError, failure.Status)
+	require.NotNil(t, failure.RetryAt)
+	require.Equal(t, 5, failure.DisableAfter)
+}
+
+func TestDispatcher_Drain_Failures(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc.handler("s", http.StatusBadGateway))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		url           string
+		attempts      int
+		expectedRetry bool
+		expectedCode  int
+	}{
+		{name: "retried", url: server.URL, attempts: 1, expectedRetry: true, expectedCode: http.StatusBadGateway},
+		{name: "given up", url: server.URL, attempts: 2, expectedCode: http.StatusBadGateway},
+		{name: "unreachable", url: "http://127.0.0.1:1", expectedRetry: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := newMockQueue(repository.PendingDelivery{
+				ID: 1, WebhookID: uuid.New(), URL: tt.url, Secret: "s", EventKey: "a",
+				Payload: []byte(`{}`), Attempts: tt.attempts,
+			})
+
+			n, err := webhook.NewDispatcher(testConfig(), queue, zap.NewNop()).Drain(context.Background())
+			require.NoError(t, err)
+			require.Zero(t, n)
+
+			failure := queue.failed[1]
+			require.NotNil(t, failure)
+			require.Equal(t, tt.expectedCode, failure.Status)
+			require.NotEmpty(t, failure.Reason)
+			require.Equal(t, tt.expectedRetry, failure.RetryAt != nil)
+		})
+	}
+}
+
+func TestDispatcher_Drain_RedirectFails(t *testing.T) {
+	target := &receiver{}
+	targetServer := httptest.NewServer(target.handler("s", http.StatusOK))
+	defer targetServer.Close()
+
+	redirect := httptest.NewServer(http.RedirectHandler(targetServer.URL, http.StatusTemporaryRedirect))
+	defer redirect.Close()
+
+	queue := newMockQueue(repository.PendingDelivery{
+		ID: 1, WebhookID: uuid.New(), URL: redirect.URL, Secret: "s", EventKey: "a", Payload: []byte(`{}`),
+	})
+
+	n, err := webhook.NewDispatcher(testConfig(), queue, zap.NewNop()).Drain(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, n)
+	require.Empty(t, target.received)
+	require.Equal(t, http.StatusTemporaryRedirect, queue.failed[1].Status)
+}
+
+func TestDispatcher_Drain_RefusesPrivateTargets(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc.handler("s", http.StatusOK))
+	defer server.Close()
+
+	for _, url := range []string{server.URL, "http://169.254.169.254/latest/meta-data/"} {
+		queue := newMockQueue(repository.PendingDelivery{
+			ID: 1, WebhookID: uuid.New(), URL: url, Secret: "s", EventKey: "a", Payload: []byte(`{}`),
+		})
+
+		cfg := testConfig()
+		cfg.AllowPrivateTargets = false
+		n, err := webhook.NewDispatcher(cfg, queue, zap.NewNop()).Drain(context.Background())
+		require.NoError(t, err)
+		require.Zero(t, n)
+		require.Contains(t, queue.failed[1].Reason, webhook.ErrPrivateTarget.Error())
+	}
+	require.Empty(t, rc.received)
+}
+
+func TestSign(t *testing.T) {
+	// Computed independently: printf '1700000000.{"a":1}' | openssl dgst -sha256 -hmac secret
+	const expected = "sha256=49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686"
+	require.Equal(t, expected, webhook.Sign("secret", 1_700_000_000, []byte(`{"a":1}`)))
+}
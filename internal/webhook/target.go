@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+// ErrPrivateTarget is returned for webhook URLs pointing at, or resolving to, an
+// address of the network the service runs in rather than a partner endpoint.
+var ErrPrivateTarget = errors.New("webhook target is a loopback, private or link-local address")
+
+// PublicAddr reports whether deliveries may be sent to addr. Unspecified, loopback,
+// private (RFC 1918 and RFC 4193), link-local, including the 169.254.169.254 cloud
+// metadata endpoint, and multicast addresses are refused.
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsUnspecified() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsMulticast()
+}
+
+// CheckHost returns ErrPrivateTarget when host, the host name of a webhook URL, is
+// an IP address that is not public or a localhost name. Other names are checked
+// once resolved, each time a delivery is sent.
+func CheckHost(host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !PublicAddr(addr) {
+			return ErrPrivateTarget
+		}
+		return nil
+	}
+
+	name := strings.TrimSuffix(strings.ToLower(host), ".")
+	if name == "localhost" || strings.HasSuffix(name, ".localhost") {
+		return ErrPrivateTarget
+	}
+	return nil
+}
+
+// publicOnly is the Control function of the delivery dialer. It runs after the name
+// was resolved, so a name pointing at an internal address is refused too.
+func publicOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !PublicAddr(addr) {
+		return ErrPrivateTarget
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package webhook_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dagherghinescu/companies/internal/webhook"
+)
+
+func TestCheckHost(t *testing.T) {
+	tests := []struct {
+		host        string
+		expectedErr error
+	}{
+		{host: "partner.example.com"},
+		{host: "93.184.215.14"},
+		{host: "2606:2800:21f:cb07:6820:80da:af6b:8b2c"},
+		{host: "localhost", expectedErr: webhook.ErrPrivateTarget},
+		{host: "api.LOCALHOST.", expectedErr: webhook.ErrPrivateTarget},
+		{host: "127.0.0.1", expectedErr: webhook.ErrPrivateTarget},
+		{host: "::1", expectedErr: webhook.ErrPrivateTarget},
+		{host: "0.0.0.0", expectedErr: webhook.ErrPrivateTarget},
+		{host: "10.1.2.3", expectedErr: webhook.ErrPrivateTarget},
+		{host: "172.16.0.1", expectedErr: webhook.ErrPrivateTarget},
+		{host: "192.168.1.1", expectedErr: webhook.ErrPrivateTarget},
+		{host: "169.254.169.254", expectedErr: webhook.ErrPrivateTarget},
+		{host: "fd00::1", expectedErr: webhook.ErrPrivateTarget},
+		{host: "fe80::1", expectedErr: webhook.ErrPrivateTarget},
+		{host: "::ffff:127.0.0.1", expectedErr: webhook.ErrPrivateTarget},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			require.ErrorIs(t, webhook.CheckHost(tt.host), tt.expectedErr)
+		})
+	}
+}